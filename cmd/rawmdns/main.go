@@ -0,0 +1,518 @@
+// Command rawmdns decodes, encodes and inspects mDNS messages.
+//
+// Usage:
+//
+//	rawmdns decode [-in auto|hex|base64|raw|pcap] [-out text|json|dump|summary] [-max-len n] [-filter expr] [file]
+//	rawmdns encode [-in zone|json] [-out raw|hex|base64] [-origin name] [file]
+//	rawmdns lint [-in auto|hex|base64|raw|pcap] [-severity info|warning|error] [-unicast] [-legacy] [-announcement] [-filter expr] [file]
+//	rawmdns diff [-in auto|hex|base64|raw|pcap] [-ignore-order] [-filter expr] fileA fileB
+//
+// With no file, or a file named "-", input is read from stdin. A pcap or
+// pcapng capture may hold many messages; decode and lint handle each of them,
+// diff compares the first message of each capture. With -filter, only the
+// messages selected by a rawmdns.CompileFilter expression are handled.
+//
+// lint checks each message with rawmdns.Lint. Messages are taken to be sent
+// multicast from port 5353 unless flags say otherwise, but for a message from
+// a capture its addresses decide.
+//
+// It uses only the public API of the rawmdns packages, and so doubles as an
+// example of their use.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/sayotte/rawmdns"
+	"github.com/sayotte/rawmdns/pcap"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage: rawmdns <command> [flags] [file]
+       rawmdns diff [flags] fileA fileB
+
+commands:
+  decode   print messages as text, RFC 8427 JSON, an annotated dump or a one-line summary
+  encode   build a message from zone-file records or JSON
+  lint     check messages against the mDNS and DNS-SD RFCs
+  diff     compare two messages
+
+Run "rawmdns <command> -h" for the flags of a command.
+`
+
+// run is main without the process: it returns the exit status, 0 on success,
+// 1 if lint or diff found something, and 2 on error.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd := &command{name: args[0], stdin: stdin, stdout: stdout, stderr: stderr}
+	var err error
+	var status int
+	switch args[0] {
+	case "decode":
+		err = cmd.decode(args[1:])
+	case "encode":
+		err = cmd.encode(args[1:])
+	case "lint":
+		status, err = cmd.lint(args[1:])
+	case "diff":
+		status, err = cmd.diff(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "rawmdns: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "rawmdns %s: %s\n", args[0], err)
+		return 2
+	}
+	return status
+}
+
+type command struct {
+	name   string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("rawmdns "+c.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// filterFlag adds the -filter flag to a command's flags. The returned
+// function compiles it, returning a nil Filter if the flag wasn't given.
+func filterFlag(fs *flag.FlagSet) func() (*rawmdns.Filter, error) {
+	expr := fs.String("filter", "", "only handle messages matching this filter `expression`")
+	return func() (*rawmdns.Filter, error) {
+		if *expr == "" {
+			return nil, nil
+		}
+		f, err := rawmdns.CompileFilter(*expr)
+		if err != nil {
+			return nil, fmt.Errorf("-filter: %s", err)
+		}
+		return f, nil
+	}
+}
+
+// input is one wire-format message read from a file, with a label saying
+// where it came from. For a message from a capture, src and dst hold the
+// addresses it was sent between; otherwise they're nil.
+type input struct {
+	label    string
+	wire     []byte
+	src, dst *net.UDPAddr
+}
+
+// readFile reads the named file, or stdin for "" or "-".
+func (c *command) readFile(name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// readInputs reads the wire-format messages in a file, in the given format.
+// If filter is not nil, only the messages it selects are returned.
+func (c *command) readInputs(name, format string, filter *rawmdns.Filter) ([]input, error) {
+	b, err := c.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if format == "auto" {
+		format = detectFormat(b)
+	}
+	label := name
+	if label == "" {
+		label = "-"
+	}
+
+	switch format {
+	case "raw":
+		return selectInput(input{label: label, wire: b}, filter), nil
+	case "hex":
+		wire, err := hex.DecodeString(stripSpace(strings.ReplaceAll(string(b), ":", "")))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", label, err)
+		}
+		return selectInput(input{label: label, wire: wire}, filter), nil
+	case "base64":
+		wire, err := base64.StdEncoding.DecodeString(stripSpace(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", label, err)
+		}
+		return selectInput(input{label: label, wire: wire}, filter), nil
+	case "pcap":
+		pr, err := pcap.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", label, err)
+		}
+		pr.SetFilter(filter)
+		var inputs []input
+		for {
+			pkt, err := pr.Next()
+			if err == io.EOF {
+				return inputs, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", label, err)
+			}
+			pktLabel := fmt.Sprintf("%s #%d %s %s > %s", label, len(inputs)+1,
+				pkt.Timestamp.Format("2006-01-02T15:04:05.000000000Z07:00"), pkt.Src, pkt.Dst)
+			inputs = append(inputs, input{label: pktLabel, wire: pkt.Payload, src: pkt.Src, dst: pkt.Dst})
+		}
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+}
+
+// selectInput returns inp alone, or nothing if filter doesn't select it.
+func selectInput(inp input, filter *rawmdns.Filter) []input {
+	if filter != nil {
+		dm, err := decodeWire(inp.wire)
+		if err != nil || !filter.Match(dm) {
+			return nil
+		}
+	}
+	return []input{inp}
+}
+
+// detectFormat guesses whether a file is a capture, hex, base64 or a raw
+// message. A raw message always starts with a two byte ID and flags, which
+// are rarely both printable, so text input is taken to be encoded.
+func detectFormat(b []byte) string {
+	if len(b) >= 4 {
+		switch string(b[:4]) {
+		case "\xd4\xc3\xb2\xa1", "\xa1\xb2\xc3\xd4", "\x4d\x3c\xb2\xa1", "\xa1\xb2\x3c\x4d", "\x0a\x0d\x0d\x0a":
+			return "pcap"
+		}
+	}
+	text := stripSpace(string(b))
+	if text == "" {
+		return "raw"
+	}
+	isHex := true
+	for _, r := range strings.ReplaceAll(text, ":", "") {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			isHex = false
+			break
+		}
+	}
+	if isHex {
+		return "hex"
+	}
+	if _, err := base64.StdEncoding.DecodeString(text); err == nil {
+		return "base64"
+	}
+	return "raw"
+}
+
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func decodeWire(wire []byte) (rawmdns.DNSMessage, error) {
+	d := rawmdns.NewDecoder(bytes.NewReader(wire))
+	return d.DecodeDNSMessage()
+}
+
+func (c *command) decode(args []string) error {
+	fs := c.flagSet()
+	in := fs.String("in", "auto", "input `format`: auto, hex, base64, raw or pcap")
+	out := fs.String("out", "text", "output `format`: text, json, dump or summary")
+	octets := fs.Bool("octets", false, "include messageOctetsHEX in JSON output")
+	maxLen := fs.Int("max-len", 0, "truncate summaries to this many `bytes`, or 0 for no limit")
+	compileFilter := filterFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("expected at most one file")
+	}
+	filter, err := compileFilter()
+	if err != nil {
+		return err
+	}
+	inputs, err := c.readInputs(fs.Arg(0), *in, filter)
+	if err != nil {
+		return err
+	}
+
+	for i, inp := range inputs {
+		if *out == "summary" {
+			// one line per message, so it's prefixed with the label
+			dm, err := decodeWire(inp.wire)
+			if err != nil {
+				fmt.Fprintf(c.stdout, "%s: error: %s\n", inp.label, err)
+				continue
+			}
+			if len(inputs) > 1 {
+				fmt.Fprintf(c.stdout, "%s: ", inp.label)
+			}
+			fmt.Fprintln(c.stdout, dm.Summary(*maxLen))
+			continue
+		}
+		if len(inputs) > 1 {
+			if i > 0 {
+				fmt.Fprintln(c.stdout)
+			}
+			fmt.Fprintf(c.stdout, ";; %s\n", inp.label)
+		}
+		switch *out {
+		case "dump":
+			wd, err := rawmdns.DumpMessage(inp.wire)
+			fmt.Fprint(c.stdout, wd)
+			if err != nil {
+				fmt.Fprintf(c.stdout, ";; error: %s\n", err)
+			}
+		case "text", "json":
+			dm, err := decodeWire(inp.wire)
+			if err != nil {
+				fmt.Fprintf(c.stdout, ";; error: %s\n", err)
+				continue
+			}
+			if *out == "text" {
+				fmt.Fprintln(c.stdout, dm)
+				continue
+			}
+			var opts []rawmdns.JSONOption
+			if *octets {
+				opts = append(opts, rawmdns.JSONIncludeMessageOctets)
+			}
+			b, err := dm.ToJSON(opts...)
+			if err != nil {
+				return err
+			}
+			var indented bytes.Buffer
+			if err = json.Indent(&indented, b, "", "  "); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.stdout, indented.String())
+		default:
+			return fmt.Errorf("unknown output format %q", *out)
+		}
+	}
+	return nil
+}
+
+func (c *command) encode(args []string) error {
+	fs := c.flagSet()
+	in := fs.String("in", "zone", "input `format`: zone or json")
+	out := fs.String("out", "raw", "output `format`: raw, hex or base64")
+	origin := fs.String("origin", ".", "origin for relative names in zone input")
+	id := fs.Uint("id", 0, "message ID for zone input")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("expected at most one file")
+	}
+	b, err := c.readFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var dm rawmdns.DNSMessage
+	switch *in {
+	case "zone":
+		// Zone input becomes an unsolicited mDNS response, which is
+		// what a responder announcing the records would send
+		// $INCLUDEs are relative to the directory of the zone file
+		dir := "."
+		if name := fs.Arg(0); name != "" && name != "-" {
+			dir = filepath.Dir(name)
+		}
+		rrs, err := rawmdns.ParseZone(bytes.NewReader(b), *origin, os.DirFS(dir))
+		if err != nil {
+			return err
+		}
+		if *id > 0xFFFF {
+			return fmt.Errorf("-id must be at most 65535")
+		}
+		dm.Hdr = rawmdns.DNSHeader{ID: uint16(*id), IsResponse: true, Authoritative: true, NumAnswers: uint16(len(rrs))}
+		dm.Answers = rrs
+	case "json":
+		if err = json.Unmarshal(b, &dm); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown input format %q", *in)
+	}
+
+	wire, err := dm.ToBytes()
+	if err != nil {
+		return err
+	}
+	switch *out {
+	case "raw":
+		_, err = c.stdout.Write(wire)
+	case "hex":
+		_, err = fmt.Fprintln(c.stdout, hex.EncodeToString(wire))
+	case "base64":
+		_, err = fmt.Fprintln(c.stdout, base64.StdEncoding.EncodeToString(wire))
+	default:
+		return fmt.Errorf("unknown output format %q", *out)
+	}
+	return err
+}
+
+// lintWire returns a description of each problem found in a message: that
+// it's malformed, or doesn't survive being re-encoded, and what rawmdns.Lint
+// finds at or above the given severity.
+func lintWire(wire []byte, ctx rawmdns.Context, severity rawmdns.Severity) []string {
+	wd, err := rawmdns.DumpMessage(wire)
+	if err != nil {
+		return []string{fmt.Sprintf("malformed: %s", err)}
+	}
+	dm, err := decodeWire(wire)
+	if err != nil {
+		return []string{fmt.Sprintf("does not decode: %s", err)}
+	}
+
+	var problems []string
+	if last := wd.Fields[len(wd.Fields)-1]; last.Path == "trailing" {
+		problems = append(problems, fmt.Sprintf("%d bytes after the last record", last.End-last.Start))
+	}
+	for _, f := range rawmdns.Lint(dm, ctx) {
+		if f.Severity >= severity {
+			problems = append(problems, f.String())
+		}
+	}
+	reencoded, err := dm.ToBytes()
+	if err != nil {
+		return append(problems, fmt.Sprintf("does not re-encode: %s", err))
+	}
+	again, err := decodeWire(reencoded)
+	if err != nil {
+		return append(problems, fmt.Sprintf("re-encoded message does not decode: %s", err))
+	}
+	for _, d := range rawmdns.DiffMessages(dm, again) {
+		problems = append(problems, fmt.Sprintf("changes when re-encoded: %s", d))
+	}
+	return problems
+}
+
+// lintSeverities maps the values of the lint -severity flag.
+var lintSeverities = map[string]rawmdns.Severity{
+	"info":    rawmdns.SeverityInfo,
+	"warning": rawmdns.SeverityWarning,
+	"error":   rawmdns.SeverityError,
+}
+
+func (c *command) lint(args []string) (int, error) {
+	fs := c.flagSet()
+	in := fs.String("in", "auto", "input `format`: auto, hex, base64, raw or pcap")
+	severityName := fs.String("severity", "warning", "report findings of at least this `severity`: info, warning or error")
+	unicast := fs.Bool("unicast", false, "messages are sent directly to a host, not multicast")
+	legacy := fs.Bool("legacy", false, "messages are legacy unicast queries or responses to them")
+	announcement := fs.Bool("announcement", false, "responses are unsolicited service announcements")
+	compileFilter := filterFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+	if fs.NArg() > 1 {
+		return 0, fmt.Errorf("expected at most one file")
+	}
+	severity, found := lintSeverities[*severityName]
+	if !found {
+		return 0, fmt.Errorf("unknown severity %q", *severityName)
+	}
+	filter, err := compileFilter()
+	if err != nil {
+		return 0, err
+	}
+	inputs, err := c.readInputs(fs.Arg(0), *in, filter)
+	if err != nil {
+		return 0, err
+	}
+	status := 0
+	for _, inp := range inputs {
+		ctx := rawmdns.Context{Multicast: !*unicast, LegacyUnicast: *legacy, Announcement: *announcement}
+		if inp.src != nil && inp.dst != nil {
+			// a capture says how each message was sent
+			ctx.Multicast = inp.dst.IP.IsMulticast()
+			ctx.LegacyUnicast = inp.src.Port != pcap.MDNSPort || inp.dst.Port != pcap.MDNSPort
+		}
+		for _, problem := range lintWire(inp.wire, ctx, severity) {
+			fmt.Fprintf(c.stdout, "%s: %s\n", inp.label, problem)
+			status = 1
+		}
+	}
+	return status, nil
+}
+
+func (c *command) diff(args []string) (int, error) {
+	fs := c.flagSet()
+	in := fs.String("in", "auto", "input `format`: auto, hex, base64, raw or pcap")
+	ignoreOrder := fs.Bool("ignore-order", false, "match up records regardless of their order")
+	compileFilter := filterFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+	if fs.NArg() != 2 {
+		return 0, fmt.Errorf("expected two files")
+	}
+	if fs.Arg(0) == fs.Arg(1) && (fs.Arg(0) == "-" || fs.Arg(0) == "") {
+		return 0, fmt.Errorf("only one file may be stdin")
+	}
+	filter, err := compileFilter()
+	if err != nil {
+		return 0, err
+	}
+
+	var msgs [2]rawmdns.DNSMessage
+	for i := range msgs {
+		inputs, err := c.readInputs(fs.Arg(i), *in, filter)
+		if err != nil {
+			return 0, err
+		}
+		if len(inputs) == 0 {
+			return 0, fmt.Errorf("%s: no messages", fs.Arg(i))
+		}
+		if msgs[i], err = decodeWire(inputs[0].wire); err != nil {
+			return 0, fmt.Errorf("%s: %s", inputs[0].label, err)
+		}
+	}
+
+	var opts []rawmdns.DiffOption
+	if *ignoreOrder {
+		opts = append(opts, rawmdns.IgnoreRecordOrder)
+	}
+	diffs := rawmdns.DiffMessages(msgs[0], msgs[1], opts...)
+	for _, d := range diffs {
+		fmt.Fprintln(c.stdout, d)
+	}
+	if len(diffs) > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
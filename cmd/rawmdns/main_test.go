@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runTest(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestDetectFormat(t *testing.T) {
+	answer, err := os.ReadFile("../../testdata/airplay-answer.cap")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %s", err)
+	}
+	testCases := []struct {
+		in       []byte
+		expected string
+	}{
+		{answer, "raw"},
+		{[]byte(hex.EncodeToString(answer) + "\n"), "hex"},
+		{[]byte("AACEAAAAAAEAAAAA\nB3ByaW50ZXI=\n"), "base64"},
+		{[]byte("\xd4\xc3\xb2\xa1\x02\x00"), "pcap"},
+		{[]byte("\x0a\x0d\x0d\x0a\x1c\x00"), "pcap"},
+	}
+	for _, tc := range testCases {
+		if format := detectFormat(tc.in); format != tc.expected {
+			t.Errorf("detectFormat(%q...) is %s, expected %s", tc.in[:6], format, tc.expected)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	zone := "printer.local. 120 CLASS32769 A 10.0.0.5\n"
+	status, wireHex, stderr := runTest(t, zone, "encode", "-out", "hex")
+	if status != 0 {
+		t.Fatalf("encode failed: %s", stderr)
+	}
+	if wireHex != "000084000000000100000000077072696e746572056c6f63616c00000180010000007800040a000005\n" {
+		t.Errorf("encode output is %s", wireHex)
+	}
+
+	status, text, stderr := runTest(t, wireHex, "decode")
+	if status != 0 {
+		t.Fatalf("decode failed: %s", stderr)
+	}
+	if !strings.Contains(text, "printer.local.\t120\tIN\tA\t10.0.0.5\t; cache-flush") {
+		t.Errorf("decode output is:\n%s", text)
+	}
+
+	status, jsonText, stderr := runTest(t, wireHex, "decode", "-out", "json")
+	if status != 0 {
+		t.Fatalf("decode -out json failed: %s", stderr)
+	}
+	status, again, stderr := runTest(t, jsonText, "encode", "-in", "json", "-out", "hex")
+	if status != 0 || again != wireHex {
+		t.Errorf("JSON did not encode back to the same message: %s %s", again, stderr)
+	}
+
+	status, summary, _ := runTest(t, wireHex, "decode", "-out", "summary")
+	if status != 0 || summary != "0*- 1/0/0 (Cache flush) A 10.0.0.5\n" {
+		t.Errorf("decode -out summary output is %q", summary)
+	}
+
+	status, dump, _ := runTest(t, wireHex, "decode", "-out", "dump")
+	if status != 0 || !strings.Contains(dump, "answers[0].rdata.address: 10.0.0.5") {
+		t.Errorf("decode -out dump output is:\n%s", dump)
+	}
+}
+
+func TestEncode_include(t *testing.T) {
+	// the included file is found next to the zone file, not in the working
+	// directory
+	dir := t.TempDir()
+	zone := filepath.Join(dir, "printer.zone")
+	if err := os.WriteFile(zone, []byte("$INCLUDE address.zone\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "address.zone"), []byte("printer.local. 120 IN A 10.0.0.5\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile: %s", err)
+	}
+
+	status, wireHex, stderr := runTest(t, "", "encode", "-out", "hex", zone)
+	if status != 0 {
+		t.Fatalf("encode failed: %s", stderr)
+	}
+	if !strings.Contains(wireHex, "077072696e746572056c6f63616c00000100010000007800040a000005") {
+		t.Errorf("encode output is %s", wireHex)
+	}
+}
+
+func TestLintAndDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.hex")
+	b := filepath.Join(dir, "b.hex")
+	// b has a different TTL, and an extra byte after the record
+	if err := os.WriteFile(a, []byte("000084000000000100000000077072696e746572056c6f63616c00000180010000007800040a000005"), 0644); err != nil {
+		t.Fatalf("os.WriteFile: %s", err)
+	}
+	if err := os.WriteFile(b, []byte("000084000000000100000000077072696e746572056c6f63616c00000180010000007900040a00000500"), 0644); err != nil {
+		t.Fatalf("os.WriteFile: %s", err)
+	}
+
+	if status, out, stderr := runTest(t, "", "lint", a); status != 0 {
+		t.Errorf("lint of a good message returned %d: %s %s", status, out, stderr)
+	}
+	status, out, _ := runTest(t, "", "lint", b)
+	if status != 1 || !strings.Contains(out, "1 bytes after the last record") {
+		t.Errorf("lint of a bad message returned %d: %s", status, out)
+	}
+
+	// a multicast response must have a zero query ID
+	id := "000184000000000100000000077072696e746572056c6f63616c00000180010000007800040a000005"
+	status, out, _ = runTest(t, id, "lint")
+	if status != 1 || !strings.Contains(out, "-: error: header: multicast response has query ID 1, expected 0 (RFC 6762 section 18.1)") {
+		t.Errorf("lint of a response with a query ID returned %d: %s", status, out)
+	}
+	if status, out, _ = runTest(t, id, "lint", "-unicast"); status != 0 {
+		t.Errorf("lint -unicast of a response with a query ID returned %d: %s", status, out)
+	}
+
+	status, out, _ = runTest(t, "", "diff", a, b)
+	if status != 1 || !strings.Contains(out, "TTL: 120 != 121") {
+		t.Errorf("diff returned %d: %s", status, out)
+	}
+	if status, out, _ = runTest(t, "", "diff", a, a); status != 0 || out != "" {
+		t.Errorf("diff of a message with itself returned %d: %s", status, out)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	if status, _, stderr := runTest(t, "", "frobnicate"); status != 2 || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("Unknown command returned %d: %s", status, stderr)
+	}
+}
+
+func TestFilterFlag(t *testing.T) {
+	wireHex := "000084000000000100000000077072696e746572056c6f63616c00000180010000007800040a000005"
+	if status, out, _ := runTest(t, wireHex, "decode", "-filter", "answer.type == A and answer.ttl == 120"); status != 0 || !strings.Contains(out, "printer.local.") {
+		t.Errorf("decode of a matching message returned %d: %s", status, out)
+	}
+	if status, out, _ := runTest(t, wireHex, "decode", "-filter", "answer.type == SRV"); status != 0 || out != "" {
+		t.Errorf("decode of a message which doesn't match returned %d: %q", status, out)
+	}
+	status, _, stderr := runTest(t, wireHex, "decode", "-filter", "answer.tll == 0")
+	if status != 2 || !strings.Contains(stderr, `-filter: column 1: unknown field "answer.tll"`) {
+		t.Errorf("decode with a bad filter returned %d: %s", status, stderr)
+	}
+}
@@ -0,0 +1,96 @@
+package rawmdns
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Compare orders two records "lexicographically" as described in RFC 6762
+// section 8.2.1, for breaking ties between simultaneous probes. It returns -1
+// if a sorts before b, 1 if a is lexicographically later than b, or 0 if they
+// are the same.
+//
+// Records are compared first by class, ignoring the cache-flush bit, then by
+// type, then byte by byte on their uncompressed RDATA; if one RDATA is a
+// prefix of the other, the longer one is later. Owner names and TTLs are not
+// compared.
+//
+// A record whose PackRData fails has no RDATA to compare, so it sorts after
+// every record of its class and type which can be packed, and is the same as
+// any other which can't. A host probing with such a record can't send it, so
+// it should fix the record rather than rely on the tiebreak.
+func Compare(a, b DNSResourceRecord) int {
+	return newTiebreakRecord(a).compare(newTiebreakRecord(b))
+}
+
+// CompareRecordSets compares two sets of records, such as the records two
+// hosts are probing for with the same name, following RFC 6762 section 8.2.1:
+// each set is sorted using Compare, then the records are compared pairwise and
+// the first difference decides. If one set runs out of records first, the set
+// with records remaining is later. It returns -1 if our set loses the
+// tiebreak against theirs, 1 if it wins, or 0 if the sets are identical and
+// there is no conflict at all. Neither input slice is modified.
+func CompareRecordSets(ours, theirs []DNSResourceRecord) int {
+	sortedOurs := sortedForTiebreak(ours)
+	sortedTheirs := sortedForTiebreak(theirs)
+	for i := 0; i < len(sortedOurs) && i < len(sortedTheirs); i++ {
+		if c := sortedOurs[i].compare(sortedTheirs[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(sortedOurs) < len(sortedTheirs):
+		return -1
+	case len(sortedOurs) > len(sortedTheirs):
+		return 1
+	}
+	return 0
+}
+
+// tiebreakRecord is a record alongside its packed RDATA, so that a set can be
+// sorted without packing each record over and over.
+type tiebreakRecord struct {
+	common ResourceRecordCommon
+	rdata  []byte
+	// packFailed is set if the RDATA could not be packed
+	packFailed bool
+}
+
+func newTiebreakRecord(rr DNSResourceRecord) tiebreakRecord {
+	rdata, err := rr.PackRData()
+	return tiebreakRecord{common: rr.GetCommon(), rdata: rdata, packFailed: err != nil}
+}
+
+// compare is Compare on records whose RDATA is already packed.
+func (tr tiebreakRecord) compare(other tiebreakRecord) int {
+	switch {
+	case tr.common.Class < other.common.Class:
+		return -1
+	case tr.common.Class > other.common.Class:
+		return 1
+	case tr.common.Type < other.common.Type:
+		return -1
+	case tr.common.Type > other.common.Type:
+		return 1
+	case tr.packFailed && other.packFailed:
+		return 0
+	case tr.packFailed:
+		return 1
+	case other.packFailed:
+		return -1
+	}
+	// bytes.Compare already treats the longer of two otherwise-equal slices
+	// as the greater
+	return bytes.Compare(tr.rdata, other.rdata)
+}
+
+func sortedForTiebreak(rrs []DNSResourceRecord) []tiebreakRecord {
+	sorted := make([]tiebreakRecord, len(rrs))
+	for i, rr := range rrs {
+		sorted[i] = newTiebreakRecord(rr)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].compare(sorted[j]) < 0
+	})
+	return sorted
+}
@@ -0,0 +1,109 @@
+package rawmdns
+
+import (
+	"net"
+	"testing"
+)
+
+func newCompareTestA(addr string) ARecord {
+	return ARecord{
+		Common: ResourceRecordCommon{Domain: "cheshire.local", Type: TypeA, Class: ClassINET, TTL: 120},
+		Addr:   net.ParseIP(addr).To4(),
+	}
+}
+
+func TestCompare(t *testing.T) {
+	flushA := newCompareTestA("169.254.99.200")
+	flushA.Common.CacheFlush = true
+	anyClassA := newCompareTestA("169.254.99.200")
+	anyClassA.Common.Class = ClassANY
+	aaaa := AAAARecord{
+		Common: ResourceRecordCommon{Domain: "cheshire.local", Type: TypeAAAA, Class: ClassINET, TTL: 120},
+		Addr:   net.ParseIP("fe80::1"),
+	}
+	short := UnknownRecord{
+		Common: ResourceRecordCommon{Domain: "cheshire.local", Type: 65280, Class: ClassINET},
+		RData:  []byte{1, 2},
+	}
+	long := short
+	long.RData = []byte{1, 2, 0}
+
+	testCases := []struct {
+		name     string
+		a, b     DNSResourceRecord
+		expected int
+	}{
+		// This is the example from RFC 6762 section 8.2
+		{"rdata", newCompareTestA("169.254.99.200"), newCompareTestA("169.254.200.50"), -1},
+		{"rdata reversed", newCompareTestA("169.254.200.50"), newCompareTestA("169.254.99.200"), 1},
+		{"cache-flush ignored", flushA, newCompareTestA("169.254.99.200"), 0},
+		{"class before type", anyClassA, aaaa, 1},
+		{"type before rdata", aaaa, newCompareTestA("169.254.200.50"), 1},
+		{"longer rdata later", long, short, 1},
+	}
+	for _, tc := range testCases {
+		if c := Compare(tc.a, tc.b); c != tc.expected {
+			t.Errorf("%s: Compare returned %d, expected %d", tc.name, c, tc.expected)
+		}
+	}
+}
+
+func TestCompareRecordSets(t *testing.T) {
+	a1 := newCompareTestA("169.254.1.1")
+	a2 := newCompareTestA("169.254.2.2")
+	a3 := newCompareTestA("169.254.3.3")
+
+	testCases := []struct {
+		name          string
+		ours, theirs  []DNSResourceRecord
+		expected      int
+		expectedOrder []DNSResourceRecord
+	}{
+		{"identical", []DNSResourceRecord{a2, a1}, []DNSResourceRecord{a1, a2}, 0, []DNSResourceRecord{a2, a1}},
+		{"first difference decides", []DNSResourceRecord{a1, a3}, []DNSResourceRecord{a2, a1}, 1, []DNSResourceRecord{a1, a3}},
+		{"remaining records win", []DNSResourceRecord{a1, a2}, []DNSResourceRecord{a2, a1, a3}, -1, []DNSResourceRecord{a1, a2}},
+	}
+	for _, tc := range testCases {
+		if c := CompareRecordSets(tc.ours, tc.theirs); c != tc.expected {
+			t.Errorf("%s: CompareRecordSets returned %d, expected %d", tc.name, c, tc.expected)
+		}
+		for i := range tc.ours {
+			if Compare(tc.ours[i], tc.expectedOrder[i]) != 0 {
+				t.Errorf("%s: input slice was reordered", tc.name)
+				break
+			}
+		}
+	}
+}
+
+func TestCompare_packError(t *testing.T) {
+	// a NextHashedOwner that isn't base32hex can't be packed
+	newBad := func(next string) NSEC3Record {
+		return NSEC3Record{
+			Common:          ResourceRecordCommon{Domain: "cheshire.local", Type: TypeNSEC3, Class: ClassINET, TTL: 120},
+			NextHashedOwner: next,
+		}
+	}
+	bad := newBad("not base32hex!")
+	good := newBad("")
+	a := newCompareTestA("169.254.99.200")
+
+	testCases := []struct {
+		name     string
+		a, b     DNSResourceRecord
+		expected int
+	}{
+		{"both unpackable", bad, newBad("also not base32hex!"), 0},
+		{"unpackable later", bad, good, 1},
+		{"unpackable later reversed", good, bad, -1},
+		{"type still decides", a, bad, -1},
+	}
+	for _, tc := range testCases {
+		if c := Compare(tc.a, tc.b); c != tc.expected {
+			t.Errorf("%s: Compare returned %d, expected %d", tc.name, c, tc.expected)
+		}
+	}
+	if c := CompareRecordSets([]DNSResourceRecord{bad, a}, []DNSResourceRecord{good, a}); c != 1 {
+		t.Errorf("CompareRecordSets returned %d, expected 1", c)
+	}
+}
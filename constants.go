@@ -33,16 +33,28 @@ const (
 	TypeA RecordType = 1
 	// TypeNS is the typecode for a Nameserver record. These are unused in mDNS. See also: RFC 1035
 	TypeNS RecordType = 2
+	// TypeMD is an obsolete typecode for a mail destination, replaced by MX. See also: RFC 1035
+	TypeMD RecordType = 3
+	// TypeMF is an obsolete typecode for a mail forwarder, replaced by MX. See also: RFC 1035
+	TypeMF RecordType = 4
 	// TypeCNAME is the typecode for a CNAME record, which is an alias of one name/record to another. See also: RFC 1035
 	TypeCNAME RecordType = 5
 	// TypeSOA is the typecode for start-of-authority record. These are unused in mDNS. See also: RFC 1035/2308
 	TypeSOA RecordType = 6 // unused by mdns
+	// TypeMB is an experimental typecode for a mailbox domain name. See also: RFC 1035
+	TypeMB RecordType = 7
+	// TypeMG is an experimental typecode for a mail group member. See also: RFC 1035
+	TypeMG RecordType = 8
+	// TypeMR is an experimental typecode for a mail rename domain name. See also: RFC 1035
+	TypeMR RecordType = 9
 	// TypeWKS is an obsolete typecode for the "Well Known Service" record type. See also: RFC 1123
 	TypeWKS RecordType = 11
 	// TypePTR is the typecode for a Pointer record, which is a reverse-lookup pointer mapping a string to another record. See also: RFC 1035
 	TypePTR RecordType = 12
 	// TypeHINFO is the typecode for a HostInfo record, which contains information about a given hosts CPU and OS. See also: RFC 1010 / 1035
 	TypeHINFO RecordType = 13
+	// TypeMINFO is an experimental typecode for mailbox or mail list information. See also: RFC 1035
+	TypeMINFO RecordType = 14
 	// TypeMX is the typecode for a Mail-eXchange record, which associates a DNS domain with an MTA or MTAs. See also: RFC 1035 / 7505
 	TypeMX RecordType = 15
 	// TypeTXT is the typecode for a TXT record, which has a special RDATA format for DNS-SD. See also: RFC 6763
@@ -53,7 +65,7 @@ const (
 	TypeAFSDB RecordType = 18
 	// TypeX25 is an obsolete typecode, dead along with the X.25 suite.
 	TypeX25 RecordType = 19
-	// TypeNSAPPTR is undocumented; this is only here to shut up the linter. Happy Googling!
+	// TypeNSAPPTR is an obsolete typecode for a pointer from an NSAP address to a domain name. See also: RFC 1348
 	TypeNSAPPTR RecordType = 23
 	// TypeSIG is the typecode for a signature record. This is used to sign/verify messages. See also: RFC 2535 / 2930 / 2931
 	TypeSIG RecordType = 24
@@ -61,6 +73,8 @@ const (
 	TypeKEY RecordType = 25
 	// TypeAAAA is the typecode for an IPv6 address record. See also: RFC 3596
 	TypeAAAA RecordType = 28
+	// TypeLOC is the typecode for a location record, which gives the geographic position and size of a host or network. See also: RFC 1876
+	TypeLOC RecordType = 29
 	// TypeNXT is the typecode for a NXT record, which is obsolesced by the NSEC record type. See also: RFC 4034
 	TypeNXT RecordType = 30
 	// TypeNIMLOC is the typecode for a "NIMROD locator" record, which is an obsolete type. See also: https://tools.ietf.org/html/draft-ietf-nimrod-dns-0
@@ -73,6 +87,8 @@ const (
 	TypeKX RecordType = 36
 	// TypeCERT is the typecode for a certificate record. See also: RFC 4398
 	TypeCERT RecordType = 37
+	// TypeDNAME is the typecode for a DNAME record, which redirects an entire subtree of the namespace to another domain. See also: RFC 6672
+	TypeDNAME RecordType = 39
 	// TypeOPT is the typecode for an OPT record, which is used for various DNS extensions (aka EDNS). See also: RFC 6891
 	TypeOPT RecordType = 41
 	// TypeDS is the typecode for a "delegation signer" record, used for DNSSEC with delegated zones. See also: RFC 4034
@@ -93,6 +109,26 @@ const (
 	TypeNSEC3PARAM RecordType = 51
 	// TypeTLSA is the typecode for a "TLSA certificate association" record, which is used for DNS-based Authentication of Named Entities (aka DANE). See also: RFC 6698
 	TypeTLSA RecordType = 52
+	// TypeSMIMEA is the typecode for an "S/MIME certificate association" record, which binds an S/MIME certificate to an email address. See also: RFC 8162
+	TypeSMIMEA RecordType = 53
+	// TypeOPENPGPKEY is the typecode for a record publishing an OpenPGP transferable public key for an email address. See also: RFC 7929
+	TypeOPENPGPKEY RecordType = 61
+	// TypeSVCB is the typecode for a "service binding" record, which advertises the endpoints and parameters of a service. See also: RFC 9460
+	TypeSVCB RecordType = 64
+	// TypeHTTPS is the typecode for an HTTPS-specific service binding record. See also: RFC 9460
+	TypeHTTPS RecordType = 65
+	// TypeNID is the typecode for an ILNP node identifier record. See also: RFC 6742
+	TypeNID RecordType = 104
+	// TypeL32 is the typecode for an ILNP 32-bit locator record. See also: RFC 6742
+	TypeL32 RecordType = 105
+	// TypeL64 is the typecode for an ILNP 64-bit locator record. See also: RFC 6742
+	TypeL64 RecordType = 106
+	// TypeLP is the typecode for an ILNP locator pointer record, which names a host holding L32/L64 records. See also: RFC 6742
+	TypeLP RecordType = 107
+	// TypeEUI48 is the typecode for a record holding a 48-bit Extended Unique Identifier, i.e. a MAC address. See also: RFC 7043
+	TypeEUI48 RecordType = 108
+	// TypeEUI64 is the typecode for a record holding a 64-bit Extended Unique Identifier. See also: RFC 7043
+	TypeEUI64 RecordType = 109
 	// TypeTKEY is the typecode for a transaction key record, which provides keying material to be used with a TSIG record. See also: RFC 2930
 	TypeTKEY RecordType = 249
 	// TypeTSIG is the typecode for a transaction signature record, which can be used to authenticate dynamic DNS updates. See also: RFC 2845
@@ -103,10 +139,17 @@ const (
 	TypeAXFR RecordType = 252
 	// TypeANY is special typecode used in queries asking for any/all resource-records matching a given domain name.
 	TypeANY RecordType = 255
+	// TypeURI is the typecode for a URI record, which maps a service name to a URI. See also: RFC 7553
+	TypeURI RecordType = 256
+	// TypeCAA is the typecode for a "certification authority authorization" record, which restricts which CAs may issue certificates for a domain. See also: RFC 8659
+	TypeCAA RecordType = 257
 )
 
 // ClassINET is the only DNS message class regularly used on the internet.
 const ClassINET RecordClass = 1
+
+// ClassANY is the wildcard class, used in queries and by meta-records such as TSIG.
+const ClassANY RecordClass = 255
 const (
 	// CodeSuccess comment only here to shut the linter up, see RFC 1035 for real information.
 	CodeSuccess ResponseCode = 0
@@ -66,6 +66,16 @@ func (d *Decoder) DecodeDNSMessage() (DNSMessage, error) {
 		dm.Answers = append(dm.Answers, drr)
 	}
 
+	for i := 0; i < int(dm.Hdr.NumNameServers); i++ {
+		var drr DNSResourceRecord
+		var err error
+		drr, err = d.nextResourceRecord()
+		if err != nil {
+			return dm, fmt.Errorf("nextResourceRecord: %s\n", err)
+		}
+		dm.Authority = append(dm.Authority, drr)
+	}
+
 	for i := 0; i < int(dm.Hdr.NumAddlRecords); i++ {
 		var drr DNSResourceRecord
 		var err error
@@ -123,6 +133,11 @@ func (d *Decoder) _nextRawLabelsFromReaderWithBaseOffset(rdr io.Reader, baseOffs
 			}
 			cursor += bytesRead
 			lRec.targetOffset = uint16(((uint16(lRec.length) & 0x3F) << 8) + uint16(buf[0]))
+			// A pointer must lead to a label of an earlier name; anything
+			// else could be a loop
+			if int(lRec.targetOffset) >= baseOffset || !d.hasLabelAt(lRec.targetOffset) {
+				return nil, fmt.Errorf("Illegal compression pointer to 0x%X", lRec.targetOffset)
+			}
 			rlList = append(rlList, d.rawLabelsFromOffset(lRec.targetOffset)...)
 
 			lRec.length = 0
@@ -151,12 +166,27 @@ func (d *Decoder) _nextRawLabelsFromReaderWithBaseOffset(rdr io.Reader, baseOffs
 	return rlList, nil
 }
 
+func (d Decoder) hasLabelAt(off uint16) bool {
+	for _, lr := range d.labelRecords {
+		if lr.offset == off {
+			return true
+		}
+	}
+	return false
+}
+
+// rawLabelsFromOffset returns the labels of the name starting at off, which
+// must be the offset of a label already decoded.
 func (d Decoder) rawLabelsFromOffset(off uint16) rawLabels {
 	var rawLabels rawLabels
-	for _, lr := range []labelRecord(d.labelRecords) {
-		if lr.offset < off {
-			continue
+	start := len(d.labelRecords)
+	for i, lr := range d.labelRecords {
+		if lr.offset == off {
+			start = i
+			break
 		}
+	}
+	for _, lr := range d.labelRecords[start:] {
 		if lr.isPtr {
 			rawLabels = append(rawLabels, d.rawLabelsFromOffset(lr.targetOffset)...)
 			break
@@ -229,40 +259,33 @@ func (d *Decoder) nextResourceRecord() (DNSResourceRecord, error) {
 }
 
 func (d *Decoder) rawRRtoDNSResourceRecord(rdrr rawResourceRecord) (DNSResourceRecord, error) {
-	switch rdrr.static.Type {
-	case TypeA:
-		return d.newARecordFromRawRR(rdrr), nil
-	case TypeAAAA:
-		return d.newAAAARecordFromRawRR(rdrr), nil
-	case TypeSRV:
-		return d.newSRVRecordFromRawRR(rdrr)
-	case TypePTR:
-		return d.newPTRRecordFromRawRR(rdrr)
-	case TypeTXT:
-		return d.newTXTRecordFromRawRR(rdrr), nil
-	case TypeNSEC:
-		return d.newNSECRecordFromRawRR(rdrr)
-	case TypeOPT:
-		return d.newOPTRecordFromRawRR(rdrr), nil
-	default:
-		return nil, fmt.Errorf("Unhandled RR type: %d", rdrr.static.Type)
-	}
+	rr := recordForType(rdrr.static.Type)
+	return rr.UnpackRData(RData{rrr: rdrr, d: d})
 }
 
-func (d *Decoder) newARecordFromRawRR(rdrr rawResourceRecord) ARecord {
+func (d *Decoder) newARecordFromRawRR(rdrr rawResourceRecord) (ARecord, error) {
 	a := ARecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) != 4 {
+		return a, fmt.Errorf("TypeA: RDATA must be 4 bytes, got %d", len(rdrr.rData))
+	}
 	a.Addr = net.IP(rdrr.rData[0:4])
-	return a
+	return a, nil
 }
 
-func (d *Decoder) newAAAARecordFromRawRR(rdrr rawResourceRecord) AAAARecord {
+func (d *Decoder) newAAAARecordFromRawRR(rdrr rawResourceRecord) (AAAARecord, error) {
 	a := AAAARecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) != 16 {
+		return a, fmt.Errorf("TypeAAAA: RDATA must be 16 bytes, got %d", len(rdrr.rData))
+	}
 	a.Addr = net.IP(rdrr.rData[0:16])
-	return a
+	return a, nil
 }
 
 func (d *Decoder) newSRVRecordFromRawRR(rdrr rawResourceRecord) (SRVRecord, error) {
 	s := SRVRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 7 {
+		return s, fmt.Errorf("TypeSRV: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
 	s.Priority = binary.BigEndian.Uint16(rdrr.rData[0:2])
 	s.Weight = binary.BigEndian.Uint16(rdrr.rData[2:4])
 	s.Port = binary.BigEndian.Uint16(rdrr.rData[4:6])
@@ -330,6 +353,470 @@ func (d *Decoder) newNSECRecordFromRawRR(rdrr rawResourceRecord) (NSECRecord, er
 		return n, fmt.Errorf("TypePTR: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
 	}
 	n.NextDomainName = rlList.toDomain()
+	n.NextDomainTypes = readTypeBitMap(rdr)
+
+	return n, nil
+}
+
+func (d *Decoder) newNSEC3RecordFromRawRR(rdrr rawResourceRecord) (NSEC3Record, error) {
+	n := NSEC3Record{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 5 {
+		return n, fmt.Errorf("TypeNSEC3: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	n.HashAlgorithm = rdrr.rData[0]
+	n.Flags = rdrr.rData[1]
+	n.Iterations = binary.BigEndian.Uint16(rdrr.rData[2:4])
+
+	salt, rest, err := readCharacterString(rdrr.rData[4:])
+	if err != nil {
+		return n, fmt.Errorf("TypeNSEC3: Salt: readCharacterString: %s", err)
+	}
+	n.Salt = salt
+
+	var nextHashed []byte
+	nextHashed, rest, err = readCharacterString(rest)
+	if err != nil {
+		return n, fmt.Errorf("TypeNSEC3: NextHashedOwner: readCharacterString: %s", err)
+	}
+	n.NextHashedOwner = strings.ToLower(nsec3Encoding.EncodeToString(nextHashed))
+	n.Types = readTypeBitMap(bytes.NewReader(rest))
+
+	return n, nil
+}
+
+func (d *Decoder) newNSEC3PARAMRecordFromRawRR(rdrr rawResourceRecord) (NSEC3PARAMRecord, error) {
+	n := NSEC3PARAMRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 5 {
+		return n, fmt.Errorf("TypeNSEC3PARAM: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	n.HashAlgorithm = rdrr.rData[0]
+	n.Flags = rdrr.rData[1]
+	n.Iterations = binary.BigEndian.Uint16(rdrr.rData[2:4])
+
+	salt, _, err := readCharacterString(rdrr.rData[4:])
+	if err != nil {
+		return n, fmt.Errorf("TypeNSEC3PARAM: Salt: readCharacterString: %s", err)
+	}
+	n.Salt = salt
+
+	return n, nil
+}
+
+func (d *Decoder) newSVCBRecordFromRawRR(rdrr rawResourceRecord) (SVCBRecord, error) {
+	s := SVCBRecord{Common: commonFromRawRR(rdrr)}
+	var err error
+	s.Priority, s.Target, s.Params, err = d.unpackSVCBRData(rdrr)
+	if err != nil {
+		return s, fmt.Errorf("TypeSVCB: %s", err)
+	}
+	return s, nil
+}
+
+func (d *Decoder) newHTTPSRecordFromRawRR(rdrr rawResourceRecord) (HTTPSRecord, error) {
+	h := HTTPSRecord{Common: commonFromRawRR(rdrr)}
+	var err error
+	h.Priority, h.Target, h.Params, err = d.unpackSVCBRData(rdrr)
+	if err != nil {
+		return h, fmt.Errorf("TypeHTTPS: %s", err)
+	}
+	return h, nil
+}
+
+func (d *Decoder) unpackSVCBRData(rdrr rawResourceRecord) (uint16, string, []SvcParam, error) {
+	if len(rdrr.rData) < 3 {
+		return 0, "", nil, fmt.Errorf("RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	priority := binary.BigEndian.Uint16(rdrr.rData[0:2])
+
+	// "target" field starts at byte 2 in the RDATA section
+	rdr := bytes.NewReader(rdrr.rData[2:])
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg+2)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("_nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+
+	rest := rdrr.rData[len(rdrr.rData)-rdr.Len():]
+	params, err := unpackSvcParams(rest)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("unpackSvcParams: %s", err)
+	}
+
+	return priority, rlList.toDomain(), params, nil
+}
+
+func (d *Decoder) newNAPTRRecordFromRawRR(rdrr rawResourceRecord) (NAPTRRecord, error) {
+	n := NAPTRRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 4 {
+		return n, fmt.Errorf("TypeNAPTR: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	n.Order = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	n.Preference = binary.BigEndian.Uint16(rdrr.rData[2:4])
+
+	rest := rdrr.rData[4:]
+	for _, field := range []*string{&n.Flags, &n.Service, &n.Regexp} {
+		var cs []byte
+		var err error
+		cs, rest, err = readCharacterString(rest)
+		if err != nil {
+			return n, fmt.Errorf("TypeNAPTR: readCharacterString: %s", err)
+		}
+		*field = string(cs)
+	}
+
+	// The replacement field must not be compressed, but decoding it through
+	// the label machinery is harmless and keeps it available as a
+	// compression target for later records
+	replacementOffsetInMsg := rdrr.rDataOffsetInMsg + len(rdrr.rData) - len(rest)
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(bytes.NewReader(rest), replacementOffsetInMsg)
+	if err != nil {
+		return n, fmt.Errorf("TypeNAPTR: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	n.Replacement = rlList.toDomain()
+
+	return n, nil
+}
+
+func (d *Decoder) newURIRecordFromRawRR(rdrr rawResourceRecord) (URIRecord, error) {
+	u := URIRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 5 {
+		return u, fmt.Errorf("TypeURI: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	u.Priority = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	u.Weight = binary.BigEndian.Uint16(rdrr.rData[2:4])
+	u.Target = string(rdrr.rData[4:])
+	return u, nil
+}
+
+func (d *Decoder) newSSHFPRecordFromRawRR(rdrr rawResourceRecord) (SSHFPRecord, error) {
+	s := SSHFPRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 2 {
+		return s, fmt.Errorf("TypeSSHFP: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	s.Algorithm = rdrr.rData[0]
+	s.FingerprintType = rdrr.rData[1]
+	s.Fingerprint = rdrr.rData[2:]
+	return s, nil
+}
+
+func (d *Decoder) newTLSARecordFromRawRR(rdrr rawResourceRecord) (TLSARecord, error) {
+	t := TLSARecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 3 {
+		return t, fmt.Errorf("TypeTLSA: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	t.Usage = rdrr.rData[0]
+	t.Selector = rdrr.rData[1]
+	t.MatchingType = rdrr.rData[2]
+	t.Data = rdrr.rData[3:]
+	return t, nil
+}
+
+func (d *Decoder) newSMIMEARecordFromRawRR(rdrr rawResourceRecord) (SMIMEARecord, error) {
+	s := SMIMEARecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 3 {
+		return s, fmt.Errorf("TypeSMIMEA: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	s.Usage = rdrr.rData[0]
+	s.Selector = rdrr.rData[1]
+	s.MatchingType = rdrr.rData[2]
+	s.Data = rdrr.rData[3:]
+	return s, nil
+}
+
+func (d *Decoder) newOPENPGPKEYRecordFromRawRR(rdrr rawResourceRecord) OPENPGPKEYRecord {
+	o := OPENPGPKEYRecord{Common: commonFromRawRR(rdrr)}
+	o.PublicKey = rdrr.rData
+	return o
+}
+
+func (d *Decoder) newCAARecordFromRawRR(rdrr rawResourceRecord) (CAARecord, error) {
+	c := CAARecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 2 {
+		return c, fmt.Errorf("TypeCAA: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	c.Flags = rdrr.rData[0]
+	tag, rest, err := readCharacterString(rdrr.rData[1:])
+	if err != nil {
+		return c, fmt.Errorf("TypeCAA: Tag: readCharacterString: %s", err)
+	}
+	c.Tag = string(tag)
+	c.Value = rest
+	return c, nil
+}
+
+func (d *Decoder) newTSIGRecordFromRawRR(rdrr rawResourceRecord) (TSIGRecord, error) {
+	t := TSIGRecord{Common: commonFromRawRR(rdrr)}
+
+	rdr := bytes.NewReader(rdrr.rData)
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg)
+	if err != nil {
+		return t, fmt.Errorf("TypeTSIG: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	t.Algorithm = rlList.toDomain()
+
+	rest := rdrr.rData[len(rdrr.rData)-rdr.Len():]
+	if len(rest) < 10 {
+		return t, fmt.Errorf("TypeTSIG: RDATA too short after algorithm name (%d bytes)", len(rest))
+	}
+	t.TimeSigned = uint64(binary.BigEndian.Uint16(rest[0:2]))<<32 | uint64(binary.BigEndian.Uint32(rest[2:6]))
+	t.Fudge = binary.BigEndian.Uint16(rest[6:8])
+	macLen := int(binary.BigEndian.Uint16(rest[8:10]))
+	rest = rest[10:]
+	if len(rest) < macLen+6 {
+		return t, fmt.Errorf("TypeTSIG: MAC length %d exceeds RDATA", macLen)
+	}
+	t.MAC = rest[:macLen]
+	rest = rest[macLen:]
+	t.OriginalID = binary.BigEndian.Uint16(rest[0:2])
+	t.Error = binary.BigEndian.Uint16(rest[2:4])
+	otherLen := int(binary.BigEndian.Uint16(rest[4:6]))
+	rest = rest[6:]
+	if len(rest) < otherLen {
+		return t, fmt.Errorf("TypeTSIG: Other Len %d exceeds RDATA", otherLen)
+	}
+	t.OtherData = rest[:otherLen]
+
+	return t, nil
+}
+
+func (d *Decoder) newLOCRecordFromRawRR(rdrr rawResourceRecord) (LOCRecord, error) {
+	l := LOCRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 16 {
+		return l, fmt.Errorf("TypeLOC: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	l.Version = rdrr.rData[0]
+	if l.Version != 0 {
+		return l, fmt.Errorf("TypeLOC: unsupported version %d", l.Version)
+	}
+	l.Size = rdrr.rData[1]
+	l.HorizPre = rdrr.rData[2]
+	l.VertPre = rdrr.rData[3]
+	if err := l.checkPrecisions(); err != nil {
+		return l, fmt.Errorf("TypeLOC: %s", err)
+	}
+	l.Latitude = locAngleFromWire(binary.BigEndian.Uint32(rdrr.rData[4:8]))
+	l.Longitude = locAngleFromWire(binary.BigEndian.Uint32(rdrr.rData[8:12]))
+	l.Altitude = locAltitudeFromWire(binary.BigEndian.Uint32(rdrr.rData[12:16]))
+	return l, nil
+}
+
+func (d *Decoder) newCERTRecordFromRawRR(rdrr rawResourceRecord) (CERTRecord, error) {
+	c := CERTRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 5 {
+		return c, fmt.Errorf("TypeCERT: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	c.CertType = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	c.KeyTag = binary.BigEndian.Uint16(rdrr.rData[2:4])
+	c.Algorithm = rdrr.rData[4]
+	c.Certificate = rdrr.rData[5:]
+	return c, nil
+}
+
+func (d *Decoder) newIPSECKEYRecordFromRawRR(rdrr rawResourceRecord) (IPSECKEYRecord, error) {
+	i := IPSECKEYRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 3 {
+		return i, fmt.Errorf("TypeIPSECKEY: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	i.Precedence = rdrr.rData[0]
+	i.GatewayType = rdrr.rData[1]
+	i.Algorithm = rdrr.rData[2]
+	rest := rdrr.rData[3:]
+
+	switch i.GatewayType {
+	case IPSECKEYGatewayNone:
+	case IPSECKEYGatewayIPv4:
+		if len(rest) < 4 {
+			return i, fmt.Errorf("TypeIPSECKEY: truncated IPv4 gateway")
+		}
+		i.GatewayAddr = net.IP(rest[0:4])
+		rest = rest[4:]
+	case IPSECKEYGatewayIPv6:
+		if len(rest) < 16 {
+			return i, fmt.Errorf("TypeIPSECKEY: truncated IPv6 gateway")
+		}
+		i.GatewayAddr = net.IP(rest[0:16])
+		rest = rest[16:]
+	case IPSECKEYGatewayDomain:
+		// The gateway name must not be compressed, but decoding it through
+		// the label machinery is harmless
+		rdr := bytes.NewReader(rest)
+		rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg+3)
+		if err != nil {
+			return i, fmt.Errorf("TypeIPSECKEY: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+		}
+		i.GatewayDomain = rlList.toDomain()
+		rest = rest[len(rest)-rdr.Len():]
+	default:
+		return i, fmt.Errorf("TypeIPSECKEY: unknown gateway type %d", i.GatewayType)
+	}
+	i.PublicKey = rest
+
+	return i, nil
+}
+
+func (d *Decoder) newKXRecordFromRawRR(rdrr rawResourceRecord) (KXRecord, error) {
+	k := KXRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 3 {
+		return k, fmt.Errorf("TypeKX: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	k.Preference = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	rdr := bytes.NewReader(rdrr.rData[2:])
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg+2)
+	if err != nil {
+		return k, fmt.Errorf("TypeKX: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	k.Exchanger = rlList.toDomain()
+	return k, nil
+}
+
+func (d *Decoder) newWKSRecordFromRawRR(rdrr rawResourceRecord) (WKSRecord, error) {
+	w := WKSRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 5 {
+		return w, fmt.Errorf("TypeWKS: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	w.Address = net.IP(rdrr.rData[0:4])
+	w.Protocol = rdrr.rData[4]
+	for octetNum, octet := range rdrr.rData[5:] {
+		for bitNum := 0; bitNum < 8; bitNum++ {
+			if octet&(0x80>>uint(bitNum)) != 0 {
+				w.Ports = append(w.Ports, uint16(octetNum*8+bitNum))
+			}
+		}
+	}
+	return w, nil
+}
+
+func (d *Decoder) newRPRecordFromRawRR(rdrr rawResourceRecord) (RPRecord, error) {
+	r := RPRecord{Common: commonFromRawRR(rdrr)}
+	rdr := bytes.NewReader(rdrr.rData)
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg)
+	if err != nil {
+		return r, fmt.Errorf("TypeRP: Mailbox: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	r.Mailbox = rlList.toDomain()
+
+	txtOffsetInMsg := rdrr.rDataOffsetInMsg + len(rdrr.rData) - rdr.Len()
+	rlList, err = d._nextRawLabelsFromReaderWithBaseOffset(rdr, txtOffsetInMsg)
+	if err != nil {
+		return r, fmt.Errorf("TypeRP: TXTDomain: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	r.TXTDomain = rlList.toDomain()
+	return r, nil
+}
+
+func (d *Decoder) newAFSDBRecordFromRawRR(rdrr rawResourceRecord) (AFSDBRecord, error) {
+	a := AFSDBRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 3 {
+		return a, fmt.Errorf("TypeAFSDB: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	a.Subtype = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	rdr := bytes.NewReader(rdrr.rData[2:])
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg+2)
+	if err != nil {
+		return a, fmt.Errorf("TypeAFSDB: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	a.Hostname = rlList.toDomain()
+	return a, nil
+}
+
+func (d *Decoder) newX25RecordFromRawRR(rdrr rawResourceRecord) (X25Record, error) {
+	x := X25Record{Common: commonFromRawRR(rdrr)}
+	addr, _, err := readCharacterString(rdrr.rData)
+	if err != nil {
+		return x, fmt.Errorf("TypeX25: readCharacterString: %s", err)
+	}
+	x.PSDNAddress = string(addr)
+	return x, nil
+}
+
+func (d *Decoder) newNSAPPTRRecordFromRawRR(rdrr rawResourceRecord) (NSAPPTRRecord, error) {
+	n := NSAPPTRRecord{Common: commonFromRawRR(rdrr)}
+	rdr := bytes.NewReader(rdrr.rData)
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg)
+	if err != nil {
+		return n, fmt.Errorf("TypeNSAPPTR: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	n.PtrDName = rlList.toDomain()
+	return n, nil
+}
+
+func (d *Decoder) newDNAMERecordFromRawRR(rdrr rawResourceRecord) (DNAMERecord, error) {
+	dn := DNAMERecord{Common: commonFromRawRR(rdrr)}
+	rdr := bytes.NewReader(rdrr.rData)
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg)
+	if err != nil {
+		return dn, fmt.Errorf("TypeDNAME: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	dn.Target = rlList.toDomain()
+	return dn, nil
+}
+
+func (d *Decoder) newEUI48RecordFromRawRR(rdrr rawResourceRecord) (EUI48Record, error) {
+	e := EUI48Record{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) != 6 {
+		return e, fmt.Errorf("TypeEUI48: RDATA must be 6 bytes, got %d", len(rdrr.rData))
+	}
+	e.Address = net.HardwareAddr(rdrr.rData)
+	return e, nil
+}
+
+func (d *Decoder) newEUI64RecordFromRawRR(rdrr rawResourceRecord) (EUI64Record, error) {
+	e := EUI64Record{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) != 8 {
+		return e, fmt.Errorf("TypeEUI64: RDATA must be 8 bytes, got %d", len(rdrr.rData))
+	}
+	e.Address = net.HardwareAddr(rdrr.rData)
+	return e, nil
+}
+
+func (d *Decoder) newNIDRecordFromRawRR(rdrr rawResourceRecord) (NIDRecord, error) {
+	n := NIDRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) != 10 {
+		return n, fmt.Errorf("TypeNID: RDATA must be 10 bytes, got %d", len(rdrr.rData))
+	}
+	n.Preference = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	n.NodeID = binary.BigEndian.Uint64(rdrr.rData[2:10])
+	return n, nil
+}
+
+func (d *Decoder) newL32RecordFromRawRR(rdrr rawResourceRecord) (L32Record, error) {
+	l := L32Record{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) != 6 {
+		return l, fmt.Errorf("TypeL32: RDATA must be 6 bytes, got %d", len(rdrr.rData))
+	}
+	l.Preference = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	l.Locator32 = net.IP(rdrr.rData[2:6])
+	return l, nil
+}
+
+func (d *Decoder) newL64RecordFromRawRR(rdrr rawResourceRecord) (L64Record, error) {
+	l := L64Record{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) != 10 {
+		return l, fmt.Errorf("TypeL64: RDATA must be 10 bytes, got %d", len(rdrr.rData))
+	}
+	l.Preference = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	l.Locator64 = binary.BigEndian.Uint64(rdrr.rData[2:10])
+	return l, nil
+}
+
+func (d *Decoder) newLPRecordFromRawRR(rdrr rawResourceRecord) (LPRecord, error) {
+	l := LPRecord{Common: commonFromRawRR(rdrr)}
+	if len(rdrr.rData) < 3 {
+		return l, fmt.Errorf("TypeLP: RDATA too short (%d bytes)", len(rdrr.rData))
+	}
+	l.Preference = binary.BigEndian.Uint16(rdrr.rData[0:2])
+	rdr := bytes.NewReader(rdrr.rData[2:])
+	rlList, err := d._nextRawLabelsFromReaderWithBaseOffset(rdr, rdrr.rDataOffsetInMsg+2)
+	if err != nil {
+		return l, fmt.Errorf("TypeLP: _nextRawLabelsFromReaderWithBaseOffset: %s", err)
+	}
+	l.FQDN = rlList.toDomain()
+	return l, nil
+}
+
+// readTypeBitMap decodes the "Type Bit Maps" field shared by NSEC and NSEC3
+// records (RFC 4034 section 4.1.2), consuming rdr until it is exhausted.
+func readTypeBitMap(rdr *bytes.Reader) []RecordType {
+	var types []RecordType
 
 StopLoop:
 	for {
@@ -364,15 +851,15 @@ StopLoop:
 			for bitNum = 0; bitNum < 8; bitNum++ {
 				if (octet<<bitNum)&0x80 == 0x80 {
 					typ := RecordType((typeGroup * 256) + (octetNum * 8) + int(bitNum))
-					n.NextDomainTypes = append(n.NextDomainTypes, typ)
+					types = append(types, typ)
 				}
 			}
 		}
 	}
 
-	sort.Sort(recordTypes(n.NextDomainTypes))
+	sort.Sort(recordTypes(types))
 
-	return n, nil
+	return types
 }
 
 func (d *Decoder) newOPTRecordFromRawRR(rdrr rawResourceRecord) OPTRecord {
@@ -417,6 +904,22 @@ func (d *Decoder) newOPTRecordFromRawRR(rdrr rawResourceRecord) OPTRecord {
 	return o
 }
 
+// readCharacterString splits a single length-prefixed <character-string>
+// (RFC 1035 section 3.3) from the front of b, returning its contents and
+// whatever follows it.
+func readCharacterString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 1 {
+		return nil, nil, fmt.Errorf("missing length octet")
+	}
+	length := int(b[0])
+	if len(b) < 1+length {
+		return nil, nil, fmt.Errorf("length %d exceeds remaining %d bytes", length, len(b)-1)
+	}
+	content := make([]byte, length)
+	copy(content, b[1:1+length])
+	return content, b[1+length:], nil
+}
+
 type labelRecord struct {
 	offset       uint16
 	length       uint8
@@ -459,9 +962,26 @@ func (rlList rawLabels) toBytes() []byte {
 
 type domain string
 
+// toCanonicalBytes returns the uncompressed, lower-cased wire format of the
+// domain as used by DNSSEC (RFC 4034 section 6.2). A trailing dot is ignored,
+// and "" or "." is the root.
+func (d domain) toCanonicalBytes() []byte {
+	return domain(strings.ToLower(string(d))).toRawLabels().toBytes()
+}
+
+// toRawLabels splits the domain into its labels. A trailing dot is ignored,
+// so "foo.local." and "foo.local" are the same name, and "" or "." is the
+// root, which has no labels at all, only the terminating 0-length label added
+// by rawLabels.toBytes. Splitting "x." or "." naively on dots would give an
+// extra empty label, which would be written as a second, invalid, 0-length
+// label.
 func (d domain) toRawLabels() rawLabels {
 	var rlList rawLabels
-	for _, s := range strings.Split(string(d), ".") {
+	name := strings.TrimSuffix(string(d), ".")
+	if name == "" {
+		return rlList
+	}
+	for _, s := range strings.Split(name, ".") {
 		rlList = append(rlList, rawLabel{
 			length:  uint8(len(s)),
 			content: s,
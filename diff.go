@@ -0,0 +1,287 @@
+package rawmdns
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MessageSection names a part of a DNSMessage in a Difference.
+type MessageSection string
+
+const (
+	// SectionHeader is the DNSHeader of a message.
+	SectionHeader MessageSection = "header"
+	// SectionQuestion is the Questions of a message.
+	SectionQuestion MessageSection = "question"
+	// SectionAnswer is the Answers of a message.
+	SectionAnswer MessageSection = "answer"
+	// SectionAuthority is the Authority records of a message.
+	SectionAuthority MessageSection = "authority"
+	// SectionAdditional is the Additional records of a message.
+	SectionAdditional MessageSection = "additional"
+)
+
+// DiffKind says how an item differs between the two messages given to
+// DiffMessages.
+type DiffKind int
+
+const (
+	// DiffChanged means the item is in both messages, but differs.
+	DiffChanged DiffKind = iota
+	// DiffMissing means the item is only in the first message.
+	DiffMissing
+	// DiffExtra means the item is only in the second message.
+	DiffExtra
+)
+
+func (dk DiffKind) String() string {
+	switch dk {
+	case DiffChanged:
+		return "changed"
+	case DiffMissing:
+		return "missing"
+	case DiffExtra:
+		return "extra"
+	default:
+		return fmt.Sprintf("DiffKind(%d)", int(dk))
+	}
+}
+
+// DiffOption changes how DiffMessages compares messages.
+type DiffOption int
+
+const (
+	// IgnoreRecordOrder matches up records within each section regardless of
+	// their position, rather than comparing them index by index.
+	IgnoreRecordOrder DiffOption = iota + 1
+)
+
+// Difference is a single difference found by DiffMessages. For the header,
+// Field names the DNSHeader field and A and B hold its values. Otherwise A
+// and B hold the DNSQuestion or DNSResourceRecord from each message, nil if
+// absent, and IndexA and IndexB their position in the section, -1 if absent.
+// Reasons holds the details of a changed question or record.
+type Difference struct {
+	Section MessageSection
+	Kind    DiffKind
+	Field   string
+	IndexA  int
+	IndexB  int
+	A       interface{}
+	B       interface{}
+	Reasons []string
+}
+
+func (d Difference) String() string {
+	if d.Section == SectionHeader {
+		return fmt.Sprintf("header %s: %v != %v", d.Field, d.A, d.B)
+	}
+	switch d.Kind {
+	case DiffMissing:
+		return fmt.Sprintf("%s[%d] missing: %v", d.Section, d.IndexA, d.A)
+	case DiffExtra:
+		return fmt.Sprintf("%s[%d] extra: %v", d.Section, d.IndexB, d.B)
+	default:
+		return fmt.Sprintf("%s[%d]/[%d] changed: %s", d.Section, d.IndexA, d.IndexB, strings.Join(d.Reasons, "; "))
+	}
+}
+
+// DiffMessages compares two messages and returns every difference between
+// them, or nil if they are the same. Unlike the Equal methods of the records,
+// it never panics when records of different types, pointers to records or
+// nil records are compared.
+func DiffMessages(a, b DNSMessage, opts ...DiffOption) []Difference {
+	ignoreOrder := false
+	for _, opt := range opts {
+		if opt == IgnoreRecordOrder {
+			ignoreOrder = true
+		}
+	}
+
+	diffs := diffHeaders(a.Hdr, b.Hdr)
+	diffs = append(diffs, diffQuestions(a.Questions, b.Questions)...)
+	sections := []struct {
+		section MessageSection
+		a, b    []DNSResourceRecord
+	}{
+		{SectionAnswer, a.Answers, b.Answers},
+		{SectionAuthority, a.Authority, b.Authority},
+		{SectionAdditional, a.Additional, b.Additional},
+	}
+	for _, s := range sections {
+		if ignoreOrder {
+			diffs = append(diffs, diffRecordsUnordered(s.section, s.a, s.b)...)
+		} else {
+			diffs = append(diffs, diffRecordsOrdered(s.section, s.a, s.b)...)
+		}
+	}
+	return diffs
+}
+
+func diffHeaders(a, b DNSHeader) []Difference {
+	var diffs []Difference
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < av.NumField(); i++ {
+		if av.Field(i).Interface() != bv.Field(i).Interface() {
+			diffs = append(diffs, Difference{
+				Section: SectionHeader,
+				Kind:    DiffChanged,
+				Field:   av.Type().Field(i).Name,
+				IndexA:  -1,
+				IndexB:  -1,
+				A:       av.Field(i).Interface(),
+				B:       bv.Field(i).Interface(),
+			})
+		}
+	}
+	return diffs
+}
+
+func diffQuestions(a, b []DNSQuestion) []Difference {
+	var diffs []Difference
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(b):
+			diffs = append(diffs, Difference{Section: SectionQuestion, Kind: DiffMissing, IndexA: i, IndexB: -1, A: a[i]})
+		case i >= len(a):
+			diffs = append(diffs, Difference{Section: SectionQuestion, Kind: DiffExtra, IndexA: -1, IndexB: i, B: b[i]})
+		case a[i] != b[i]:
+			var reasons []string
+			if a[i].Domain != b[i].Domain {
+				reasons = append(reasons, fmt.Sprintf("Domain: %q != %q", a[i].Domain, b[i].Domain))
+			}
+			if a[i].Type != b[i].Type {
+				reasons = append(reasons, fmt.Sprintf("Type: %d != %d", a[i].Type, b[i].Type))
+			}
+			if a[i].Class != b[i].Class {
+				reasons = append(reasons, fmt.Sprintf("Class: %d != %d", a[i].Class, b[i].Class))
+			}
+			if a[i].AcceptUnicastResponse != b[i].AcceptUnicastResponse {
+				reasons = append(reasons, fmt.Sprintf("AcceptUnicastResponse: %t != %t", a[i].AcceptUnicastResponse, b[i].AcceptUnicastResponse))
+			}
+			diffs = append(diffs, Difference{
+				Section: SectionQuestion,
+				Kind:    DiffChanged,
+				IndexA:  i,
+				IndexB:  i,
+				A:       a[i],
+				B:       b[i],
+				Reasons: reasons,
+			})
+		}
+	}
+	return diffs
+}
+
+func diffRecordsOrdered(section MessageSection, a, b []DNSResourceRecord) []Difference {
+	var diffs []Difference
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(b):
+			diffs = append(diffs, Difference{Section: section, Kind: DiffMissing, IndexA: i, IndexB: -1, A: a[i]})
+		case i >= len(a):
+			diffs = append(diffs, Difference{Section: section, Kind: DiffExtra, IndexA: -1, IndexB: i, B: b[i]})
+		default:
+			if same, reasons := safeRecordEqual(a[i], b[i]); !same {
+				diffs = append(diffs, Difference{
+					Section: section,
+					Kind:    DiffChanged,
+					IndexA:  i,
+					IndexB:  i,
+					A:       a[i],
+					B:       b[i],
+					Reasons: reasons,
+				})
+			}
+		}
+	}
+	return diffs
+}
+
+// diffRecordsUnordered first pairs off identical records, then pairs the
+// leftovers which share an owner name, type and class as changed records,
+// and reports whatever remains as missing or extra.
+func diffRecordsUnordered(section MessageSection, a, b []DNSResourceRecord) []Difference {
+	matchedA := make([]bool, len(a))
+	matchedB := make([]bool, len(b))
+	for i := range a {
+		for j := range b {
+			if matchedB[j] {
+				continue
+			}
+			if same, _ := safeRecordEqual(a[i], b[j]); same {
+				matchedA[i], matchedB[j] = true, true
+				break
+			}
+		}
+	}
+
+	var diffs []Difference
+	for i := range a {
+		if matchedA[i] {
+			continue
+		}
+		for j := range b {
+			ra, rb := derefRecord(a[i]), derefRecord(b[j])
+			if matchedB[j] || ra == nil || rb == nil || rrsetKeyFor(ra.GetCommon()) != rrsetKeyFor(rb.GetCommon()) {
+				continue
+			}
+			_, reasons := safeRecordEqual(a[i], b[j])
+			diffs = append(diffs, Difference{
+				Section: section,
+				Kind:    DiffChanged,
+				IndexA:  i,
+				IndexB:  j,
+				A:       a[i],
+				B:       b[j],
+				Reasons: reasons,
+			})
+			matchedA[i], matchedB[j] = true, true
+			break
+		}
+	}
+	for i := range a {
+		if !matchedA[i] {
+			diffs = append(diffs, Difference{Section: section, Kind: DiffMissing, IndexA: i, IndexB: -1, A: a[i]})
+		}
+	}
+	for j := range b {
+		if !matchedB[j] {
+			diffs = append(diffs, Difference{Section: section, Kind: DiffExtra, IndexA: -1, IndexB: j, B: b[j]})
+		}
+	}
+	return diffs
+}
+
+// safeRecordEqual wraps DNSResourceRecord.Equal, which panics when given a
+// record of a different type than its receiver, or nil. Pointers to records
+// are compared as the records they point to, and nil records, or nil
+// pointers, are equal only to each other.
+func safeRecordEqual(a, b DNSResourceRecord) (bool, []string) {
+	a, b = derefRecord(a), derefRecord(b)
+	if a == nil && b == nil {
+		return true, nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		reason := fmt.Sprintf("record type: %T != %T", a, b)
+		return false, []string{reason}
+	}
+	return a.Equal(b)
+}
+
+// derefRecord returns the record rr points to if it is a pointer to one, or
+// nil if it is a nil pointer.
+func derefRecord(rr DNSResourceRecord) DNSResourceRecord {
+	v := reflect.ValueOf(rr)
+	if v.Kind() != reflect.Ptr {
+		return rr
+	}
+	if v.IsNil() {
+		return nil
+	}
+	if elem, ok := v.Elem().Interface().(DNSResourceRecord); ok {
+		return elem
+	}
+	return rr
+}
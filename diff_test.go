@@ -0,0 +1,123 @@
+package rawmdns
+
+import (
+	"net"
+	"testing"
+)
+
+func newDiffTestMessage() DNSMessage {
+	return DNSMessage{
+		Hdr:       DNSHeader{IsResponse: true, Authoritative: true, NumQuestions: 1, NumAnswers: 2},
+		Questions: []DNSQuestion{{Domain: "printer.local", Type: TypeA, Class: ClassINET}},
+		Answers: []DNSResourceRecord{
+			ARecord{
+				Common: ResourceRecordCommon{Domain: "printer.local", Type: TypeA, Class: ClassINET, TTL: 120},
+				Addr:   net.ParseIP("169.254.1.1"),
+			},
+			PTRRecord{
+				Common:   ResourceRecordCommon{Domain: "_ipp._tcp.local", Type: TypePTR, Class: ClassINET, TTL: 4500},
+				PtrDName: "printer._ipp._tcp.local",
+			},
+		},
+	}
+}
+
+func TestDiffMessages_same(t *testing.T) {
+	if diffs := DiffMessages(newDiffTestMessage(), newDiffTestMessage()); diffs != nil {
+		t.Errorf("Expected no differences, got %v", diffs)
+	}
+}
+
+func TestDiffMessages(t *testing.T) {
+	a := newDiffTestMessage()
+	b := newDiffTestMessage()
+	b.Hdr.Authoritative = false
+	b.Questions[0].AcceptUnicastResponse = true
+	// Swapping the answers puts an ARecord opposite a PTRRecord, which must
+	// not panic
+	b.Answers[0], b.Answers[1] = b.Answers[1], b.Answers[0]
+	b.Additional = []DNSResourceRecord{a.Answers[0]}
+
+	diffs := DiffMessages(a, b)
+	expected := []struct {
+		section MessageSection
+		kind    DiffKind
+	}{
+		{SectionHeader, DiffChanged},
+		{SectionQuestion, DiffChanged},
+		{SectionAnswer, DiffChanged},
+		{SectionAnswer, DiffChanged},
+		{SectionAdditional, DiffExtra},
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Got %d differences, expected %d: %v", len(diffs), len(expected), diffs)
+	}
+	for i, e := range expected {
+		if diffs[i].Section != e.section || diffs[i].Kind != e.kind {
+			t.Errorf("diffs[%d] is %s %s, expected %s %s", i, diffs[i].Section, diffs[i].Kind, e.section, e.kind)
+		}
+	}
+	if diffs[0].Field != "Authoritative" {
+		t.Errorf("diffs[0].Field is %q, expected \"Authoritative\"", diffs[0].Field)
+	}
+}
+
+func TestDiffMessages_ignoreRecordOrder(t *testing.T) {
+	a := newDiffTestMessage()
+	b := newDiffTestMessage()
+	b.Answers[0], b.Answers[1] = b.Answers[1], b.Answers[0]
+	if diffs := DiffMessages(a, b, IgnoreRecordOrder); diffs != nil {
+		t.Errorf("Expected no differences, got %v", diffs)
+	}
+
+	changed := b.Answers[1].(ARecord)
+	changed.Common.TTL = 10
+	b.Answers[1] = changed
+	b.Answers = append(b.Answers, changed)
+	diffs := DiffMessages(a, b, IgnoreRecordOrder)
+	if len(diffs) != 2 {
+		t.Fatalf("Got %d differences, expected 2: %v", len(diffs), diffs)
+	}
+	if diffs[0].Kind != DiffChanged || diffs[0].IndexA != 0 || diffs[0].IndexB != 1 {
+		t.Errorf("diffs[0] is %v, expected answer 0 changed to answer 1", diffs[0])
+	}
+	if diffs[1].Kind != DiffExtra || diffs[1].IndexB != 2 {
+		t.Errorf("diffs[1] is %v, expected extra answer 2", diffs[1])
+	}
+}
+
+func TestDiffMessages_pointersAndNil(t *testing.T) {
+	a := newDiffTestMessage()
+	b := newDiffTestMessage()
+	aA, bA := a.Answers[0].(ARecord), b.Answers[0].(ARecord)
+	bA.Common.TTL = 10
+	a.Answers[0], b.Answers[0] = &aA, &bA
+	// nil records, and nil pointers, are equal only to each other
+	var nilA *ARecord
+	a.Additional = []DNSResourceRecord{nil, nilA, a.Answers[1]}
+	b.Additional = []DNSResourceRecord{nilA, nil, nil}
+
+	testCases := []struct {
+		opts     []DiffOption
+		expected []DiffKind
+	}{
+		// the PTR record is opposite a nil record
+		{nil, []DiffKind{DiffChanged, DiffChanged}},
+		// a nil record can't be paired with the PTR record as changed
+		{[]DiffOption{IgnoreRecordOrder}, []DiffKind{DiffChanged, DiffMissing, DiffExtra}},
+	}
+	for _, tc := range testCases {
+		diffs := DiffMessages(a, b, tc.opts...)
+		if len(diffs) != len(tc.expected) {
+			t.Fatalf("%v: got %d differences, expected %d: %v", tc.opts, len(diffs), len(tc.expected), diffs)
+		}
+		if diffs[0].Section != SectionAnswer || len(diffs[0].Reasons) != 1 || diffs[0].Reasons[0] != "TTL: 120 != 10" {
+			t.Errorf("%v: diffs[0] is %v, expected the TTL of answer 0 changed", tc.opts, diffs[0])
+		}
+		for i, d := range diffs {
+			if d.Kind != tc.expected[i] || (i > 0 && d.Section != SectionAdditional) {
+				t.Errorf("%v: diffs[%d] is %v, expected an additional record %s", tc.opts, i, d, tc.expected[i])
+			}
+		}
+	}
+}
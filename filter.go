@@ -0,0 +1,849 @@
+package rawmdns
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FilterError is returned by CompileFilter for an expression it cannot
+// compile. Column counts bytes from 1.
+type FilterError struct {
+	Column int
+	Msg    string
+}
+
+func (fe FilterError) Error() string {
+	return fmt.Sprintf("column %d: %s", fe.Column, fe.Msg)
+}
+
+func filterErrorf(pos int, format string, args ...interface{}) error {
+	return FilterError{Column: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Filter is a compiled filter expression, which selects messages. Use
+// CompileFilter to make one.
+type Filter struct {
+	src   string
+	terms []filterTerm
+}
+
+// filterTerm is a part of the expression, joined to the rest by "and", which
+// shares no section with any other term, so it can be matched on its own.
+type filterTerm struct {
+	eval     filterFunc
+	sections []filterSection
+}
+
+// CompileFilter compiles a filter expression, such as
+//
+//	response and answer.type == SRV and answer.name ~ "*._ipp._tcp.local" and answer.ttl == 0
+//
+// An expression combines predicates with "and", "or", "not" (or "&&", "||"
+// and "!") and parentheses. A predicate is a true-or-false field on its own,
+// or a field compared with a value using ==, !=, <, <=, >, >=, ~ (matches a
+// glob pattern, where * is any run of characters and ? any one) or !~.
+//
+// The header fields are id, opcode, rcode, qdcount, ancount, nscount and
+// arcount, and the flags response, query, aa, tc, rd, ra, ad and cd. Each
+// question has question.name, question.type, question.class and question.qu,
+// set if a unicast response is requested. Records have name, type, class,
+// ttl, cacheflush and data, their RDATA in presentation format, under the
+// section they're in: answer, authority or additional, or rr for any of the
+// three.
+//
+// Every mention of a section refers to the same question or record, so the
+// example above matches a message with a single answer which is an SRV record
+// for an IPP instance with a TTL of 0. A message matches if any choice of
+// question and records does. When a section is empty, comparisons of its
+// fields are false.
+//
+// Matching tries every combination of a question and records from the
+// sections mentioned together, so its cost is the product of their sizes.
+// Parts of the expression joined by "and" which mention no section in common
+// are matched separately, so that
+//
+//	answer.type == SRV and additional.type == A
+//
+// costs the number of answers plus the number of additional records, but
+// (answer.type == SRV or additional.type == A) costs their product.
+//
+// Names compare case-insensitively, ignoring any trailing dot. Types,
+// classes, opcodes and response codes may be given by name or number, e.g.
+// SRV, TYPE33 or 33. Values which aren't a single word, such as most
+// patterns, must be double-quoted.
+func CompileFilter(expr string) (*Filter, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	fp := &filterParser{tokens: tokens}
+	node, err := fp.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := fp.peek(); tok.kind != filterEOF {
+		return nil, filterErrorf(tok.pos, "expected and, or, or the end of the filter, found %s", tok)
+	}
+
+	var terms []filterTerm
+	for _, conjunct := range splitFilterAnd(node, nil) {
+		fc := &filterCompiler{sections: make(map[filterSection]bool)}
+		eval, err := fc.compile(conjunct)
+		if err != nil {
+			return nil, err
+		}
+		term := filterTerm{eval: eval}
+		for section := range fc.sections {
+			term.sections = append(term.sections, section)
+		}
+		terms = append(terms, term)
+	}
+	f := &Filter{src: expr, terms: mergeFilterTerms(terms)}
+	// try the cheapest terms first, since any of them can rule a message out
+	sort.SliceStable(f.terms, func(i, j int) bool { return len(f.terms[i].sections) < len(f.terms[j].sections) })
+	return f, nil
+}
+
+// splitFilterAnd appends the operands of the "and"s at the top of node to
+// conjuncts, left to right.
+func splitFilterAnd(node filterNode, conjuncts []filterNode) []filterNode {
+	if and, ok := node.(filterAnd); ok {
+		conjuncts = splitFilterAnd(and.left, conjuncts)
+		return splitFilterAnd(and.right, conjuncts)
+	}
+	return append(conjuncts, node)
+}
+
+// mergeFilterTerms combines terms which mention a section in common, since
+// they must be matched against the same choice of question or record, until
+// no two terms share a section.
+func mergeFilterTerms(terms []filterTerm) []filterTerm {
+	var merged []filterTerm
+	for _, term := range terms {
+		for i := 0; i < len(merged); {
+			if !sharesFilterSection(merged[i].sections, term.sections) {
+				i++
+				continue
+			}
+			left, right := merged[i].eval, term.eval
+			term.eval = func(b *filterBinding) bool { return left(b) && right(b) }
+			term.sections = append(append([]filterSection(nil), merged[i].sections...), term.sections...)
+			merged = append(merged[:i], merged[i+1:]...)
+		}
+		merged = append(merged, term)
+	}
+	for _, term := range merged {
+		sort.Slice(term.sections, func(i, j int) bool { return term.sections[i] < term.sections[j] })
+	}
+	return merged
+}
+
+func sharesFilterSection(a, b []filterSection) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns the expression the Filter was compiled from.
+func (f *Filter) String() string {
+	return f.src
+}
+
+// Match reports whether a message is selected by the Filter.
+func (f *Filter) Match(dm DNSMessage) bool {
+	b := &filterBinding{dm: &dm}
+	for _, term := range f.terms {
+		if !term.bind(b, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// bind tries each question or record of the i'th section the term mentions,
+// and returns true as soon as one makes it true.
+func (ft filterTerm) bind(b *filterBinding, i int) bool {
+	if i == len(ft.sections) {
+		return ft.eval(b)
+	}
+	section := ft.sections[i]
+	if section == filterQuestion {
+		b.question = nil
+		if len(b.dm.Questions) == 0 {
+			return ft.bind(b, i+1)
+		}
+		for j := range b.dm.Questions {
+			b.question = &b.dm.Questions[j]
+			if ft.bind(b, i+1) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var rrs []DNSResourceRecord
+	switch section {
+	case filterAnswer:
+		rrs = b.dm.Answers
+	case filterAuthority:
+		rrs = b.dm.Authority
+	case filterAdditional:
+		rrs = b.dm.Additional
+	case filterAnyRecord:
+		rrs = append(append(append(rrs, b.dm.Answers...), b.dm.Authority...), b.dm.Additional...)
+	}
+	b.records[section] = nil
+	if len(rrs) == 0 {
+		return ft.bind(b, i+1)
+	}
+	for _, rr := range rrs {
+		b.records[section] = rr
+		if ft.bind(b, i+1) {
+			return true
+		}
+	}
+	return false
+}
+
+type filterSection int
+
+const (
+	filterHeader filterSection = iota
+	filterQuestion
+	filterAnswer
+	filterAuthority
+	filterAdditional
+	filterAnyRecord
+)
+
+var filterSectionNames = map[string]filterSection{
+	"question":   filterQuestion,
+	"answer":     filterAnswer,
+	"authority":  filterAuthority,
+	"additional": filterAdditional,
+	"rr":         filterAnyRecord,
+}
+
+// filterBinding is the message being matched, and the question and records
+// currently chosen from it; a nil question or record means the section is
+// empty.
+type filterBinding struct {
+	dm       *DNSMessage
+	question *DNSQuestion
+	records  [filterAnyRecord + 1]DNSResourceRecord
+}
+
+type filterFunc func(b *filterBinding) bool
+
+// Lexing
+
+type filterTokenKind int
+
+const (
+	filterEOF filterTokenKind = iota
+	filterWord
+	filterString
+	filterOp
+	filterLParen
+	filterRParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+func (ft filterToken) String() string {
+	switch ft.kind {
+	case filterEOF:
+		return "the end of the filter"
+	case filterString:
+		return strconv.Quote(ft.text)
+	default:
+		return fmt.Sprintf("%q", ft.text)
+	}
+}
+
+// isKeyword reports whether a token joins predicates, rather than being part
+// of one.
+func (ft filterToken) isKeyword() bool {
+	if ft.kind == filterOp {
+		return ft.text == "&&" || ft.text == "||" || ft.text == "!"
+	}
+	if ft.kind == filterWord {
+		switch strings.ToLower(ft.text) {
+		case "and", "or", "not":
+			return true
+		}
+	}
+	return false
+}
+
+func (ft filterToken) is(word, op string) bool {
+	return (ft.kind == filterWord && strings.EqualFold(ft.text, word)) || (ft.kind == filterOp && ft.text == op)
+}
+
+func isFilterWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_.-*?", c) >= 0
+}
+
+var filterOps = []string{"==", "!=", "<=", ">=", "!~", "&&", "||", "<", ">", "~", "!"}
+
+func lexFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{filterLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{filterRParen, ")", i})
+			i++
+		case c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(s) {
+					return nil, filterErrorf(start, "unterminated string")
+				}
+				if s[i] == '"' {
+					i++
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				sb.WriteByte(s[i])
+				i++
+			}
+			tokens = append(tokens, filterToken{filterString, sb.String(), start})
+		case isFilterWordByte(c):
+			start := i
+			for i < len(s) && isFilterWordByte(s[i]) {
+				i++
+			}
+			tokens = append(tokens, filterToken{filterWord, s[start:i], start})
+		default:
+			found := false
+			for _, op := range filterOps {
+				if strings.HasPrefix(s[i:], op) {
+					tokens = append(tokens, filterToken{filterOp, op, i})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, filterErrorf(i, "unexpected character %q", c)
+			}
+		}
+	}
+	return append(tokens, filterToken{filterEOF, "", len(s)}), nil
+}
+
+// Parsing
+
+type filterNode interface{}
+
+type filterAnd struct{ left, right filterNode }
+
+type filterOr struct{ left, right filterNode }
+
+type filterNot struct{ expr filterNode }
+
+// filterPredicate is a field on its own, if op is nil, or compared with a
+// value.
+type filterPredicate struct {
+	field filterToken
+	op    *filterToken
+	value filterToken
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (fp *filterParser) peek() filterToken {
+	return fp.tokens[fp.pos]
+}
+
+func (fp *filterParser) next() filterToken {
+	tok := fp.tokens[fp.pos]
+	if tok.kind != filterEOF {
+		fp.pos++
+	}
+	return tok
+}
+
+func (fp *filterParser) parseOr() (filterNode, error) {
+	left, err := fp.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for fp.peek().is("or", "||") {
+		fp.next()
+		right, err := fp.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (fp *filterParser) parseAnd() (filterNode, error) {
+	left, err := fp.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for fp.peek().is("and", "&&") {
+		fp.next()
+		right, err := fp.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (fp *filterParser) parseNot() (filterNode, error) {
+	if fp.peek().is("not", "!") {
+		fp.next()
+		expr, err := fp.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{expr}, nil
+	}
+	return fp.parsePrimary()
+}
+
+func (fp *filterParser) parsePrimary() (filterNode, error) {
+	tok := fp.next()
+	if tok.kind == filterLParen {
+		expr, err := fp.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := fp.next(); closing.kind != filterRParen {
+			return nil, filterErrorf(closing.pos, "expected ) to match the ( at column %d, found %s", tok.pos+1, closing)
+		}
+		return expr, nil
+	}
+	if tok.kind != filterWord || tok.isKeyword() {
+		return nil, filterErrorf(tok.pos, "expected a field, not or (, found %s", tok)
+	}
+
+	p := filterPredicate{field: tok}
+	if op := fp.peek(); op.kind == filterOp && !op.isKeyword() {
+		fp.next()
+		p.op = &op
+		p.value = fp.next()
+		if (p.value.kind != filterWord && p.value.kind != filterString) || p.value.isKeyword() {
+			return nil, filterErrorf(p.value.pos, "expected a value after %s, found %s", op.text, p.value)
+		}
+	}
+	return p, nil
+}
+
+// Compiling
+
+type filterKind int
+
+const (
+	filterBool filterKind = iota
+	filterNumber
+	filterType
+	filterClass
+	filterOpCode
+	filterRCode
+	filterName
+	filterText
+)
+
+// filterField reads one field from the current binding. The getter matching
+// kind is set; it returns false if the section the field is in is empty.
+type filterField struct {
+	kind    filterKind
+	section filterSection
+	boolean func(b *filterBinding) (bool, bool)
+	number  func(b *filterBinding) (uint64, bool)
+	text    func(b *filterBinding) (string, bool)
+}
+
+func headerFlag(get func(hdr DNSHeader) bool) filterField {
+	return filterField{kind: filterBool, boolean: func(b *filterBinding) (bool, bool) {
+		return get(b.dm.Hdr), true
+	}}
+}
+
+func headerNumber(kind filterKind, get func(hdr DNSHeader) uint64) filterField {
+	return filterField{kind: kind, number: func(b *filterBinding) (uint64, bool) {
+		return get(b.dm.Hdr), true
+	}}
+}
+
+var filterHeaderFields = map[string]filterField{
+	"response": headerFlag(func(hdr DNSHeader) bool { return hdr.IsResponse }),
+	"query":    headerFlag(func(hdr DNSHeader) bool { return !hdr.IsResponse }),
+	"aa":       headerFlag(func(hdr DNSHeader) bool { return hdr.Authoritative }),
+	"tc":       headerFlag(func(hdr DNSHeader) bool { return hdr.Truncated }),
+	"rd":       headerFlag(func(hdr DNSHeader) bool { return hdr.RecursionDesired }),
+	"ra":       headerFlag(func(hdr DNSHeader) bool { return hdr.RecursionAvailable }),
+	"ad":       headerFlag(func(hdr DNSHeader) bool { return hdr.AuthenticatedData }),
+	"cd":       headerFlag(func(hdr DNSHeader) bool { return hdr.CheckingDisabled }),
+	"id":       headerNumber(filterNumber, func(hdr DNSHeader) uint64 { return uint64(hdr.ID) }),
+	"opcode":   headerNumber(filterOpCode, func(hdr DNSHeader) uint64 { return uint64(hdr.OpCode) }),
+	"rcode":    headerNumber(filterRCode, func(hdr DNSHeader) uint64 { return uint64(hdr.ResponseCode) }),
+	"qdcount":  headerNumber(filterNumber, func(hdr DNSHeader) uint64 { return uint64(hdr.NumQuestions) }),
+	"ancount":  headerNumber(filterNumber, func(hdr DNSHeader) uint64 { return uint64(hdr.NumAnswers) }),
+	"nscount":  headerNumber(filterNumber, func(hdr DNSHeader) uint64 { return uint64(hdr.NumNameServers) }),
+	"arcount":  headerNumber(filterNumber, func(hdr DNSHeader) uint64 { return uint64(hdr.NumAddlRecords) }),
+}
+
+var filterQuestionFields = map[string]filterField{
+	"name": {kind: filterName, text: func(b *filterBinding) (string, bool) {
+		if b.question == nil {
+			return "", false
+		}
+		return b.question.Domain, true
+	}},
+	"type": {kind: filterType, number: func(b *filterBinding) (uint64, bool) {
+		if b.question == nil {
+			return 0, false
+		}
+		return uint64(b.question.Type), true
+	}},
+	"class": {kind: filterClass, number: func(b *filterBinding) (uint64, bool) {
+		if b.question == nil {
+			return 0, false
+		}
+		return uint64(b.question.Class), true
+	}},
+	"qu": {kind: filterBool, boolean: func(b *filterBinding) (bool, bool) {
+		if b.question == nil {
+			return false, false
+		}
+		return b.question.AcceptUnicastResponse, true
+	}},
+}
+
+// filterRecordField returns the named field of the records in a section.
+func filterRecordField(section filterSection, name string) (filterField, bool) {
+	common := func(b *filterBinding) (ResourceRecordCommon, bool) {
+		rr := b.records[section]
+		if rr == nil {
+			return ResourceRecordCommon{}, false
+		}
+		return rr.GetCommon(), true
+	}
+	field := filterField{section: section}
+	switch name {
+	case "name":
+		field.kind = filterName
+		field.text = func(b *filterBinding) (string, bool) {
+			c, ok := common(b)
+			return c.Domain, ok
+		}
+	case "type":
+		field.kind = filterType
+		field.number = func(b *filterBinding) (uint64, bool) {
+			c, ok := common(b)
+			return uint64(c.Type), ok
+		}
+	case "class":
+		field.kind = filterClass
+		field.number = func(b *filterBinding) (uint64, bool) {
+			c, ok := common(b)
+			return uint64(c.Class), ok
+		}
+	case "ttl":
+		field.kind = filterNumber
+		field.number = func(b *filterBinding) (uint64, bool) {
+			c, ok := common(b)
+			return uint64(c.TTL), ok
+		}
+	case "cacheflush":
+		field.kind = filterBool
+		field.boolean = func(b *filterBinding) (bool, bool) {
+			c, ok := common(b)
+			return c.CacheFlush, ok
+		}
+	case "data":
+		field.kind = filterText
+		field.text = func(b *filterBinding) (string, bool) {
+			rr := b.records[section]
+			if rr == nil {
+				return "", false
+			}
+			if s, ok := presentRData(rr); ok {
+				return s, true
+			}
+			rdata, err := rr.PackRData()
+			if err != nil {
+				return "", false
+			}
+			return presentGenericRData(rdata), true
+		}
+	default:
+		return filterField{}, false
+	}
+	return field, true
+}
+
+const filterRecordFieldNames = "name, type, class, ttl, cacheflush and data"
+
+func lookupFilterField(tok filterToken) (filterField, error) {
+	name := strings.ToLower(tok.text)
+	if field, found := filterHeaderFields[name]; found {
+		return field, nil
+	}
+	dot := strings.IndexByte(name, '.')
+	if dot < 0 {
+		if section, found := filterSectionNames[name]; found && section != filterQuestion {
+			return filterField{}, filterErrorf(tok.pos, "%s needs a field, one of %s", tok.text, filterRecordFieldNames)
+		}
+		return filterField{}, filterErrorf(tok.pos, "unknown field %q", tok.text)
+	}
+	section, found := filterSectionNames[name[:dot]]
+	if !found {
+		return filterField{}, filterErrorf(tok.pos, "unknown section %q; expected question, answer, authority, additional or rr", tok.text[:dot])
+	}
+	if section == filterQuestion {
+		field, found := filterQuestionFields[name[dot+1:]]
+		if !found {
+			return filterField{}, filterErrorf(tok.pos, "unknown field %q; question fields are name, type, class and qu", tok.text)
+		}
+		field.section = filterQuestion
+		return field, nil
+	}
+	field, found := filterRecordField(section, name[dot+1:])
+	if !found {
+		return filterField{}, filterErrorf(tok.pos, "unknown field %q; record fields are %s", tok.text, filterRecordFieldNames)
+	}
+	return field, nil
+}
+
+// filterOpsFor lists the comparisons which apply to each kind of field.
+var filterOpsFor = map[filterKind][]string{
+	filterBool:   {"==", "!="},
+	filterNumber: {"==", "!=", "<", "<=", ">", ">="},
+	filterType:   {"==", "!="},
+	filterClass:  {"==", "!="},
+	filterOpCode: {"==", "!="},
+	filterRCode:  {"==", "!="},
+	filterName:   {"==", "!=", "~", "!~"},
+	filterText:   {"==", "!=", "~", "!~"},
+}
+
+// parseFilterNumber parses the value compared with a number-like field.
+func parseFilterNumber(kind filterKind, s string) (uint64, bool) {
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return v, kind == filterNumber || v <= 0xFFFF
+	}
+	switch kind {
+	case filterType:
+		rt, ok := parseZoneType(s)
+		return uint64(rt), ok
+	case filterClass:
+		rc, ok := parseZoneClass(s)
+		return uint64(rc), ok
+	case filterOpCode:
+		for oc, name := range opCodeNames {
+			if strings.EqualFold(s, name) {
+				return uint64(oc), true
+			}
+		}
+	case filterRCode:
+		for rc, name := range responseCodeNames {
+			if strings.EqualFold(s, name) {
+				return uint64(rc), true
+			}
+		}
+	}
+	return 0, false
+}
+
+var filterKindNames = map[filterKind]string{
+	filterNumber: "number",
+	filterType:   "record type",
+	filterClass:  "class",
+	filterOpCode: "opcode",
+	filterRCode:  "response code",
+}
+
+type filterCompiler struct {
+	sections map[filterSection]bool
+}
+
+func (fc *filterCompiler) compile(node filterNode) (filterFunc, error) {
+	switch n := node.(type) {
+	case filterAnd:
+		left, right, err := fc.compilePair(n.left, n.right)
+		if err != nil {
+			return nil, err
+		}
+		return func(b *filterBinding) bool { return left(b) && right(b) }, nil
+	case filterOr:
+		left, right, err := fc.compilePair(n.left, n.right)
+		if err != nil {
+			return nil, err
+		}
+		return func(b *filterBinding) bool { return left(b) || right(b) }, nil
+	case filterNot:
+		expr, err := fc.compile(n.expr)
+		if err != nil {
+			return nil, err
+		}
+		return func(b *filterBinding) bool { return !expr(b) }, nil
+	case filterPredicate:
+		return fc.compilePredicate(n)
+	default:
+		panic(fmt.Sprintf("unknown filter node %T", node))
+	}
+}
+
+func (fc *filterCompiler) compilePair(left, right filterNode) (filterFunc, filterFunc, error) {
+	l, err := fc.compile(left)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := fc.compile(right)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+func (fc *filterCompiler) compilePredicate(p filterPredicate) (filterFunc, error) {
+	field, err := lookupFilterField(p.field)
+	if err != nil {
+		return nil, err
+	}
+	if field.section != filterHeader {
+		fc.sections[field.section] = true
+	}
+
+	if p.op == nil {
+		if field.kind != filterBool {
+			return nil, filterErrorf(p.field.pos, "%s is not true or false, so it needs comparing with a value", p.field.text)
+		}
+		return func(b *filterBinding) bool {
+			v, ok := field.boolean(b)
+			return ok && v
+		}, nil
+	}
+	op := p.op.text
+	allowed := false
+	for _, o := range filterOpsFor[field.kind] {
+		if o == op {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, filterErrorf(p.op.pos, "%s cannot be compared with %s; use one of %s", p.field.text, op, strings.Join(filterOpsFor[field.kind], " "))
+	}
+	negate := op == "!=" || op == "!~"
+
+	switch field.kind {
+	case filterBool:
+		var want bool
+		switch strings.ToLower(p.value.text) {
+		case "true":
+			want = true
+		case "false":
+		default:
+			return nil, filterErrorf(p.value.pos, "%s is true or false, not %s", p.field.text, p.value)
+		}
+		return func(b *filterBinding) bool {
+			v, ok := field.boolean(b)
+			return ok && (v == want) != negate
+		}, nil
+	case filterName, filterText:
+		want := p.value.text
+		fold := func(s string) string { return s }
+		if field.kind == filterName {
+			fold = func(s string) string { return strings.ToLower(strings.TrimSuffix(s, ".")) }
+		}
+		want = fold(want)
+		glob := op == "~" || op == "!~"
+		return func(b *filterBinding) bool {
+			v, ok := field.text(b)
+			if !ok {
+				return false
+			}
+			if glob {
+				return globMatch(want, fold(v)) != negate
+			}
+			return (fold(v) == want) != negate
+		}, nil
+	default:
+		want, ok := parseFilterNumber(field.kind, p.value.text)
+		if !ok || p.value.kind != filterWord {
+			return nil, filterErrorf(p.value.pos, "%s is not a %s", p.value, filterKindNames[field.kind])
+		}
+		return func(b *filterBinding) bool {
+			v, ok := field.number(b)
+			if !ok {
+				return false
+			}
+			switch op {
+			case "==":
+				return v == want
+			case "!=":
+				return v != want
+			case "<":
+				return v < want
+			case "<=":
+				return v <= want
+			case ">":
+				return v > want
+			default:
+				return v >= want
+			}
+		}, nil
+	}
+}
+
+// globMatch reports whether s matches pattern, in which * matches any run of
+// characters, including dots, and ? matches any one character.
+func globMatch(pattern, s string) bool {
+	// star and starS are where to resume after the last *, if a later part of
+	// the pattern fails to match
+	star, starS := -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, starS = p, i
+			p++
+		case star >= 0:
+			starS++
+			p, i = star+1, starS
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
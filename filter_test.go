@@ -0,0 +1,162 @@
+package rawmdns
+
+import (
+	"net"
+	"testing"
+)
+
+// filterTestGoodbye returns a response saying goodbye to an IPP printer's
+// SRV record, alongside an unrelated A record.
+func filterTestGoodbye() DNSMessage {
+	return DNSMessage{
+		Hdr: DNSHeader{IsResponse: true, Authoritative: true, NumAnswers: 2},
+		Answers: []DNSResourceRecord{
+			ARecord{
+				Common: ResourceRecordCommon{Domain: "Printer.local", Type: TypeA, Class: ClassINET, TTL: 120, CacheFlush: true},
+				Addr:   net.ParseIP("10.0.0.5").To4(),
+			},
+			SRVRecord{
+				Common: ResourceRecordCommon{Domain: "Office Printer._ipp._tcp.local", Type: TypeSRV, Class: ClassINET, TTL: 0, CacheFlush: true},
+				Port:   631,
+				Target: "Printer.local",
+			},
+		},
+	}
+}
+
+func TestFilter(t *testing.T) {
+	goodbye := filterTestGoodbye()
+	query := DNSMessage{
+		Hdr:       DNSHeader{NumQuestions: 1},
+		Questions: []DNSQuestion{{Domain: "_ipp._tcp.local", Type: TypePTR, Class: ClassINET, AcceptUnicastResponse: true}},
+	}
+	testCases := []struct {
+		expr    string
+		goodbye bool
+		query   bool
+	}{
+		{`response and answer.type == SRV and answer.name ~ "*._ipp._tcp.local" and answer.ttl == 0`, true, false},
+		// the A record has TTL 120, and the SRV record isn't an A record, so
+		// no single answer matches
+		{`answer.type == A and answer.ttl == 0`, false, false},
+		{`answer.type == A && rr.ttl == 0`, true, false},
+		{`query and question.qu and question.name == _IPP._tcp.local.`, false, true},
+		{`not answer.type == A`, true, true},
+		{`answer.type != A`, true, false},
+		{`answer.name == printer.local and answer.cacheflush == true`, true, false},
+		{`answer.type == TYPE33 or (question.type == 12 and question.class == IN)`, true, true},
+		{`ancount >= 2 and opcode == QUERY and rcode == NOERROR and id == 0`, true, false},
+		{`answer.data ~ "0 0 631 *"`, true, false},
+		{`answer.data == "10.0.0.5"`, true, false},
+		{`additional.ttl < 4500`, false, false},
+		{`!aa || answer.name ~ "?rinter.local"`, true, true},
+	}
+	for _, tc := range testCases {
+		f, err := CompileFilter(tc.expr)
+		if err != nil {
+			t.Errorf("CompileFilter(%q): %s", tc.expr, err)
+			continue
+		}
+		if f.String() != tc.expr {
+			t.Errorf("String() is %q, expected %q", f, tc.expr)
+		}
+		if got := f.Match(goodbye); got != tc.goodbye {
+			t.Errorf("%q matches the goodbye: %t, expected %t", tc.expr, got, tc.goodbye)
+		}
+		if got := f.Match(query); got != tc.query {
+			t.Errorf("%q matches the query: %t, expected %t", tc.expr, got, tc.query)
+		}
+	}
+}
+
+// TestFilter_large checks that predicates on different sections are matched
+// separately: trying every combination of 300 questions and 300 records in
+// each of three sections would take 8.1 billion evaluations.
+func TestFilter_large(t *testing.T) {
+	const n = 300
+	dm := DNSMessage{Hdr: DNSHeader{IsResponse: true}}
+	for i := 0; i < n; i++ {
+		common := ResourceRecordCommon{Domain: "printer.local", Type: TypeA, Class: ClassINET, TTL: 120}
+		a := ARecord{Common: common, Addr: net.IPv4(10, 0, byte(i>>8), byte(i)).To4()}
+		dm.Questions = append(dm.Questions, DNSQuestion{Domain: "printer.local", Type: TypeA, Class: ClassINET})
+		dm.Answers = append(dm.Answers, a)
+		dm.Authority = append(dm.Authority, a)
+		dm.Additional = append(dm.Additional, a)
+	}
+	last := dm.Additional[n-1].(ARecord)
+	last.Common.TTL = 0
+	dm.Additional[n-1] = last
+
+	testCases := []struct {
+		expr     string
+		expected bool
+	}{
+		{`question.type == A and answer.ttl == 120 and authority.type == A and additional.ttl == 0`, true},
+		{`question.type == A and answer.ttl == 120 and authority.type == A and additional.ttl == 1`, false},
+		// both predicates on the additional section must hold for the same
+		// record
+		{`response and additional.ttl == 0 and additional.data == "10.0.1.43" and answer.type == A`, true},
+		{`response and additional.ttl == 0 and additional.data == "10.0.0.0" and answer.type == A`, false},
+	}
+	for _, tc := range testCases {
+		f, err := CompileFilter(tc.expr)
+		if err != nil {
+			t.Errorf("CompileFilter(%q): %s", tc.expr, err)
+			continue
+		}
+		if got := f.Match(dm); got != tc.expected {
+			t.Errorf("%q matches: %t, expected %t", tc.expr, got, tc.expected)
+		}
+	}
+}
+
+func TestCompileFilter_errors(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{``, `column 1: expected a field, not or (, found the end of the filter`},
+		{`answer.tll == 0`, `column 1: unknown field "answer.tll"; record fields are name, type, class, ttl, cacheflush and data`},
+		{`answers.ttl == 0`, `column 1: unknown section "answers"; expected question, answer, authority, additional or rr`},
+		{`response and answer`, `column 14: answer needs a field, one of name, type, class, ttl, cacheflush and data`},
+		{`answer.ttl`, `column 1: answer.ttl is not true or false, so it needs comparing with a value`},
+		{`answer.type < SRV`, `column 13: answer.type cannot be compared with <; use one of == !=`},
+		{`answer.type == SRVV`, `column 16: "SRVV" is not a record type`},
+		{`answer.ttl == "0"`, `column 15: "0" is not a number`},
+		{`answer.ttl ==`, `column 14: expected a value after ==, found the end of the filter`},
+		{`(response or query`, `column 19: expected ) to match the ( at column 1, found the end of the filter`},
+		{`response query`, `column 10: expected and, or, or the end of the filter, found "query"`},
+		{`answer.name == "printer`, `column 16: unterminated string`},
+		{`response & query`, `column 10: unexpected character '&'`},
+		{`aa == maybe`, `column 7: aa is true or false, not "maybe"`},
+	}
+	for _, tc := range testCases {
+		_, err := CompileFilter(tc.expr)
+		if err == nil {
+			t.Errorf("CompileFilter(%q) succeeded, expected an error", tc.expr)
+			continue
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("CompileFilter(%q) error is %q, expected %q", tc.expr, err, tc.expected)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	testCases := []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"*", "", true},
+		{"*._ipp._tcp.local", "a.b._ipp._tcp.local", true},
+		{"*._ipp._tcp.local", "_ipp._tcp.local", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+		{"?", "ab", false},
+	}
+	for _, tc := range testCases {
+		if got := globMatch(tc.pattern, tc.s); got != tc.expected {
+			t.Errorf("globMatch(%q, %q) is %t, expected %t", tc.pattern, tc.s, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package rawmdns
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONOption changes how DNSMessage.ToJSON represents a message.
+type JSONOption int
+
+const (
+	// JSONIncludeMessageOctets adds the messageOctetsHEX member, holding the
+	// whole message in wire format, alongside the other members.
+	JSONIncludeMessageOctets JSONOption = iota + 1
+)
+
+// jsonMessage is a DNS message object as described in RFC 8427 section 2.1.
+// Records are kept as maps so that the type-specific rdata member, e.g.
+// "rdataAAAA", can be named after the record type.
+type jsonMessage struct {
+	ID      uint16 `json:"ID"`
+	QR      bool   `json:"QR"`
+	Opcode  uint8  `json:"Opcode"`
+	AA      bool   `json:"AA"`
+	TC      bool   `json:"TC"`
+	RD      bool   `json:"RD"`
+	RA      bool   `json:"RA"`
+	AD      bool   `json:"AD"`
+	CD      bool   `json:"CD"`
+	RCODE   uint8  `json:"RCODE"`
+	QDCOUNT uint16 `json:"QDCOUNT"`
+	ANCOUNT uint16 `json:"ANCOUNT"`
+	NSCOUNT uint16 `json:"NSCOUNT"`
+	ARCOUNT uint16 `json:"ARCOUNT"`
+
+	QNAME      string `json:"QNAME,omitempty"`
+	QTYPE      *int   `json:"QTYPE,omitempty"`
+	QTYPEname  string `json:"QTYPEname,omitempty"`
+	QCLASS     *int   `json:"QCLASS,omitempty"`
+	QCLASSname string `json:"QCLASSname,omitempty"`
+
+	QuestionRRs   []map[string]json.RawMessage `json:"questionRRs,omitempty"`
+	AnswerRRs     []map[string]json.RawMessage `json:"answerRRs,omitempty"`
+	AuthorityRRs  []map[string]json.RawMessage `json:"authorityRRs,omitempty"`
+	AdditionalRRs []map[string]json.RawMessage `json:"additionalRRs,omitempty"`
+
+	MessageOctetsHEX string `json:"messageOctetsHEX,omitempty"`
+}
+
+// MarshalJSON encodes the message as an RFC 8427 DNS message object; it is
+// the same as ToJSON without options.
+func (dm DNSMessage) MarshalJSON() ([]byte, error) {
+	return dm.ToJSON()
+}
+
+// ToJSON encodes the message as an RFC 8427 DNS message object.
+//
+// Every record carries its TYPE, CLASS, TTL and RDATAHEX, which is enough to
+// rebuild it exactly, plus the RDATA in presentation format as
+// "rdata<TYPEname>" if the type has one. The mDNS cache-flush and
+// unicast-response bits are kept in the top bit of CLASS, as on the wire, and
+// the header counts are written as they are in Hdr rather than recomputed.
+func (dm DNSMessage) ToJSON(opts ...JSONOption) ([]byte, error) {
+	jm := jsonMessage{
+		ID:      dm.Hdr.ID,
+		QR:      dm.Hdr.IsResponse,
+		Opcode:  uint8(dm.Hdr.OpCode),
+		AA:      dm.Hdr.Authoritative,
+		TC:      dm.Hdr.Truncated,
+		RD:      dm.Hdr.RecursionDesired,
+		RA:      dm.Hdr.RecursionAvailable,
+		AD:      dm.Hdr.AuthenticatedData,
+		CD:      dm.Hdr.CheckingDisabled,
+		RCODE:   uint8(dm.Hdr.ResponseCode),
+		QDCOUNT: dm.Hdr.NumQuestions,
+		ANCOUNT: dm.Hdr.NumAnswers,
+		NSCOUNT: dm.Hdr.NumNameServers,
+		ARCOUNT: dm.Hdr.NumAddlRecords,
+	}
+
+	if len(dm.Questions) == 1 {
+		q := dm.Questions[0]
+		qtype, qclass := int(q.Type), int(questionWireClass(q))
+		jm.QNAME = presentName(q.Domain)
+		jm.QTYPE, jm.QTYPEname = &qtype, q.Type.String()
+		jm.QCLASS, jm.QCLASSname = &qclass, q.Class.String()
+	}
+	for _, q := range dm.Questions {
+		obj, err := jsonObject(map[string]interface{}{
+			"NAME":      presentName(q.Domain),
+			"TYPE":      q.Type,
+			"TYPEname":  q.Type.String(),
+			"CLASS":     questionWireClass(q),
+			"CLASSname": q.Class.String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		jm.QuestionRRs = append(jm.QuestionRRs, obj)
+	}
+
+	sections := []struct {
+		rrs  []DNSResourceRecord
+		objs *[]map[string]json.RawMessage
+	}{
+		{dm.Answers, &jm.AnswerRRs},
+		{dm.Authority, &jm.AuthorityRRs},
+		{dm.Additional, &jm.AdditionalRRs},
+	}
+	for _, s := range sections {
+		for _, rr := range s.rrs {
+			obj, err := recordToJSON(rr)
+			if err != nil {
+				return nil, err
+			}
+			*s.objs = append(*s.objs, obj)
+		}
+	}
+
+	for _, opt := range opts {
+		if opt == JSONIncludeMessageOctets {
+			octets, err := dm.ToBytes()
+			if err != nil {
+				return nil, fmt.Errorf("DNSMessage.ToBytes: %s", err)
+			}
+			jm.MessageOctetsHEX = strings.ToUpper(hex.EncodeToString(octets))
+		}
+	}
+
+	return json.Marshal(jm)
+}
+
+func questionWireClass(q DNSQuestion) RecordClass {
+	if q.AcceptUnicastResponse {
+		return q.Class | 0x8000
+	}
+	return q.Class
+}
+
+func jsonObject(members map[string]interface{}) (map[string]json.RawMessage, error) {
+	obj := make(map[string]json.RawMessage, len(members))
+	for name, v := range members {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal(%s): %s", name, err)
+		}
+		obj[name] = b
+	}
+	return obj, nil
+}
+
+func recordToJSON(rr DNSResourceRecord) (map[string]json.RawMessage, error) {
+	common := rr.GetCommon()
+	rdata, err := rr.PackRData()
+	if err != nil {
+		return nil, fmt.Errorf("%T.PackRData: %s", rr, err)
+	}
+	class := common.Class
+	if common.CacheFlush {
+		class |= 0x8000
+	}
+	members := map[string]interface{}{
+		"NAME":      presentName(common.Domain),
+		"TYPE":      common.Type,
+		"TYPEname":  common.Type.String(),
+		"CLASS":     class,
+		"CLASSname": common.Class.String(),
+		"TTL":       common.TTL,
+		"RDLENGTH":  len(rdata),
+		"RDATAHEX":  strings.ToUpper(hex.EncodeToString(rdata)),
+	}
+	if presentation, ok := presentRData(rr); ok {
+		members["rdata"+common.Type.String()] = presentation
+	}
+	return jsonObject(members)
+}
+
+// presentRData returns just the RDATA part of a record's String output, if
+// the type has a presentation format the zone file parser can read back.
+func presentRData(rr DNSResourceRecord) (string, bool) {
+	if _, found := zoneRDataParsers[rr.GetCommon().Type]; !found {
+		return "", false
+	}
+	s, ok := rr.(fmt.Stringer)
+	if !ok {
+		return "", false
+	}
+	// Owner, TTL, class and type never contain a literal tab, as
+	// presentName escapes it
+	fields := strings.SplitN(s.String(), "\t", 5)
+	if len(fields) != 5 {
+		return "", false
+	}
+	return strings.TrimSuffix(fields[4], "\t; cache-flush"), true
+}
+
+// UnmarshalJSON decodes an RFC 8427 DNS message object. If it has a
+// messageOctetsHEX member, the message is decoded from that alone. Otherwise
+// each record is built from its RDATAHEX member or, failing that, from its
+// "rdata<TYPEname>" member in presentation format.
+func (dm *DNSMessage) UnmarshalJSON(b []byte) error {
+	var jm jsonMessage
+	err := json.Unmarshal(b, &jm)
+	if err != nil {
+		return err
+	}
+
+	if jm.MessageOctetsHEX != "" {
+		octets, err := hex.DecodeString(jm.MessageOctetsHEX)
+		if err != nil {
+			return fmt.Errorf("messageOctetsHEX: %s", err)
+		}
+		d := NewDecoder(bytes.NewReader(octets))
+		decoded, err := d.DecodeDNSMessage()
+		if err != nil {
+			return fmt.Errorf("messageOctetsHEX: d.DecodeDNSMessage: %s", err)
+		}
+		*dm = decoded
+		return nil
+	}
+
+	if jm.Opcode > 0xF || jm.RCODE > 0xF {
+		return fmt.Errorf("Opcode and RCODE must be 0 through 15")
+	}
+	msg := DNSMessage{
+		Hdr: DNSHeader{
+			ID:                 jm.ID,
+			IsResponse:         jm.QR,
+			OpCode:             OpCode(jm.Opcode),
+			Authoritative:      jm.AA,
+			Truncated:          jm.TC,
+			RecursionDesired:   jm.RD,
+			RecursionAvailable: jm.RA,
+			AuthenticatedData:  jm.AD,
+			CheckingDisabled:   jm.CD,
+			ResponseCode:       ResponseCode(jm.RCODE),
+			NumQuestions:       jm.QDCOUNT,
+			NumAnswers:         jm.ANCOUNT,
+			NumNameServers:     jm.NSCOUNT,
+			NumAddlRecords:     jm.ARCOUNT,
+		},
+	}
+
+	questions := jm.QuestionRRs
+	if questions == nil && jm.QNAME != "" {
+		// The single-question form of RFC 8427 section 2.1
+		obj, err := jsonObject(map[string]interface{}{"NAME": jm.QNAME})
+		if err != nil {
+			return err
+		}
+		if jm.QTYPE != nil {
+			obj["TYPE"], _ = json.Marshal(*jm.QTYPE)
+		}
+		if jm.QTYPEname != "" {
+			obj["TYPEname"], _ = json.Marshal(jm.QTYPEname)
+		}
+		if jm.QCLASS != nil {
+			obj["CLASS"], _ = json.Marshal(*jm.QCLASS)
+		}
+		if jm.QCLASSname != "" {
+			obj["CLASSname"], _ = json.Marshal(jm.QCLASSname)
+		}
+		questions = append(questions, obj)
+	}
+	for i, obj := range questions {
+		common, err := commonFromJSON(obj)
+		if err != nil {
+			return fmt.Errorf("questionRRs[%d]: %s", i, err)
+		}
+		msg.Questions = append(msg.Questions, DNSQuestion{
+			Domain:                common.Domain,
+			Type:                  common.Type,
+			Class:                 common.Class,
+			AcceptUnicastResponse: common.CacheFlush,
+		})
+	}
+
+	sections := []struct {
+		name string
+		objs []map[string]json.RawMessage
+		rrs  *[]DNSResourceRecord
+	}{
+		{"answerRRs", jm.AnswerRRs, &msg.Answers},
+		{"authorityRRs", jm.AuthorityRRs, &msg.Authority},
+		{"additionalRRs", jm.AdditionalRRs, &msg.Additional},
+	}
+	for _, s := range sections {
+		for i, obj := range s.objs {
+			rr, err := recordFromJSON(obj)
+			if err != nil {
+				return fmt.Errorf("%s[%d]: %s", s.name, i, err)
+			}
+			*s.rrs = append(*s.rrs, rr)
+		}
+	}
+
+	*dm = msg
+	return nil
+}
+
+// commonFromJSON reads the NAME, TYPE, CLASS and TTL members shared by
+// question and resource record objects. The top bit of CLASS is returned as
+// CacheFlush; for a question it is the unicast-response bit.
+func commonFromJSON(obj map[string]json.RawMessage) (ResourceRecordCommon, error) {
+	var common ResourceRecordCommon
+	var name string
+	if err := jsonMember(obj, "NAME", &name, true); err != nil {
+		return common, err
+	}
+	zp := &zoneParser{}
+	domain, err := zp.parseName(zoneToken{raw: name}, false)
+	if err != nil {
+		return common, fmt.Errorf("NAME: %s", err)
+	}
+	common.Domain = domain
+
+	var typeCode uint16
+	var typeName string
+	if err = jsonMember(obj, "TYPE", &typeCode, false); err != nil {
+		return common, err
+	}
+	if err = jsonMember(obj, "TYPEname", &typeName, false); err != nil {
+		return common, err
+	}
+	switch {
+	case obj["TYPE"] != nil:
+		common.Type = RecordType(typeCode)
+	case typeName != "":
+		rt, ok := parseZoneType(typeName)
+		if !ok {
+			return common, fmt.Errorf("unknown TYPEname %q", typeName)
+		}
+		common.Type = rt
+	default:
+		return common, fmt.Errorf("missing TYPE")
+	}
+
+	var classCode uint16
+	var className string
+	if err = jsonMember(obj, "CLASS", &classCode, false); err != nil {
+		return common, err
+	}
+	if err = jsonMember(obj, "CLASSname", &className, false); err != nil {
+		return common, err
+	}
+	switch {
+	case obj["CLASS"] != nil:
+		common.Class = RecordClass(classCode)
+	case className != "":
+		rc, ok := parseZoneClass(className)
+		if !ok {
+			return common, fmt.Errorf("unknown CLASSname %q", className)
+		}
+		common.Class = rc
+	default:
+		return common, fmt.Errorf("missing CLASS")
+	}
+	common.CacheFlush = common.Class&0x8000 == 0x8000
+	common.Class &= 0x7FFF
+
+	err = jsonMember(obj, "TTL", &common.TTL, false)
+	return common, err
+}
+
+func jsonMember(obj map[string]json.RawMessage, name string, v interface{}, required bool) error {
+	raw, found := obj[name]
+	if !found {
+		if required {
+			return fmt.Errorf("missing %s", name)
+		}
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	return nil
+}
+
+func recordFromJSON(obj map[string]json.RawMessage) (DNSResourceRecord, error) {
+	common, err := commonFromJSON(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var rdataHex, presentation string
+	if err = jsonMember(obj, "RDATAHEX", &rdataHex, false); err != nil {
+		return nil, err
+	}
+	presentationMember := "rdata" + common.Type.String()
+	if err = jsonMember(obj, presentationMember, &presentation, false); err != nil {
+		return nil, err
+	}
+
+	if obj["RDATAHEX"] == nil {
+		if obj[presentationMember] == nil {
+			return nil, fmt.Errorf("missing RDATAHEX")
+		}
+		zp := &zoneParser{}
+		entries, err := zp.lex([]byte(presentation))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", presentationMember, err)
+		}
+		var tokens []zoneToken
+		for _, entry := range entries {
+			tokens = append(tokens, entry.tokens...)
+		}
+		p := &rdataParser{zp: zp, tokens: tokens}
+		rr, err := p.parse(common)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", presentationMember, err)
+		}
+		return rr, nil
+	}
+
+	rdata, err := hex.DecodeString(rdataHex)
+	if err != nil {
+		return nil, fmt.Errorf("RDATAHEX: %s", err)
+	}
+	if obj["RDLENGTH"] != nil {
+		var rdLength int
+		if err = jsonMember(obj, "RDLENGTH", &rdLength, false); err != nil {
+			return nil, err
+		}
+		if rdLength != len(rdata) {
+			return nil, fmt.Errorf("RDLENGTH is %d, but RDATAHEX is %d bytes", rdLength, len(rdata))
+		}
+	}
+	rr, err := recordForType(common.Type).UnpackRData(NewRData(common, rdata))
+	if err != nil {
+		return nil, fmt.Errorf("RDATAHEX: %s", err)
+	}
+	return rr, nil
+}
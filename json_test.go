@@ -0,0 +1,152 @@
+package rawmdns
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func newJSONTestMessage() DNSMessage {
+	common := func(name string, rt RecordType, ttl uint32) ResourceRecordCommon {
+		return ResourceRecordCommon{Domain: name, Type: rt, Class: ClassINET, TTL: ttl}
+	}
+	flush := common("printer.local", TypeA, 120)
+	flush.CacheFlush = true
+	return DNSMessage{
+		Hdr: DNSHeader{ID: 7, IsResponse: true, Authoritative: true, AuthenticatedData: true, NumQuestions: 1, NumAnswers: 3, NumAddlRecords: 2},
+		Questions: []DNSQuestion{
+			{Domain: "printer.local", Type: TypeA, Class: ClassINET, AcceptUnicastResponse: true},
+		},
+		Answers: []DNSResourceRecord{
+			ARecord{Common: flush, Addr: net.ParseIP("10.0.0.5").To4()},
+			TXTRecord{Common: common("My Printer._ipp._tcp.local", TypeTXT, 4500), texts: []string{"txtvers=1", "note=lobby"}},
+			HTTPSRecord{Common: common("lab.example", TypeHTTPS, 300), Priority: 1, Params: []SvcParam{
+				SvcALPN{IDs: []string{"h2"}},
+			}},
+		},
+		Additional: []DNSResourceRecord{
+			UnknownRecord{Common: common("printer.local", 65301, 120), RData: []byte{0xde, 0xad}},
+			OPTRecord{Common: ResourceRecordCommon{Type: TypeOPT, Class: 1440}, Options: map[uint16][]byte{4: {0, 1, 2, 3, 4, 5}}},
+		},
+	}
+}
+
+func TestDNSMessage_JSONRoundtrip(t *testing.T) {
+	orig := newJSONTestMessage()
+	b, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatalf("json.Marshal: %s", err)
+	}
+
+	var parsed DNSMessage
+	if err = json.Unmarshal(b, &parsed); err != nil {
+		t.Fatalf("json.Unmarshal: %s", err)
+	}
+	if diffs := DiffMessages(orig, parsed); diffs != nil {
+		t.Fatalf("Message changed through JSON: %v", diffs)
+	}
+
+	// JSON -> DNSMessage -> wire -> JSON
+	wire, err := parsed.ToBytes()
+	if err != nil {
+		t.Fatalf("ToBytes: %s", err)
+	}
+	d := NewDecoder(bytes.NewReader(wire))
+	decoded, err := d.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("DecodeDNSMessage: %s", err)
+	}
+	b2, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("json.Marshal: %s", err)
+	}
+	if !bytes.Equal(b, b2) {
+		t.Errorf("JSON changed through wire format:\n%s\n%s", b, b2)
+	}
+}
+
+func TestDNSMessage_JSONMembers(t *testing.T) {
+	b, err := newJSONTestMessage().ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON: %s", err)
+	}
+	for _, member := range []string{
+		`"QNAME":"printer.local."`,
+		`"QCLASS":32769`,
+		`"CLASS":32769`,
+		`"rdataA":"10.0.0.5"`,
+		`"rdataTXT":"\"txtvers=1\" \"note=lobby\""`,
+		`"RDATAHEX":"DEAD"`,
+		`"TYPEname":"OPT"`,
+	} {
+		if !strings.Contains(string(b), member) {
+			t.Errorf("%s missing from %s", member, b)
+		}
+	}
+	if strings.Contains(string(b), "messageOctetsHEX") {
+		t.Errorf("Unexpected messageOctetsHEX in %s", b)
+	}
+}
+
+func TestDNSMessage_JSONMessageOctets(t *testing.T) {
+	orig := newJSONTestMessage()
+	b, err := orig.ToJSON(JSONIncludeMessageOctets)
+	if err != nil {
+		t.Fatalf("ToJSON: %s", err)
+	}
+	// Only messageOctetsHEX should be used when present
+	var jm map[string]interface{}
+	if err = json.Unmarshal(b, &jm); err != nil {
+		t.Fatalf("json.Unmarshal: %s", err)
+	}
+	delete(jm, "answerRRs")
+	b, _ = json.Marshal(jm)
+
+	var parsed DNSMessage
+	if err = json.Unmarshal(b, &parsed); err != nil {
+		t.Fatalf("json.Unmarshal: %s", err)
+	}
+	if diffs := DiffMessages(orig, parsed); diffs != nil {
+		t.Errorf("Message changed through messageOctetsHEX: %v", diffs)
+	}
+}
+
+func TestDNSMessage_UnmarshalJSONPresentation(t *testing.T) {
+	in := `{"ID":0,"QR":true,"ANCOUNT":1,"QNAME":"printer.local.","QTYPEname":"SRV","QCLASS":1,
+		"answerRRs":[{"NAME":"printer._ipp._tcp.local.","TYPEname":"SRV","CLASSname":"IN","TTL":120,
+		"rdataSRV":"0 0 631 printer.local."}]}`
+	var dm DNSMessage
+	if err := json.Unmarshal([]byte(in), &dm); err != nil {
+		t.Fatalf("json.Unmarshal: %s", err)
+	}
+	if len(dm.Questions) != 1 || dm.Questions[0].Type != TypeSRV {
+		t.Errorf("Bad questions: %v", dm.Questions)
+	}
+	srv, ok := dm.Answers[0].(SRVRecord)
+	if !ok || srv.Port != 631 || srv.Target != "printer.local" || srv.Common.Domain != "printer._ipp._tcp.local" {
+		t.Errorf("Bad answer: %v", dm.Answers[0])
+	}
+}
+
+func TestDNSMessage_UnmarshalJSONErrors(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected string
+	}{
+		{`{"answerRRs":[{"TYPE":1,"CLASS":1}]}`, "answerRRs[0]: missing NAME"},
+		{`{"answerRRs":[{"NAME":".","CLASS":1}]}`, "answerRRs[0]: missing TYPE"},
+		{`{"answerRRs":[{"NAME":".","TYPE":1,"CLASS":1,"RDLENGTH":4,"RDATAHEX":"0A00"}]}`, "answerRRs[0]: RDLENGTH is 4, but RDATAHEX is 2 bytes"},
+		{`{"additionalRRs":[{"NAME":".","TYPE":1,"CLASS":1,"rdataA":"fe80::1"}]}`, "additionalRRs[0]: rdataA:"},
+		{`{"answerRRs":[{"NAME":"a.","TYPE":1,"CLASS":1}]}`, "answerRRs[0]: missing RDATAHEX"},
+		{`{"answerRRs":[{"NAME":"a.","TYPE":1,"CLASS":1,"RDATAHEX":"01"}]}`, "answerRRs[0]: RDATAHEX: TypeA: RDATA must be 4 bytes, got 1"},
+	}
+	for _, tc := range testCases {
+		var dm DNSMessage
+		err := json.Unmarshal([]byte(tc.in), &dm)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+			t.Errorf("json.Unmarshal(%s) returned %v, expected %q", tc.in, err, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package rawmdns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is how serious a Finding is.
+type Severity int
+
+const (
+	// SeverityInfo means the message departs from a recommendation, such as
+	// the suggested TTLs, which it may have good reason to.
+	SeverityInfo Severity = iota
+	// SeverityWarning means the message breaks a SHOULD in the RFCs.
+	SeverityWarning
+	// SeverityError means the message breaks a MUST in the RFCs.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Context describes how a message given to Lint was, or will be, sent, since
+// several rules depend on it.
+type Context struct {
+	// Multicast is set if the message is sent to the mDNS multicast group,
+	// rather than directly to one host.
+	Multicast bool
+	// LegacyUnicast is set if the message is a legacy unicast query, i.e. one
+	// sent from a port other than 5353, or a response to such a query; see
+	// RFC 6762 section 6.7.
+	LegacyUnicast bool
+	// Announcement is set if the message is an unsolicited response
+	// announcing services, which should include the service type enumeration
+	// PTR for each of them.
+	Announcement bool
+}
+
+// Finding is a single problem found by Lint. Section and Index locate the
+// question or record it is about; for the header, or the message as a whole,
+// Section is SectionHeader and Index is -1. Reference names the RFC section
+// holding the rule, e.g. "RFC 6762 section 18.1".
+type Finding struct {
+	Severity  Severity
+	Section   MessageSection
+	Index     int
+	Reference string
+	Message   string
+}
+
+func (f Finding) String() string {
+	where := string(f.Section)
+	if f.Index >= 0 {
+		where = fmt.Sprintf("%s[%d]", f.Section, f.Index)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Severity, where, f.Message, f.Reference)
+}
+
+// Recommended TTLs; see RFC 6762 section 10.
+const (
+	lintHostTTL        = 120
+	lintOtherTTL       = 4500
+	lintLegacyTTL      = 10
+	lintMaxMessageSize = 9000 - 20 - 8 // the limit covers the IPv4 and UDP headers
+	lintMaxTXTSize     = 1300
+)
+
+// serviceTypeEnumeration is the name under which DNS-SD service types are
+// listed; see RFC 6763 section 9.
+const serviceTypeEnumeration = "_services._dns-sd._udp"
+
+type linter struct {
+	dm       DNSMessage
+	ctx      Context
+	findings []Finding
+}
+
+func (l *linter) add(severity Severity, section MessageSection, index int, reference, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{
+		Severity:  severity,
+		Section:   section,
+		Index:     index,
+		Reference: reference,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// Lint checks a message against the rules of RFC 6762 (Multicast DNS) and RFC
+// 6763 (DNS-Based Service Discovery), and returns what it finds, in the order
+// the parts of the message they are about appear. It returns nil if it finds
+// nothing.
+func Lint(dm DNSMessage, ctx Context) []Finding {
+	l := &linter{dm: dm, ctx: ctx}
+	l.header()
+	l.questions()
+	l.records()
+	if dm.Hdr.IsResponse {
+		l.additionalRecords()
+		if ctx.Announcement {
+			l.serviceTypes()
+		}
+	}
+	l.size()
+	return l.findings
+}
+
+func (l *linter) header() {
+	hdr := l.dm.Hdr
+	switch {
+	case !l.ctx.Multicast || l.ctx.LegacyUnicast || hdr.ID == 0:
+	case hdr.IsResponse:
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.1", "multicast response has query ID %d, expected 0", hdr.ID)
+	default:
+		l.add(SeverityWarning, SectionHeader, -1, "RFC 6762 section 18.1", "multicast query has query ID %d, expected 0", hdr.ID)
+	}
+	if hdr.OpCode != 0 {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.3", "opcode is %d, expected 0", hdr.OpCode)
+	}
+	if hdr.IsResponse != hdr.Authoritative {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.4", "AA bit is %t in a %s", hdr.Authoritative, l.kind())
+	}
+	if hdr.IsResponse && hdr.Truncated && l.ctx.Multicast {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.5", "TC bit is set in a multicast response")
+	}
+	if hdr.RecursionDesired {
+		l.add(SeverityWarning, SectionHeader, -1, "RFC 6762 section 18.6", "RD bit is set")
+	}
+	if hdr.RecursionAvailable {
+		l.add(SeverityWarning, SectionHeader, -1, "RFC 6762 section 18.7", "RA bit is set")
+	}
+	if hdr.Reserved {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.8", "Z bit is set")
+	}
+	if hdr.AuthenticatedData {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.9", "AD bit is set")
+	}
+	if hdr.CheckingDisabled {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.10", "CD bit is set")
+	}
+	if hdr.ResponseCode != 0 {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 18.11", "response code is %d, expected 0", hdr.ResponseCode)
+	}
+
+	counts := []struct {
+		name          string
+		header, count int
+	}{
+		{"questions", int(hdr.NumQuestions), len(l.dm.Questions)},
+		{"answers", int(hdr.NumAnswers), len(l.dm.Answers)},
+		{"authority records", int(hdr.NumNameServers), len(l.dm.Authority)},
+		{"additional records", int(hdr.NumAddlRecords), len(l.dm.Additional)},
+	}
+	for _, c := range counts {
+		if c.header != c.count {
+			l.add(SeverityError, SectionHeader, -1, "RFC 1035 section 4.1.1", "header counts %d %s, message has %d", c.header, c.name, c.count)
+		}
+	}
+}
+
+func (l *linter) kind() string {
+	if l.dm.Hdr.IsResponse {
+		return "response"
+	}
+	return "query"
+}
+
+func (l *linter) questions() {
+	if !l.dm.Hdr.IsResponse {
+		return
+	}
+	switch {
+	case l.ctx.LegacyUnicast && len(l.dm.Questions) == 0:
+		l.add(SeverityError, SectionQuestion, -1, "RFC 6762 section 6.7", "legacy unicast response does not repeat the question")
+	case l.ctx.Multicast && !l.ctx.LegacyUnicast:
+		for i := range l.dm.Questions {
+			l.add(SeverityError, SectionQuestion, i, "RFC 6762 section 6", "multicast response contains a question")
+		}
+	}
+}
+
+func (l *linter) records() {
+	sections := []struct {
+		section MessageSection
+		rrs     []DNSResourceRecord
+	}{
+		{SectionAnswer, l.dm.Answers},
+		{SectionAuthority, l.dm.Authority},
+		{SectionAdditional, l.dm.Additional},
+	}
+	for _, s := range sections {
+		for i, rr := range s.rrs {
+			l.cacheFlush(s.section, i, rr)
+			l.ttl(s.section, i, rr)
+			if tr, ok := rr.(TXTRecord); ok {
+				l.txt(s.section, i, tr)
+			}
+		}
+	}
+}
+
+func (l *linter) cacheFlush(section MessageSection, index int, rr DNSResourceRecord) {
+	common := rr.GetCommon()
+	switch {
+	case !common.CacheFlush:
+	case !l.dm.Hdr.IsResponse:
+		l.add(SeverityError, section, index, "RFC 6762 section 10.2", "cache-flush bit is set in a query")
+	case l.ctx.LegacyUnicast:
+		l.add(SeverityError, section, index, "RFC 6762 section 10.2", "cache-flush bit is set in a legacy unicast response")
+	case common.Type == TypePTR && isServiceName(common.Domain):
+		l.add(SeverityError, section, index, "RFC 6762 section 10.2", "cache-flush bit is set on shared PTR record %s", common.Domain)
+	}
+}
+
+func (l *linter) ttl(section MessageSection, index int, rr DNSResourceRecord) {
+	common := rr.GetCommon()
+	if !l.dm.Hdr.IsResponse || common.TTL == 0 {
+		// TTLs of known answers count down, and 0 is a goodbye
+		return
+	}
+	if l.ctx.LegacyUnicast {
+		if common.TTL > lintLegacyTTL {
+			l.add(SeverityWarning, section, index, "RFC 6762 section 6.7", "TTL %d in a legacy unicast response is over %d", common.TTL, lintLegacyTTL)
+		}
+		return
+	}
+	var expected uint32
+	switch common.Type {
+	case TypeA, TypeAAAA, TypeSRV, TypeHINFO:
+		expected = lintHostTTL
+	case TypePTR:
+		// Reverse address lookups name a host, and service PTRs don't
+		expected = lintOtherTTL
+		if !isServiceName(common.Domain) {
+			expected = lintHostTTL
+		}
+	case TypeOPT, TypeNSEC:
+		// OPT has no TTL, and NSEC takes the TTL of the records it covers
+		return
+	default:
+		expected = lintOtherTTL
+	}
+	if common.TTL != expected {
+		l.add(SeverityInfo, section, index, "RFC 6762 section 10", "TTL of %s record is %d, recommended %d", common.Type, common.TTL, expected)
+	}
+}
+
+func (l *linter) txt(section MessageSection, index int, tr TXTRecord) {
+	if len(tr.texts) == 0 {
+		l.add(SeverityError, section, index, "RFC 6763 section 6.1", "TXT record has no strings, expected at least one empty string")
+	}
+	size := 0
+	for _, t := range tr.texts {
+		size += 1 + len(t)
+		if len(t) > 255 {
+			l.add(SeverityError, section, index, "RFC 6763 section 6.1", "TXT string is %d bytes, the limit is 255", len(t))
+		}
+	}
+	if size > lintMaxTXTSize {
+		l.add(SeverityWarning, section, index, "RFC 6763 section 6.2", "TXT record is %d bytes, over %d", size, lintMaxTXTSize)
+	}
+}
+
+// additionalRecords checks that the records a responder is expected to add
+// for a PTR or SRV answer are somewhere in the response.
+func (l *linter) additionalRecords() {
+	have := make(map[rrsetKey]bool)
+	for _, rrs := range [][]DNSResourceRecord{l.dm.Answers, l.dm.Authority, l.dm.Additional} {
+		for _, rr := range rrs {
+			have[lintKey(rr.GetCommon().Domain, rr.GetCommon().Type)] = true
+		}
+	}
+	for i, rr := range l.dm.Answers {
+		switch r := rr.(type) {
+		case PTRRecord:
+			if !isServiceName(r.Common.Domain) || isServiceTypeEnumeration(r.Common.Domain) || r.Common.TTL == 0 {
+				continue
+			}
+			for _, rt := range []RecordType{TypeSRV, TypeTXT} {
+				if !have[lintKey(r.PtrDName, rt)] {
+					l.add(SeverityWarning, SectionAnswer, i, "RFC 6763 section 12.1", "no %s record for PTR target %s", rt, r.PtrDName)
+				}
+			}
+		case SRVRecord:
+			if r.Target == "" || r.Target == "." || r.Common.TTL == 0 {
+				continue
+			}
+			if !have[lintKey(r.Target, TypeA)] && !have[lintKey(r.Target, TypeAAAA)] {
+				l.add(SeverityWarning, SectionAnswer, i, "RFC 6763 section 12.2", "no address record for SRV target %s", r.Target)
+			}
+		}
+	}
+}
+
+// serviceTypes checks that each service type announced has a PTR from the
+// service type enumeration name.
+func (l *linter) serviceTypes() {
+	enumerated := make(map[rrsetKey]bool)
+	for _, rrs := range [][]DNSResourceRecord{l.dm.Answers, l.dm.Additional} {
+		for _, rr := range rrs {
+			if pr, ok := rr.(PTRRecord); ok && isServiceTypeEnumeration(pr.Common.Domain) {
+				enumerated[lintKey(pr.PtrDName, TypePTR)] = true
+			}
+		}
+	}
+	reported := make(map[string]bool)
+	for i, rr := range l.dm.Answers {
+		pr, ok := rr.(PTRRecord)
+		if !ok || !isServiceName(pr.Common.Domain) || isServiceTypeEnumeration(pr.Common.Domain) {
+			continue
+		}
+		serviceType := serviceTypeOf(pr.Common.Domain)
+		key := lintKey(serviceType, TypePTR)
+		if !enumerated[key] && !reported[key.domain] {
+			reported[key.domain] = true
+			l.add(SeverityWarning, SectionAnswer, i, "RFC 6763 section 9", "no %s PTR record for service type %s", serviceTypeEnumeration, serviceType)
+		}
+	}
+}
+
+func (l *linter) size() {
+	b, err := l.dm.ToBytes()
+	if err != nil {
+		l.add(SeverityError, SectionHeader, -1, "RFC 1035 section 4.1", "message cannot be encoded: %s", err)
+		return
+	}
+	if len(b) > lintMaxMessageSize {
+		l.add(SeverityError, SectionHeader, -1, "RFC 6762 section 17", "message is %d bytes, over %d", len(b), lintMaxMessageSize)
+	}
+}
+
+func lintKey(domain string, rt RecordType) rrsetKey {
+	return rrsetKeyFor(ResourceRecordCommon{Domain: domain, Type: rt})
+}
+
+// isServiceName reports whether a name is a DNS-SD service type, such as
+// "_ipp._tcp.local", or a subtype of one, such as
+// "_printer._sub._ipp._tcp.local". PTR records with these names are shared,
+// unlike the PTR records of reverse address lookups.
+func isServiceName(domain string) bool {
+	labels := strings.Split(strings.TrimSuffix(domain, "."), ".")
+	return len(labels) >= 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_")
+}
+
+func isServiceTypeEnumeration(domain string) bool {
+	return strings.HasPrefix(strings.ToLower(domain), serviceTypeEnumeration+".")
+}
+
+// serviceTypeOf strips any subtype from a service name, leaving e.g.
+// "_ipp._tcp.local".
+func serviceTypeOf(domain string) string {
+	labels := strings.Split(strings.TrimSuffix(domain, "."), ".")
+	if len(labels) > 2 && strings.EqualFold(labels[1], "_sub") {
+		labels = labels[2:]
+	}
+	return strings.Join(labels, ".")
+}
@@ -0,0 +1,187 @@
+package rawmdns
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLint_capture(t *testing.T) {
+	b, err := os.ReadFile("testdata/airplay-answer.cap")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %s", err)
+	}
+	d := NewDecoder(bytes.NewReader(b))
+	dm, err := d.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("DecodeDNSMessage: %s", err)
+	}
+	if findings := Lint(dm, Context{Multicast: true, Announcement: true}); findings != nil {
+		t.Errorf("Unexpected findings: %v", findings)
+	}
+}
+
+// lintTestResponse returns a multicast announcement of an IPP printer which
+// Lint should find nothing wrong with.
+func lintTestResponse() DNSMessage {
+	common := func(domain string, rt RecordType, ttl uint32, cacheFlush bool) ResourceRecordCommon {
+		return ResourceRecordCommon{Domain: domain, Type: rt, Class: ClassINET, TTL: ttl, CacheFlush: cacheFlush}
+	}
+	return DNSMessage{
+		Hdr: DNSHeader{IsResponse: true, Authoritative: true, NumAnswers: 4, NumAddlRecords: 1},
+		Answers: []DNSResourceRecord{
+			PTRRecord{Common: common("_services._dns-sd._udp.local", TypePTR, 4500, false), PtrDName: "_ipp._tcp.local"},
+			PTRRecord{Common: common("_ipp._tcp.local", TypePTR, 4500, false), PtrDName: "printer._ipp._tcp.local"},
+			SRVRecord{Common: common("printer._ipp._tcp.local", TypeSRV, 120, true), Port: 631, Target: "printer.local"},
+			TXTRecord{Common: common("printer._ipp._tcp.local", TypeTXT, 4500, true), texts: []string{"txtvers=1"}},
+		},
+		Additional: []DNSResourceRecord{
+			ARecord{Common: common("printer.local", TypeA, 120, true), Addr: net.ParseIP("10.0.0.5").To4()},
+		},
+	}
+}
+
+func TestLint(t *testing.T) {
+	ctx := Context{Multicast: true, Announcement: true}
+	if findings := Lint(lintTestResponse(), ctx); findings != nil {
+		t.Fatalf("Unexpected findings for the good message: %v", findings)
+	}
+
+	testCases := []struct {
+		name     string
+		ctx      Context
+		change   func(dm *DNSMessage)
+		expected string
+	}{
+		{
+			name:     "query ID",
+			change:   func(dm *DNSMessage) { dm.Hdr.ID = 7 },
+			expected: "error: header: multicast response has query ID 7, expected 0 (RFC 6762 section 18.1)",
+		},
+		{
+			name: "question",
+			change: func(dm *DNSMessage) {
+				dm.Questions = []DNSQuestion{{Domain: "_ipp._tcp.local", Type: TypePTR, Class: ClassINET}}
+				dm.Hdr.NumQuestions = 1
+			},
+			expected: "error: question[0]: multicast response contains a question (RFC 6762 section 6)",
+		},
+		{
+			name:     "opcode",
+			change:   func(dm *DNSMessage) { dm.Hdr.OpCode = 4 },
+			expected: "error: header: opcode is 4, expected 0 (RFC 6762 section 18.3)",
+		},
+		{
+			name:     "rcode",
+			change:   func(dm *DNSMessage) { dm.Hdr.ResponseCode = 3 },
+			expected: "error: header: response code is 3, expected 0 (RFC 6762 section 18.11)",
+		},
+		{
+			name: "shared PTR cache-flush",
+			change: func(dm *DNSMessage) {
+				ptr := dm.Answers[1].(PTRRecord)
+				ptr.Common.CacheFlush = true
+				dm.Answers[1] = ptr
+			},
+			expected: "error: answer[1]: cache-flush bit is set on shared PTR record _ipp._tcp.local (RFC 6762 section 10.2)",
+		},
+		{
+			name: "TTL",
+			change: func(dm *DNSMessage) {
+				txt := dm.Answers[3].(TXTRecord)
+				txt.Common.TTL = 120
+				dm.Answers[3] = txt
+			},
+			expected: "info: answer[3]: TTL of TXT record is 120, recommended 4500 (RFC 6762 section 10)",
+		},
+		{
+			name: "SRV target",
+			change: func(dm *DNSMessage) {
+				dm.Additional = nil
+				dm.Hdr.NumAddlRecords = 0
+			},
+			expected: "warning: answer[2]: no address record for SRV target printer.local (RFC 6763 section 12.2)",
+		},
+		{
+			name: "service type enumeration",
+			change: func(dm *DNSMessage) {
+				dm.Answers = dm.Answers[1:]
+				dm.Hdr.NumAnswers--
+			},
+			expected: "warning: answer[0]: no _services._dns-sd._udp PTR record for service type _ipp._tcp.local (RFC 6763 section 9)",
+		},
+		{
+			name: "TXT string",
+			change: func(dm *DNSMessage) {
+				txt := dm.Answers[3].(TXTRecord)
+				txt.texts = []string{strings.Repeat("x", 300)}
+				dm.Answers[3] = txt
+			},
+			expected: "error: answer[3]: TXT string is 300 bytes, the limit is 255 (RFC 6763 section 6.1)",
+		},
+		{
+			name:     "header count",
+			change:   func(dm *DNSMessage) { dm.Hdr.NumAnswers = 5 },
+			expected: "error: header: header counts 5 answers, message has 4 (RFC 1035 section 4.1.1)",
+		},
+		{
+			name: "legacy unicast",
+			ctx:  Context{LegacyUnicast: true},
+			change: func(dm *DNSMessage) {
+				dm.Answers = dm.Answers[2:3]
+				dm.Hdr.NumAnswers = 1
+				dm.Additional = nil
+				dm.Hdr.NumAddlRecords = 0
+			},
+			expected: "error: question: legacy unicast response does not repeat the question (RFC 6762 section 6.7)",
+		},
+	}
+	for _, tc := range testCases {
+		dm := lintTestResponse()
+		tc.change(&dm)
+		if tc.ctx == (Context{}) {
+			tc.ctx = ctx
+		}
+		found := false
+		var got []string
+		for _, f := range Lint(dm, tc.ctx) {
+			got = append(got, f.String())
+			if f.String() == tc.expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected finding %q, got:\n%s", tc.name, tc.expected, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestLint_query(t *testing.T) {
+	dm := DNSMessage{
+		Hdr:       DNSHeader{ID: 1, RecursionDesired: true, NumQuestions: 1, NumAnswers: 1},
+		Questions: []DNSQuestion{{Domain: "_ipp._tcp.local", Type: TypePTR, Class: ClassINET}},
+		Answers: []DNSResourceRecord{
+			// a known answer, whose TTL has counted down
+			PTRRecord{
+				Common:   ResourceRecordCommon{Domain: "_ipp._tcp.local", Type: TypePTR, Class: ClassINET, TTL: 3000, CacheFlush: true},
+				PtrDName: "printer._ipp._tcp.local",
+			},
+		},
+	}
+	expected := []string{
+		"warning: header: multicast query has query ID 1, expected 0 (RFC 6762 section 18.1)",
+		"warning: header: RD bit is set (RFC 6762 section 18.6)",
+		"error: answer[0]: cache-flush bit is set in a query (RFC 6762 section 10.2)",
+	}
+	findings := Lint(dm, Context{Multicast: true})
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %v", len(expected), findings)
+	}
+	for i, f := range findings {
+		if f.String() != expected[i] {
+			t.Errorf("Finding %d is %q, expected %q", i, f, expected[i])
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package rawmdns
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// locEquator is the wire value of 0 degrees latitude or longitude; angles
+	// are offsets from it in thousandths of a second of arc.
+	locEquator = 1 << 31
+	// locMilliArcSecPerDegree is the resolution of LOC angles.
+	locMilliArcSecPerDegree = 3600000
+	// locAltitudeBase is the wire value of 0m altitude; altitudes are offsets
+	// from it in centimeters, so the lowest is 100km below the spheroid.
+	locAltitudeBase = 10000000
+)
+
+// LOCPrecision converts a size or precision in meters to RFC 1876's
+// exponent-mantissa encoding, in which the high nibble is a mantissa (0-9)
+// and the low nibble a power of ten, in centimeters. Values are rounded to
+// the nearest representable size.
+func LOCPrecision(meters float64) (uint8, error) {
+	if meters < 0 || math.IsNaN(meters) {
+		return 0, fmt.Errorf("Precision %f must be non-negative", meters)
+	}
+	cm := meters * 100
+	for exponent := 0; exponent <= 9; exponent++ {
+		mantissa := math.Floor(cm/math.Pow10(exponent) + 0.5)
+		if mantissa <= 9 {
+			return uint8(mantissa)<<4 | uint8(exponent), nil
+		}
+	}
+	return 0, fmt.Errorf("Precision %fm exceeds 90000km", meters)
+}
+
+// LOCPrecisionMeters is the inverse of LOCPrecision.
+func LOCPrecisionMeters(b uint8) float64 {
+	return float64(b>>4) * math.Pow10(int(b&0x0F)) / 100
+}
+
+// locPrecisionString formats b in meters without rounding, as it would
+// appear in a master file, e.g. "0.00", "1" or "10000".
+func locPrecisionString(b uint8) string {
+	mantissa := int(b >> 4)
+	exponent := int(b & 0x0F)
+	switch exponent {
+	case 0:
+		return fmt.Sprintf("0.%02d", mantissa)
+	case 1:
+		return fmt.Sprintf("0.%02d", mantissa*10)
+	default:
+		return fmt.Sprintf("%d", mantissa) + strings.Repeat("0", exponent-2)
+	}
+}
+
+func locAngleFromWire(v uint32) float64 {
+	return float64(int64(v)-locEquator) / locMilliArcSecPerDegree
+}
+
+func locAngleToWire(degrees float64) uint32 {
+	return uint32(int64(locEquator) + int64(math.Floor(degrees*locMilliArcSecPerDegree+0.5)))
+}
+
+func locAltitudeFromWire(v uint32) float64 {
+	return float64(int64(v)-locAltitudeBase) / 100
+}
+
+func locAltitudeToWire(meters float64) uint32 {
+	return uint32(int64(math.Floor(meters*100+0.5)) + locAltitudeBase)
+}
+
+// wireCoordinates validates and converts the record's coordinates to their
+// wire-format representations.
+func (lr LOCRecord) wireCoordinates() (uint32, uint32, uint32, error) {
+	if math.IsNaN(lr.Latitude) || lr.Latitude < -90 || lr.Latitude > 90 {
+		return 0, 0, 0, fmt.Errorf("Latitude %f out of range", lr.Latitude)
+	}
+	if math.IsNaN(lr.Longitude) || lr.Longitude < -180 || lr.Longitude > 180 {
+		return 0, 0, 0, fmt.Errorf("Longitude %f out of range", lr.Longitude)
+	}
+	if math.IsNaN(lr.Altitude) || lr.Altitude < -100000 || lr.Altitude > 42849672.95 {
+		return 0, 0, 0, fmt.Errorf("Altitude %f out of range", lr.Altitude)
+	}
+	return locAngleToWire(lr.Latitude), locAngleToWire(lr.Longitude), locAltitudeToWire(lr.Altitude), nil
+}
+
+// checkPrecisions returns an error unless the Size, HorizPre and VertPre of
+// the record are valid exponent-mantissa encodings: RFC 1876 section 2 limits
+// both nibbles to 0-9.
+func (lr LOCRecord) checkPrecisions() error {
+	for _, p := range []struct {
+		name string
+		b    uint8
+	}{{"Size", lr.Size}, {"HorizPre", lr.HorizPre}, {"VertPre", lr.VertPre}} {
+		if p.b>>4 > 9 || p.b&0x0F > 9 {
+			return fmt.Errorf("%s 0x%02x has a mantissa or exponent over 9", p.name, p.b)
+		}
+	}
+	return nil
+}
+
+// RDataString returns the record's RDATA in the master-file format of RFC
+// 1876 section 3, e.g. "52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m".
+func (lr LOCRecord) RDataString() string {
+	lat, lon, alt, err := lr.wireCoordinates()
+	if err == nil {
+		err = lr.checkPrecisions()
+	}
+	if err != nil {
+		return fmt.Sprintf("; invalid LOC: %s", err)
+	}
+	altCM := int64(alt) - locAltitudeBase
+	altSign := ""
+	if altCM < 0 {
+		altSign = "-"
+		altCM = -altCM
+	}
+	return fmt.Sprintf("%s %s %s%d.%02dm %sm %sm %sm",
+		locAngleString(lat, "N", "S"),
+		locAngleString(lon, "E", "W"),
+		altSign, altCM/100, altCM%100,
+		locPrecisionString(lr.Size),
+		locPrecisionString(lr.HorizPre),
+		locPrecisionString(lr.VertPre),
+	)
+}
+
+// locAngleString formats a wire-format angle as degrees, minutes and
+// seconds, computed with integer arithmetic to avoid float rounding.
+func locAngleString(v uint32, positive, negative string) string {
+	hemisphere := positive
+	offset := int64(v) - locEquator
+	if offset < 0 {
+		hemisphere = negative
+		offset = -offset
+	}
+	degrees := offset / locMilliArcSecPerDegree
+	offset %= locMilliArcSecPerDegree
+	minutes := offset / 60000
+	offset %= 60000
+	return fmt.Sprintf("%d %d %d.%03d %s", degrees, minutes, offset/1000, offset%1000, hemisphere)
+}
@@ -0,0 +1,98 @@
+package rawmdns
+
+import (
+	"math"
+	"testing"
+)
+
+// Position of the RIPE NCC offices, from the example in RFC 1876 section 3.
+func TestLOCRecord_RDataString(t *testing.T) {
+	lr := LOCRecord{
+		Latitude:  52 + 22.0/60 + 23.0/3600,
+		Longitude: 4 + 53.0/60 + 32.0/3600,
+		Altitude:  -2,
+		Size:      0x00,
+		HorizPre:  0x16,
+		VertPre:   0x13,
+	}
+	expected := "52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m"
+	if lr.RDataString() != expected {
+		t.Errorf("%q != %q", lr.RDataString(), expected)
+	}
+
+	lr = LOCRecord{
+		Latitude:  -(42 + 21.0/60 + 43.528/3600),
+		Longitude: -(71 + 5.0/60 + 6.284/3600),
+		Altitude:  -24,
+		Size:      0x12,
+		HorizPre:  0x16,
+		VertPre:   0x13,
+	}
+	expected = "42 21 43.528 S 71 5 6.284 W -24.00m 1m 10000m 10m"
+	if lr.RDataString() != expected {
+		t.Errorf("%q != %q", lr.RDataString(), expected)
+	}
+}
+
+func TestLOCPrecision(t *testing.T) {
+	testCases := map[float64]uint8{
+		0:     0x00,
+		0.01:  0x10,
+		1:     0x12,
+		10:    0x13,
+		10000: 0x16,
+		15:    0x23,
+	}
+	for meters, expected := range testCases {
+		b, err := LOCPrecision(meters)
+		if err != nil {
+			t.Fatalf("Unexpected error from LOCPrecision(%f): %s", meters, err)
+		}
+		if b != expected {
+			t.Errorf("LOCPrecision(%f): 0x%02x != 0x%02x", meters, b, expected)
+		}
+	}
+	if m := LOCPrecisionMeters(0x16); math.Abs(m-10000) > 1e-9 {
+		t.Errorf("LOCPrecisionMeters(0x16): %f != 10000", m)
+	}
+	if _, err := LOCPrecision(1e8); err == nil {
+		t.Error("Expected error from LOCPrecision(1e8), got none")
+	}
+}
+
+func TestLOCRecord_invalidPrecision(t *testing.T) {
+	common := ResourceRecordCommon{Domain: "printer.local", Type: TypeLOC, Class: ClassINET, TTL: 120}
+	rdata := []byte{
+		0x00,             // VERSION
+		0x12, 0x16, 0x13, // SIZE, HORIZ PRE, VERT PRE
+		0x80, 0x00, 0x00, 0x00, // LATITUDE, the equator
+		0x80, 0x00, 0x00, 0x00, // LONGITUDE, the prime meridian
+		0x00, 0x98, 0x96, 0x80, // ALTITUDE, sea level
+	}
+	if _, err := (LOCRecord{}).UnpackRData(NewRData(common, rdata)); err != nil {
+		t.Fatalf("Unexpected error from UnpackRData: %s", err)
+	}
+
+	testCases := []struct {
+		i        int
+		b        uint8
+		expected string
+	}{
+		{1, 0xA2, "TypeLOC: Size 0xa2 has a mantissa or exponent over 9"},
+		{2, 0x1A, "TypeLOC: HorizPre 0x1a has a mantissa or exponent over 9"},
+		{3, 0xFF, "TypeLOC: VertPre 0xff has a mantissa or exponent over 9"},
+	}
+	for _, tc := range testCases {
+		bad := append([]byte(nil), rdata...)
+		bad[tc.i] = tc.b
+		_, err := (LOCRecord{}).UnpackRData(NewRData(common, bad))
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("UnpackRData with 0x%02x at %d returned %v, expected %q", tc.b, tc.i, err, tc.expected)
+		}
+	}
+
+	lr := LOCRecord{Common: common, Size: 0x12, HorizPre: 0x16, VertPre: 0xA0}
+	if _, err := lr.PackRData(); err == nil {
+		t.Error("Expected error from PackRData with VertPre 0xa0, got none")
+	}
+}
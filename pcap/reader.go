@@ -0,0 +1,537 @@
+// Package pcap reads mDNS messages out of packet captures, such as those
+// written by tcpdump or Wireshark, in either the classic pcap or the pcapng
+// file format, and writes encoded messages to new pcap files.
+package pcap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/sayotte/rawmdns"
+)
+
+// MDNSPort is the UDP port mDNS traffic is sent from or to, see RFC 6762
+// section 3.
+const MDNSPort = 5353
+
+// Link-layer header types, see http://www.tcpdump.org/linktypes.html
+const (
+	linkTypeNull      = 0
+	linkTypeEthernet  = 1
+	linkTypeRaw       = 101
+	linkTypeLinuxSLL  = 113
+	linkTypeIPv4      = 228
+	linkTypeIPv6      = 229
+	linkTypeLinuxSLL2 = 276
+)
+
+const (
+	pcapMagicMicros = 0xa1b2c3d4
+	pcapMagicNanos  = 0xa1b23c4d
+
+	pcapngBlockSHB       = 0x0a0d0d0a
+	pcapngBlockIDB       = 0x00000001
+	pcapngBlockSPB       = 0x00000003
+	pcapngBlockEPB       = 0x00000006
+	pcapngByteOrderMagic = 0x1a2b3c4d
+
+	pcapngOptionEnd      = 0
+	pcapngOptionTSResol  = 9
+	pcapngOptionTSOffset = 14
+)
+
+// maxPcapngBlockLen bounds the memory a corrupt block length can make the
+// Reader allocate.
+const maxPcapngBlockLen = 16 * 1024 * 1024
+
+// Packet is an mDNS message read from a capture.
+type Packet struct {
+	Timestamp time.Time
+	Src       *net.UDPAddr
+	Dst       *net.UDPAddr
+	// Payload is the UDP payload, i.e. the message in wire format.
+	Payload []byte
+	// Message and DecodeErr are the result of Decoder.DecodeDNSMessage on
+	// Payload.
+	Message   rawmdns.DNSMessage
+	DecodeErr error
+}
+
+// pcapngInterface is what the Reader needs from a pcapng Interface
+// Description Block.
+type pcapngInterface struct {
+	linkType uint16
+	snapLen  uint32
+	// units per second of EPB timestamps, and seconds to add to them
+	tsUnitsPerSec uint64
+	tsOffset      int64
+}
+
+// Reader reads mDNS packets from a pcap or pcapng capture.
+type Reader struct {
+	rdr        *bufio.Reader
+	isPcapng   bool
+	byteOrder  binary.ByteOrder
+	reassembly *reassembler
+	filter     *rawmdns.Filter
+
+	// classic pcap only
+	linkType uint16
+	nanos    bool
+
+	// pcapng only
+	interfaces []pcapngInterface
+}
+
+// NewReader reads the file header from r, which may be in either the pcap or
+// the pcapng format.
+func NewReader(r io.Reader) (*Reader, error) {
+	pr := &Reader{rdr: bufio.NewReader(r), reassembly: newReassembler()}
+	magic, err := pr.rdr.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("reading magic number: %s", err)
+	}
+
+	if binary.BigEndian.Uint32(magic) == pcapngBlockSHB {
+		pr.isPcapng = true
+		// The section header is read like any other block, but must come
+		// first
+		if err = pr.readPcapngSectionHeader(); err != nil {
+			return nil, err
+		}
+		return pr, nil
+	}
+
+	hdr := make([]byte, 24)
+	if _, err = io.ReadFull(pr.rdr, hdr); err != nil {
+		return nil, fmt.Errorf("reading pcap header: %s", err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(hdr) == pcapMagicMicros:
+		pr.byteOrder = binary.LittleEndian
+	case binary.LittleEndian.Uint32(hdr) == pcapMagicNanos:
+		pr.byteOrder, pr.nanos = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(hdr) == pcapMagicMicros:
+		pr.byteOrder = binary.BigEndian
+	case binary.BigEndian.Uint32(hdr) == pcapMagicNanos:
+		pr.byteOrder, pr.nanos = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("not a pcap or pcapng file, magic number is %x", hdr[:4])
+	}
+	// The link type is the low 16 bits of the last field, the rest may
+	// hold FCS information
+	pr.linkType = uint16(pr.byteOrder.Uint32(hdr[20:24]))
+	return pr, nil
+}
+
+// SetFilter makes Next skip packets whose message doesn't match f, including
+// any which fail to decode. A nil Filter, the default, selects every packet.
+func (pr *Reader) SetFilter(f *rawmdns.Filter) {
+	pr.filter = f
+}
+
+// Next returns the next mDNS packet in the capture, skipping any frames which
+// are not UDP to or from port 5353, or not selected by the Filter. IPv4 and
+// IPv6 fragments are held until the whole datagram has been seen. At the end
+// of the capture, Next returns io.EOF.
+func (pr *Reader) Next() (Packet, error) {
+	for {
+		frame, linkType, ts, err := pr.nextFrame()
+		if err != nil {
+			return Packet{}, err
+		}
+		pkt, ok := pr.parseFrame(frame, linkType, ts)
+		if ok && (pr.filter == nil || (pkt.DecodeErr == nil && pr.filter.Match(pkt.Message))) {
+			return pkt, nil
+		}
+	}
+}
+
+// nextFrame returns the next captured link-layer frame.
+func (pr *Reader) nextFrame() ([]byte, uint16, time.Time, error) {
+	if pr.isPcapng {
+		return pr.nextPcapngFrame()
+	}
+
+	hdr := make([]byte, 16)
+	_, err := io.ReadFull(pr.rdr, hdr)
+	if err == io.ErrUnexpectedEOF {
+		return nil, 0, time.Time{}, fmt.Errorf("truncated pcap record header")
+	}
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+	secs := pr.byteOrder.Uint32(hdr[0:4])
+	frac := pr.byteOrder.Uint32(hdr[4:8])
+	inclLen := pr.byteOrder.Uint32(hdr[8:12])
+	if inclLen > maxPcapngBlockLen {
+		return nil, 0, time.Time{}, fmt.Errorf("pcap record length %d is too large", inclLen)
+	}
+	frame := make([]byte, inclLen)
+	if _, err = io.ReadFull(pr.rdr, frame); err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("truncated pcap record: %s", err)
+	}
+	nsecs := int64(frac) * 1000
+	if pr.nanos {
+		nsecs = int64(frac)
+	}
+	return frame, pr.linkType, time.Unix(int64(secs), nsecs).UTC(), nil
+}
+
+func (pr *Reader) nextPcapngFrame() ([]byte, uint16, time.Time, error) {
+	for {
+		blockType, body, err := pr.nextPcapngBlock()
+		if err != nil {
+			return nil, 0, time.Time{}, err
+		}
+		switch blockType {
+		case pcapngBlockSHB:
+			if err = pr.parseSHB(body); err != nil {
+				return nil, 0, time.Time{}, err
+			}
+		case pcapngBlockIDB:
+			if err = pr.parseIDB(body); err != nil {
+				return nil, 0, time.Time{}, err
+			}
+		case pcapngBlockEPB:
+			if len(body) < 20 {
+				return nil, 0, time.Time{}, fmt.Errorf("short enhanced packet block")
+			}
+			ifaceID := pr.byteOrder.Uint32(body[0:4])
+			if int(ifaceID) >= len(pr.interfaces) {
+				return nil, 0, time.Time{}, fmt.Errorf("enhanced packet block for undescribed interface %d", ifaceID)
+			}
+			iface := pr.interfaces[ifaceID]
+			ts := uint64(pr.byteOrder.Uint32(body[4:8]))<<32 | uint64(pr.byteOrder.Uint32(body[8:12]))
+			capLen := pr.byteOrder.Uint32(body[12:16])
+			if uint64(capLen) > uint64(len(body)-20) {
+				return nil, 0, time.Time{}, fmt.Errorf("enhanced packet block captured length %d overruns block", capLen)
+			}
+			return body[20 : 20+capLen], iface.linkType, iface.timestamp(ts), nil
+		case pcapngBlockSPB:
+			if len(pr.interfaces) == 0 {
+				return nil, 0, time.Time{}, fmt.Errorf("simple packet block before any interface description")
+			}
+			if len(body) < 4 {
+				return nil, 0, time.Time{}, fmt.Errorf("short simple packet block")
+			}
+			iface := pr.interfaces[0]
+			capLen := pr.byteOrder.Uint32(body[0:4])
+			if iface.snapLen != 0 && capLen > iface.snapLen {
+				capLen = iface.snapLen
+			}
+			if uint64(capLen) > uint64(len(body)-4) {
+				capLen = uint32(len(body) - 4)
+			}
+			// Simple packet blocks carry no timestamp
+			return body[4 : 4+capLen], iface.linkType, time.Time{}, nil
+		}
+		// Any other block type carries no packets, skip it
+	}
+}
+
+func (iface pcapngInterface) timestamp(ts uint64) time.Time {
+	secs := ts / iface.tsUnitsPerSec
+	rem := ts % iface.tsUnitsPerSec
+	nsecs := uint64(float64(rem) * 1e9 / float64(iface.tsUnitsPerSec))
+	return time.Unix(int64(secs)+iface.tsOffset, int64(nsecs)).UTC()
+}
+
+// readPcapngSectionHeader reads the block which must begin a pcapng file;
+// later section headers are handled by nextPcapngFrame.
+func (pr *Reader) readPcapngSectionHeader() error {
+	blockType, body, err := pr.nextPcapngBlock()
+	if err != nil {
+		return fmt.Errorf("reading pcapng section header: %s", err)
+	}
+	if blockType != pcapngBlockSHB {
+		return fmt.Errorf("pcapng file does not start with a section header")
+	}
+	return pr.parseSHB(body)
+}
+
+// nextPcapngBlock returns the type and body of the next block. A section
+// header block sets the byte order of the blocks which follow it, including
+// its own length fields, so it is read specially.
+func (pr *Reader) nextPcapngBlock() (uint32, []byte, error) {
+	hdr := make([]byte, 12)
+	_, err := io.ReadFull(pr.rdr, hdr[:8])
+	if err == io.ErrUnexpectedEOF {
+		return 0, nil, fmt.Errorf("truncated pcapng block header")
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if binary.BigEndian.Uint32(hdr[0:4]) == pcapngBlockSHB {
+		if _, err = io.ReadFull(pr.rdr, hdr[8:12]); err != nil {
+			return 0, nil, fmt.Errorf("truncated pcapng section header: %s", err)
+		}
+		switch {
+		case binary.LittleEndian.Uint32(hdr[8:12]) == pcapngByteOrderMagic:
+			pr.byteOrder = binary.LittleEndian
+		case binary.BigEndian.Uint32(hdr[8:12]) == pcapngByteOrderMagic:
+			pr.byteOrder = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("bad pcapng byte-order magic %x", hdr[8:12])
+		}
+	} else if pr.byteOrder == nil {
+		return 0, nil, fmt.Errorf("pcapng block before any section header")
+	}
+
+	blockType := pr.byteOrder.Uint32(hdr[0:4])
+	blockLen := pr.byteOrder.Uint32(hdr[4:8])
+	if blockLen%4 != 0 || blockLen < 12 || blockLen > maxPcapngBlockLen {
+		return 0, nil, fmt.Errorf("bad pcapng block length %d", blockLen)
+	}
+	headerLen := uint32(8)
+	if blockType == pcapngBlockSHB {
+		headerLen = 12
+		if blockLen < 16 {
+			return 0, nil, fmt.Errorf("bad pcapng section header length %d", blockLen)
+		}
+	}
+	rest := make([]byte, blockLen-headerLen)
+	if _, err = io.ReadFull(pr.rdr, rest); err != nil {
+		return 0, nil, fmt.Errorf("truncated pcapng block: %s", err)
+	}
+	trailer := pr.byteOrder.Uint32(rest[len(rest)-4:])
+	if trailer != blockLen {
+		return 0, nil, fmt.Errorf("pcapng block length %d does not match trailing length %d", blockLen, trailer)
+	}
+	return blockType, rest[:len(rest)-4], nil
+}
+
+func (pr *Reader) parseSHB(body []byte) error {
+	if len(body) < 12 {
+		return fmt.Errorf("short pcapng section header")
+	}
+	if major := pr.byteOrder.Uint16(body[0:2]); major != 1 {
+		return fmt.Errorf("unsupported pcapng major version %d", major)
+	}
+	// Interface IDs are scoped to a section
+	pr.interfaces = nil
+	return nil
+}
+
+func (pr *Reader) parseIDB(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("short pcapng interface description")
+	}
+	iface := pcapngInterface{
+		linkType:      pr.byteOrder.Uint16(body[0:2]),
+		snapLen:       pr.byteOrder.Uint32(body[4:8]),
+		tsUnitsPerSec: 1000000,
+	}
+	opts := body[8:]
+	for len(opts) >= 4 {
+		code := pr.byteOrder.Uint16(opts[0:2])
+		optLen := int(pr.byteOrder.Uint16(opts[2:4]))
+		if code == pcapngOptionEnd {
+			break
+		}
+		padded := (optLen + 3) &^ 3
+		if 4+padded > len(opts) {
+			return fmt.Errorf("pcapng interface option %d overruns block", code)
+		}
+		value := opts[4 : 4+optLen]
+		switch {
+		case code == pcapngOptionTSResol && optLen == 1:
+			exp := uint64(value[0] & 0x7f)
+			base := 10.0
+			if value[0]&0x80 != 0 {
+				base = 2
+			}
+			units := math.Pow(base, float64(exp))
+			if units < 1 || units > 1e18 {
+				return fmt.Errorf("unsupported pcapng timestamp resolution %d", value[0])
+			}
+			iface.tsUnitsPerSec = uint64(units)
+		case code == pcapngOptionTSOffset && optLen == 8:
+			iface.tsOffset = int64(pr.byteOrder.Uint64(value))
+		}
+		opts = opts[4+padded:]
+	}
+	pr.interfaces = append(pr.interfaces, iface)
+	return nil
+}
+
+// parseFrame walks the link-layer, IP and UDP headers of a frame, returning
+// a Packet if it holds a complete mDNS datagram.
+func (pr *Reader) parseFrame(frame []byte, linkType uint16, ts time.Time) (Packet, bool) {
+	var etherType uint16
+	switch linkType {
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return Packet{}, false
+		}
+		etherType, frame = binary.BigEndian.Uint16(frame[12:14]), frame[14:]
+		// Skip any 802.1Q or 802.1ad VLAN tags
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(frame) >= 4 {
+			etherType, frame = binary.BigEndian.Uint16(frame[2:4]), frame[4:]
+		}
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return Packet{}, false
+		}
+		etherType, frame = binary.BigEndian.Uint16(frame[14:16]), frame[16:]
+	case linkTypeLinuxSLL2:
+		if len(frame) < 20 {
+			return Packet{}, false
+		}
+		etherType, frame = binary.BigEndian.Uint16(frame[0:2]), frame[20:]
+	case linkTypeNull:
+		if len(frame) < 4 {
+			return Packet{}, false
+		}
+		// The address family is in the byte order of the capturing host,
+		// and AF_INET6 varies between operating systems
+		family := binary.LittleEndian.Uint32(frame[0:4])
+		if family > 0xFFFF {
+			family = binary.BigEndian.Uint32(frame[0:4])
+		}
+		etherType, frame = 0x86dd, frame[4:]
+		if family == 2 {
+			etherType = 0x0800
+		}
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		if len(frame) == 0 {
+			return Packet{}, false
+		}
+		etherType = 0x0800
+		if frame[0]>>4 == 6 {
+			etherType = 0x86dd
+		}
+	default:
+		return Packet{}, false
+	}
+
+	var src, dst net.IP
+	var udp []byte
+	var ok bool
+	switch etherType {
+	case 0x0800:
+		src, dst, udp, ok = pr.parseIPv4(frame, ts)
+	case 0x86dd:
+		src, dst, udp, ok = pr.parseIPv6(frame, ts)
+	}
+	if !ok {
+		return Packet{}, false
+	}
+	return parseUDP(src, dst, udp, ts)
+}
+
+func (pr *Reader) parseIPv4(b []byte, ts time.Time) (net.IP, net.IP, []byte, bool) {
+	if len(b) < 20 || b[0]>>4 != 4 {
+		return nil, nil, nil, false
+	}
+	headerLen := int(b[0]&0xf) * 4
+	totalLen := int(binary.BigEndian.Uint16(b[2:4]))
+	if headerLen < 20 || totalLen < headerLen || len(b) < headerLen {
+		return nil, nil, nil, false
+	}
+	if b[9] != 17 {
+		return nil, nil, nil, false
+	}
+	if totalLen < len(b) {
+		// Drop any link-layer padding
+		b = b[:totalLen]
+	}
+	src, dst := net.IP(append([]byte{}, b[12:16]...)), net.IP(append([]byte{}, b[16:20]...))
+	flagsFrag := binary.BigEndian.Uint16(b[6:8])
+	moreFragments := flagsFrag&0x2000 != 0
+	offset := int(flagsFrag&0x1fff) * 8
+	payload := b[headerLen:]
+	if moreFragments || offset != 0 {
+		key := fragmentKey{src: string(src), dst: string(dst), id: uint32(binary.BigEndian.Uint16(b[4:6]))}
+		var complete bool
+		payload, complete = pr.reassembly.add(key, offset, moreFragments, payload, ts)
+		if !complete {
+			return nil, nil, nil, false
+		}
+	}
+	return src, dst, payload, true
+}
+
+func (pr *Reader) parseIPv6(b []byte, ts time.Time) (net.IP, net.IP, []byte, bool) {
+	if len(b) < 40 || b[0]>>4 != 6 {
+		return nil, nil, nil, false
+	}
+	payloadLen := int(binary.BigEndian.Uint16(b[4:6]))
+	nextHeader := b[6]
+	src, dst := net.IP(append([]byte{}, b[8:24]...)), net.IP(append([]byte{}, b[24:40]...))
+	payload := b[40:]
+	if payloadLen < len(payload) {
+		payload = payload[:payloadLen]
+	}
+	for {
+		switch nextHeader {
+		case 17:
+			return src, dst, payload, true
+		case 0, 43, 60: // hop-by-hop, routing, destination options
+			if len(payload) < 8 {
+				return nil, nil, nil, false
+			}
+			extLen := (int(payload[1]) + 1) * 8
+			if len(payload) < extLen {
+				return nil, nil, nil, false
+			}
+			nextHeader, payload = payload[0], payload[extLen:]
+		case 44: // fragment
+			if len(payload) < 8 {
+				return nil, nil, nil, false
+			}
+			fragOff := binary.BigEndian.Uint16(payload[2:4])
+			offset := int(fragOff &^ 0x7)
+			moreFragments := fragOff&0x1 != 0
+			key := fragmentKey{src: string(src), dst: string(dst), id: binary.BigEndian.Uint32(payload[4:8]), v6: true}
+			nextHeader = payload[0]
+			fragment := payload[8:]
+			if !moreFragments && offset == 0 {
+				payload = fragment
+				continue
+			}
+			var complete bool
+			payload, complete = pr.reassembly.add(key, offset, moreFragments, fragment, ts)
+			if !complete {
+				return nil, nil, nil, false
+			}
+			// The headers after a fragment header are only in the first
+			// fragment, and so are now at the start of the payload
+		default:
+			return nil, nil, nil, false
+		}
+	}
+}
+
+func parseUDP(src, dst net.IP, b []byte, ts time.Time) (Packet, bool) {
+	if len(b) < 8 {
+		return Packet{}, false
+	}
+	srcPort := binary.BigEndian.Uint16(b[0:2])
+	dstPort := binary.BigEndian.Uint16(b[2:4])
+	if srcPort != MDNSPort && dstPort != MDNSPort {
+		return Packet{}, false
+	}
+	udpLen := int(binary.BigEndian.Uint16(b[4:6]))
+	payload := b[8:]
+	if udpLen >= 8 && udpLen-8 < len(payload) {
+		payload = payload[:udpLen-8]
+	}
+
+	pkt := Packet{
+		Timestamp: ts,
+		Src:       &net.UDPAddr{IP: src, Port: int(srcPort)},
+		Dst:       &net.UDPAddr{IP: dst, Port: int(dstPort)},
+		Payload:   payload,
+	}
+	d := rawmdns.NewDecoder(bytes.NewReader(payload))
+	pkt.Message, pkt.DecodeErr = d.DecodeDNSMessage()
+	return pkt, true
+}
@@ -0,0 +1,280 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sayotte/rawmdns"
+)
+
+func readTestPayload(t *testing.T) []byte {
+	b, err := os.ReadFile("../testdata/airplay-answer.cap")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %s", err)
+	}
+	return b
+}
+
+func testUDP(srcPort, dstPort uint16, payload []byte) []byte {
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(b[0:2], srcPort)
+	binary.BigEndian.PutUint16(b[2:4], dstPort)
+	binary.BigEndian.PutUint16(b[4:6], uint16(8+len(payload)))
+	return append(b, payload...)
+}
+
+// testIPv4 wraps a piece of a UDP datagram in an IPv4 header, with the given
+// fragment offset in bytes.
+func testIPv4(src, dst string, id uint16, offset int, more bool, data []byte) []byte {
+	b := make([]byte, 20, 20+len(data))
+	b[0] = 0x45
+	binary.BigEndian.PutUint16(b[2:4], uint16(20+len(data)))
+	binary.BigEndian.PutUint16(b[4:6], id)
+	flagsFrag := uint16(offset / 8)
+	if more {
+		flagsFrag |= 0x2000
+	}
+	binary.BigEndian.PutUint16(b[6:8], flagsFrag)
+	b[8], b[9] = 255, 17
+	copy(b[12:16], net.ParseIP(src).To4())
+	copy(b[16:20], net.ParseIP(dst).To4())
+	return append(b, data...)
+}
+
+func testEthernet(etherType uint16, payload []byte) []byte {
+	b := make([]byte, 14, 14+len(payload))
+	copy(b[0:6], []byte{0x01, 0x00, 0x5e, 0x00, 0x00, 0xfb})
+	binary.BigEndian.PutUint16(b[12:14], etherType)
+	return append(b, payload...)
+}
+
+func TestReader_pcap(t *testing.T) {
+	payload := readTestPayload(t)
+	udp := testUDP(5353, 5353, payload)
+
+	var buf bytes.Buffer
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], pcapMagicMicros)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:24], linkTypeEthernet)
+	buf.Write(hdr)
+	record := func(secs, usecs uint32, frame []byte) {
+		rh := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rh[0:4], secs)
+		binary.LittleEndian.PutUint32(rh[4:8], usecs)
+		binary.LittleEndian.PutUint32(rh[8:12], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(rh[12:16], uint32(len(frame)))
+		buf.Write(rh)
+		buf.Write(frame)
+	}
+	record(100, 1, testEthernet(0x0800, testIPv4("10.0.0.5", "224.0.0.251", 1, 0, false, udp)))
+	// Not mDNS, so skipped
+	record(100, 2, testEthernet(0x0800, testIPv4("10.0.0.5", "10.0.0.1", 2, 0, false, testUDP(40000, 53, payload))))
+	// Fragmented, and out of order
+	record(100, 3, testEthernet(0x0800, testIPv4("10.0.0.6", "224.0.0.251", 3, 256, false, udp[256:])))
+	record(100, 4, testEthernet(0x0800, testIPv4("10.0.0.6", "224.0.0.251", 3, 0, true, udp[:256])))
+
+	pr, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader: %s", err)
+	}
+	expected := []struct {
+		ts  time.Time
+		src string
+	}{
+		{time.Unix(100, 1000), "10.0.0.5:5353"},
+		{time.Unix(100, 4000), "10.0.0.6:5353"},
+	}
+	for i, e := range expected {
+		pkt, err := pr.Next()
+		if err != nil {
+			t.Fatalf("Next() %d: %s", i, err)
+		}
+		if !pkt.Timestamp.Equal(e.ts) || pkt.Src.String() != e.src || pkt.Dst.String() != "224.0.0.251:5353" {
+			t.Errorf("Packet %d is %s %s > %s, expected %s %s > 224.0.0.251:5353", i, pkt.Timestamp, pkt.Src, pkt.Dst, e.ts, e.src)
+		}
+		if !bytes.Equal(pkt.Payload, payload) {
+			t.Errorf("Packet %d payload differs", i)
+		}
+		if pkt.DecodeErr != nil || len(pkt.Message.Answers) == 0 {
+			t.Errorf("Packet %d decoded to %d answers, error %v", i, len(pkt.Message.Answers), pkt.DecodeErr)
+		}
+	}
+	if _, err = pr.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestReader_pcapng(t *testing.T) {
+	payload := readTestPayload(t)
+	be := binary.BigEndian
+
+	var buf bytes.Buffer
+	block := func(blockType uint32, body []byte) {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		b := make([]byte, 8, 12+len(body))
+		be.PutUint32(b[0:4], blockType)
+		be.PutUint32(b[4:8], uint32(12+len(body)))
+		b = append(b, body...)
+		b = be.AppendUint32(b, uint32(12+len(body)))
+		buf.Write(b)
+	}
+	// Section header
+	shb := be.AppendUint32(nil, pcapngByteOrderMagic)
+	shb = be.AppendUint16(shb, 1)
+	shb = be.AppendUint16(shb, 0)
+	shb = be.AppendUint64(shb, 0xffffffffffffffff)
+	block(pcapngBlockSHB, shb)
+	// Interface with nanosecond timestamps
+	idb := be.AppendUint16(nil, linkTypeLinuxSLL)
+	idb = be.AppendUint16(idb, 0)
+	idb = be.AppendUint32(idb, 0)
+	idb = be.AppendUint16(idb, pcapngOptionTSResol)
+	idb = be.AppendUint16(idb, 1)
+	idb = append(idb, 9, 0, 0, 0)
+	idb = be.AppendUint32(idb, 0)
+	block(pcapngBlockIDB, idb)
+	// A block type the reader doesn't care about
+	block(0x00000005, []byte{1, 2, 3, 4})
+
+	ip6 := make([]byte, 40)
+	ip6[0] = 0x60
+	udp := testUDP(5353, 5353, payload)
+	be.PutUint16(ip6[4:6], uint16(len(udp)))
+	ip6[6], ip6[7] = 17, 255
+	copy(ip6[8:24], net.ParseIP("fe80::1"))
+	copy(ip6[24:40], net.ParseIP("ff02::fb"))
+	sll := make([]byte, 16)
+	be.PutUint16(sll[14:16], 0x86dd)
+	frame := append(append(sll, ip6...), udp...)
+
+	ts := uint64(1500000000123456789)
+	epb := be.AppendUint32(nil, 0)
+	epb = be.AppendUint32(epb, uint32(ts>>32))
+	epb = be.AppendUint32(epb, uint32(ts))
+	epb = be.AppendUint32(epb, uint32(len(frame)))
+	epb = be.AppendUint32(epb, uint32(len(frame)))
+	epb = append(epb, frame...)
+	block(pcapngBlockEPB, epb)
+
+	pr, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader: %s", err)
+	}
+	pkt, err := pr.Next()
+	if err != nil {
+		t.Fatalf("Next: %s", err)
+	}
+	if !pkt.Timestamp.Equal(time.Unix(1500000000, 123456789)) {
+		t.Errorf("Timestamp is %s", pkt.Timestamp)
+	}
+	if pkt.Src.String() != "[fe80::1]:5353" || pkt.Dst.String() != "[ff02::fb]:5353" {
+		t.Errorf("Addresses are %s > %s", pkt.Src, pkt.Dst)
+	}
+	if pkt.DecodeErr != nil || !bytes.Equal(pkt.Payload, payload) {
+		t.Errorf("Payload differs, or failed to decode: %v", pkt.DecodeErr)
+	}
+	if _, err = pr.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestNewReader_badMagic(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader(make([]byte, 24))); err == nil {
+		t.Errorf("Expected an error for a file of zeroes")
+	}
+}
+
+func TestReader_SetFilter(t *testing.T) {
+	question, err := os.ReadFile("../testdata/airplay-question.cap")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %s", err)
+	}
+	src := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 5353}
+	dst := &net.UDPAddr{IP: net.ParseIP("224.0.0.251"), Port: 5353}
+	var buf bytes.Buffer
+	pw, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter: %s", err)
+	}
+	// a question, a message which doesn't decode, then an answer
+	for i, payload := range [][]byte{question, {0x00}, readTestPayload(t)} {
+		if err = pw.WritePacket(time.Unix(int64(i), 0), src, dst, payload); err != nil {
+			t.Fatalf("WritePacket: %s", err)
+		}
+	}
+
+	f, err := rawmdns.CompileFilter("response")
+	if err != nil {
+		t.Fatalf("CompileFilter: %s", err)
+	}
+	pr, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader: %s", err)
+	}
+	pr.SetFilter(f)
+	pkt, err := pr.Next()
+	if err != nil {
+		t.Fatalf("Next(): %s", err)
+	}
+	if pkt.Timestamp.Unix() != 2 {
+		t.Errorf("Next() returned packet %d, expected the answer", pkt.Timestamp.Unix())
+	}
+	if _, err = pr.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestReader_malformed(t *testing.T) {
+	header := []byte{0x00, 0x00, 0x84, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}
+	malformed := [][]byte{
+		// an A record with an RDLENGTH of 1
+		append(header, 5, 'l', 'o', 'c', 'a', 'l', 0,
+			0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x01, 10),
+		// a name which is a compression pointer to itself
+		append(header, 0xc0, 0x0c,
+			0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x04, 10, 0, 0, 5),
+	}
+	src := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 5353}
+	dst := &net.UDPAddr{IP: net.ParseIP("224.0.0.251"), Port: 5353}
+	var buf bytes.Buffer
+	pw, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter: %s", err)
+	}
+	for _, payload := range append(malformed, readTestPayload(t)) {
+		if err = pw.WritePacket(time.Unix(0, 0), src, dst, payload); err != nil {
+			t.Fatalf("WritePacket: %s", err)
+		}
+	}
+
+	pr, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader: %s", err)
+	}
+	for i := range malformed {
+		pkt, err := pr.Next()
+		if err != nil {
+			t.Fatalf("Next() for malformed packet %d: %s", i, err)
+		}
+		if pkt.DecodeErr == nil {
+			t.Errorf("Malformed packet %d has no DecodeErr", i)
+		}
+	}
+	pkt, err := pr.Next()
+	if err != nil {
+		t.Fatalf("Next() after the malformed packets: %s", err)
+	}
+	if pkt.DecodeErr != nil {
+		t.Errorf("Good packet after the malformed ones failed to decode: %s", pkt.DecodeErr)
+	}
+}
@@ -0,0 +1,102 @@
+package pcap
+
+import (
+	"time"
+)
+
+// fragmentTimeout is how long the fragments of a datagram are kept waiting
+// for the rest, measured in capture time. RFC 791 suggests 15 seconds as a
+// lower bound, RFC 8200 section 4.5 uses 60.
+const fragmentTimeout = 60 * time.Second
+
+// maxReassemblyLen is the largest datagram the reassembler will build; an
+// IP packet's length field cannot describe anything larger.
+const maxReassemblyLen = 65535
+
+type fragmentKey struct {
+	src, dst string
+	id       uint32
+	v6       bool
+}
+
+type fragment struct {
+	offset int
+	data   []byte
+}
+
+type pendingDatagram struct {
+	firstSeen time.Time
+	fragments []fragment
+	// totalLen is known once the last fragment, with More Fragments
+	// unset, has arrived
+	totalLen int
+	haveLast bool
+}
+
+// reassembler rebuilds IP datagrams from their fragments, which may arrive
+// in any order. Overlapping fragments are resolved in favour of whichever
+// arrived first.
+type reassembler struct {
+	pending map[fragmentKey]*pendingDatagram
+}
+
+func newReassembler() *reassembler {
+	return &reassembler{pending: make(map[fragmentKey]*pendingDatagram)}
+}
+
+// add records a fragment, returning the reassembled payload once every
+// fragment of the datagram has been seen.
+func (r *reassembler) add(key fragmentKey, offset int, more bool, data []byte, ts time.Time) ([]byte, bool) {
+	r.expire(ts)
+	if offset+len(data) > maxReassemblyLen {
+		delete(r.pending, key)
+		return nil, false
+	}
+
+	pd, found := r.pending[key]
+	if !found {
+		pd = &pendingDatagram{firstSeen: ts}
+		r.pending[key] = pd
+	}
+	pd.fragments = append(pd.fragments, fragment{offset: offset, data: append([]byte{}, data...)})
+	if !more {
+		pd.totalLen, pd.haveLast = offset+len(data), true
+	}
+	if !pd.haveLast {
+		return nil, false
+	}
+
+	// Fragments are kept in arrival order, so where they overlap the
+	// earliest has already filled the bytes and later ones are ignored
+	assembled := make([]byte, pd.totalLen)
+	filled := make([]bool, pd.totalLen)
+	for _, f := range pd.fragments {
+		for i, b := range f.data {
+			pos := f.offset + i
+			if pos >= pd.totalLen {
+				break
+			}
+			if !filled[pos] {
+				assembled[pos], filled[pos] = b, true
+			}
+		}
+	}
+	for _, ok := range filled {
+		if !ok {
+			// A hole, wait for more fragments
+			return nil, false
+		}
+	}
+	delete(r.pending, key)
+	return assembled, true
+}
+
+// expire drops datagrams which have waited too long for their missing
+// fragments.
+func (r *reassembler) expire(now time.Time) {
+	for key, pd := range r.pending {
+		if now.Sub(pd.firstSeen) > fragmentTimeout {
+			delete(r.pending, key)
+		}
+	}
+}
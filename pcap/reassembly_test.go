@@ -0,0 +1,64 @@
+package pcap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestReassembler_overlap(t *testing.T) {
+	key := fragmentKey{src: "10.0.0.6", dst: "224.0.0.251", id: 3}
+	ts := time.Unix(100, 0)
+	testCases := []struct {
+		name      string
+		fragments []fragment
+		more      []bool
+		expected  string
+	}{
+		{
+			name:      "in order",
+			fragments: []fragment{{0, []byte("aaaaaaaa")}, {8, []byte("bbbbbbbb")}},
+			more:      []bool{true, false},
+			expected:  "aaaaaaaabbbbbbbb",
+		},
+		{
+			// the later fragment starts lower, but the earlier one wins
+			// where they overlap
+			name:      "overlap from below",
+			fragments: []fragment{{8, []byte("bbbbbbbb")}, {0, []byte("aaaaaaaaaaaa")}},
+			more:      []bool{false, true},
+			expected:  "aaaaaaaabbbbbbbb",
+		},
+		{
+			name:      "overlap from above",
+			fragments: []fragment{{0, []byte("aaaaaaaaaaaa")}, {8, []byte("bbbbbbbb")}},
+			more:      []bool{true, false},
+			expected:  "aaaaaaaaaaaabbbb",
+		},
+		{
+			name:      "duplicate",
+			fragments: []fragment{{0, []byte("aaaaaaaa")}, {0, []byte("cccccccc")}, {8, []byte("bbbbbbbb")}},
+			more:      []bool{true, true, false},
+			expected:  "aaaaaaaabbbbbbbb",
+		},
+	}
+	for _, tc := range testCases {
+		r := newReassembler()
+		var got []byte
+		var done bool
+		for i, f := range tc.fragments {
+			if done {
+				t.Errorf("%s: reassembled before fragment %d", tc.name, i)
+				break
+			}
+			got, done = r.add(key, f.offset, tc.more[i], f.data, ts)
+		}
+		if !done {
+			t.Errorf("%s: not reassembled", tc.name)
+			continue
+		}
+		if !bytes.Equal(got, []byte(tc.expected)) {
+			t.Errorf("%s: reassembled %q, expected %q", tc.name, got, tc.expected)
+		}
+	}
+}
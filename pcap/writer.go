@@ -0,0 +1,157 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sayotte/rawmdns"
+)
+
+// mdnsHopLimit is the IP TTL / hop limit written on packets; RFC 6762
+// section 11 says mDNS senders use 255.
+const mdnsHopLimit = 255
+
+// Writer writes UDP datagrams to a classic pcap file with nanosecond
+// timestamps, which Wireshark and tcpdump can open and Reader can read back.
+// Each datagram gets synthetic IPv4 or IPv6 and UDP headers, with valid
+// checksums, and no link-layer header. A Writer is safe for concurrent use.
+type Writer struct {
+	mtx  sync.Mutex
+	w    io.Writer
+	ipID uint16
+}
+
+// NewWriter writes the pcap file header to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], pcapMagicNanos)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:24], linkTypeRaw)
+	if _, err := w.Write(hdr); err != nil {
+		return nil, fmt.Errorf("writing pcap header: %s", err)
+	}
+	return &Writer{w: w}, nil
+}
+
+// WriteMessage encodes dm with DNSMessage.ToBytes and writes it as a UDP
+// datagram sent from src to dst at ts.
+func (pw *Writer) WriteMessage(ts time.Time, src, dst *net.UDPAddr, dm rawmdns.DNSMessage) error {
+	payload, err := dm.ToBytes()
+	if err != nil {
+		return fmt.Errorf("DNSMessage.ToBytes: %s", err)
+	}
+	return pw.WritePacket(ts, src, dst, payload)
+}
+
+// WritePacket writes payload as a UDP datagram sent from src to dst at ts.
+// src and dst must both be IPv4 or both be IPv6 addresses.
+func (pw *Writer) WritePacket(ts time.Time, src, dst *net.UDPAddr, payload []byte) error {
+	if src == nil || dst == nil {
+		return fmt.Errorf("source and destination addresses are required")
+	}
+	if src.Port < 0 || src.Port > 0xFFFF || dst.Port < 0 || dst.Port > 0xFFFF {
+		return fmt.Errorf("port out of range")
+	}
+	if ts.Unix() < 0 || ts.Unix() > 0xFFFFFFFF {
+		return fmt.Errorf("timestamp %s can't be represented in pcap", ts)
+	}
+	if len(payload) > 0xFFFF-8-40 {
+		return fmt.Errorf("payload of %d bytes is too large for a UDP datagram", len(payload))
+	}
+
+	udp := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], uint16(src.Port))
+	binary.BigEndian.PutUint16(udp[2:4], uint16(dst.Port))
+	binary.BigEndian.PutUint16(udp[4:6], uint16(8+len(payload)))
+	udp = append(udp, payload...)
+
+	pw.mtx.Lock()
+	defer pw.mtx.Unlock()
+
+	var ip []byte
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	switch {
+	case src4 != nil && dst4 != nil:
+		if len(payload) > 0xFFFF-8-20 {
+			return fmt.Errorf("payload of %d bytes is too large for an IPv4 datagram", len(payload))
+		}
+		ip = make([]byte, 20, 20+len(udp))
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(udp)))
+		binary.BigEndian.PutUint16(ip[4:6], pw.ipID)
+		pw.ipID++
+		ip[8], ip[9] = mdnsHopLimit, 17
+		copy(ip[12:16], src4)
+		copy(ip[16:20], dst4)
+		binary.BigEndian.PutUint16(ip[10:12], ^onesComplementSum(0, ip))
+	case src4 == nil && dst4 == nil && len(src.IP) == net.IPv6len && len(dst.IP) == net.IPv6len:
+		ip = make([]byte, 40, 40+len(udp))
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:6], uint16(len(udp)))
+		ip[6], ip[7] = 17, mdnsHopLimit
+		copy(ip[8:24], src.IP)
+		copy(ip[24:40], dst.IP)
+	default:
+		return fmt.Errorf("addresses %s and %s are not both IPv4 or both IPv6", src.IP, dst.IP)
+	}
+
+	checksum := ^onesComplementSum(pseudoHeaderSum(ip), udp)
+	if checksum == 0 {
+		// Zero means no checksum was computed, see RFC 768
+		checksum = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(udp[6:8], checksum)
+	frame := append(ip, udp...)
+
+	rec := make([]byte, 16, 16+len(frame))
+	binary.LittleEndian.PutUint32(rec[0:4], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:8], uint32(ts.Nanosecond()))
+	binary.LittleEndian.PutUint32(rec[8:12], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(rec[12:16], uint32(len(frame)))
+	rec = append(rec, frame...)
+	if _, err := pw.w.Write(rec); err != nil {
+		return fmt.Errorf("writing pcap record: %s", err)
+	}
+	return nil
+}
+
+// pseudoHeaderSum sums the pseudo-header which the UDP checksum covers, see
+// RFC 768 for IPv4 and RFC 8200 section 8.1 for IPv6. ip must be a complete
+// IPv4 or IPv6 header with no extension headers.
+func pseudoHeaderSum(ip []byte) uint16 {
+	pseudo := make([]byte, 0, 40)
+	if ip[0]>>4 == 4 {
+		pseudo = append(pseudo, ip[12:20]...)
+		pseudo = append(pseudo, 0, 17)
+		pseudo = binary.BigEndian.AppendUint16(pseudo, binary.BigEndian.Uint16(ip[2:4])-20)
+	} else {
+		pseudo = append(pseudo, ip[8:40]...)
+		pseudo = append(pseudo, 0, 0)
+		pseudo = append(pseudo, ip[4:6]...)
+		pseudo = append(pseudo, 0, 0, 0, 17)
+	}
+	return onesComplementSum(0, pseudo)
+}
+
+// onesComplementSum adds b, as big-endian 16-bit words, to initial with
+// end-around carry, as in RFC 1071.
+func onesComplementSum(initial uint16, b []byte) uint16 {
+	sum := uint32(initial)
+	for len(b) >= 2 {
+		sum += uint32(binary.BigEndian.Uint16(b))
+		b = b[2:]
+	}
+	if len(b) == 1 {
+		sum += uint32(b[0]) << 8
+	}
+	for sum > 0xFFFF {
+		sum = sum>>16 + sum&0xFFFF
+	}
+	return uint16(sum)
+}
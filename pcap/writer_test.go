@@ -0,0 +1,115 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sayotte/rawmdns"
+)
+
+func TestWriter_roundtrip(t *testing.T) {
+	dm := rawmdns.DNSMessage{
+		Hdr: rawmdns.DNSHeader{IsResponse: true, Authoritative: true, NumAnswers: 1},
+		Answers: []rawmdns.DNSResourceRecord{
+			rawmdns.ARecord{
+				Common: rawmdns.ResourceRecordCommon{Domain: "printer.local", Type: rawmdns.TypeA, Class: rawmdns.ClassINET, TTL: 120, CacheFlush: true},
+				Addr:   net.ParseIP("10.0.0.5").To4(),
+			},
+		},
+	}
+	packets := []struct {
+		ts       time.Time
+		src, dst *net.UDPAddr
+	}{
+		{
+			time.Unix(1500000000, 123456789),
+			&net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 5353},
+			&net.UDPAddr{IP: net.ParseIP("224.0.0.251"), Port: 5353},
+		},
+		{
+			time.Unix(1500000001, 1),
+			&net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 5353},
+			&net.UDPAddr{IP: net.ParseIP("fe80::2"), Port: 49152},
+		},
+	}
+
+	var buf bytes.Buffer
+	pw, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter: %s", err)
+	}
+	for _, p := range packets {
+		if err = pw.WriteMessage(p.ts, p.src, p.dst, dm); err != nil {
+			t.Fatalf("WriteMessage: %s", err)
+		}
+	}
+	checkTestCaptureChecksums(t, buf.Bytes())
+
+	pr, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader: %s", err)
+	}
+	for i, p := range packets {
+		pkt, err := pr.Next()
+		if err != nil {
+			t.Fatalf("Next() %d: %s", i, err)
+		}
+		if !pkt.Timestamp.Equal(p.ts) {
+			t.Errorf("Packet %d timestamp is %s, expected %s", i, pkt.Timestamp, p.ts)
+		}
+		if pkt.Src.String() != p.src.String() || pkt.Dst.String() != p.dst.String() {
+			t.Errorf("Packet %d is %s > %s, expected %s > %s", i, pkt.Src, pkt.Dst, p.src, p.dst)
+		}
+		if pkt.DecodeErr != nil {
+			t.Fatalf("Packet %d failed to decode: %s", i, pkt.DecodeErr)
+		}
+		if diffs := rawmdns.DiffMessages(dm, pkt.Message); diffs != nil {
+			t.Errorf("Packet %d message differs: %v", i, diffs)
+		}
+	}
+	if _, err = pr.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+// checkTestCaptureChecksums verifies the IPv4 header and UDP checksums of
+// every frame in a capture made by Writer; a correct checksum sums to 0xFFFF
+// when included in the data it covers.
+func checkTestCaptureChecksums(t *testing.T, capture []byte) {
+	capture = capture[24:]
+	for len(capture) > 0 {
+		frameLen := binary.LittleEndian.Uint32(capture[8:12])
+		frame := capture[16 : 16+frameLen]
+		capture = capture[16+frameLen:]
+
+		headerLen := 40
+		if frame[0]>>4 == 4 {
+			headerLen = 20
+			if sum := onesComplementSum(0, frame[:20]); sum != 0xFFFF {
+				t.Errorf("Bad IPv4 header checksum, sum is %x", sum)
+			}
+		}
+		if sum := onesComplementSum(pseudoHeaderSum(frame[:headerLen]), frame[headerLen:]); sum != 0xFFFF {
+			t.Errorf("Bad UDP checksum, sum is %x", sum)
+		}
+	}
+}
+
+func TestWriter_badAddresses(t *testing.T) {
+	pw, err := NewWriter(io.Discard)
+	if err != nil {
+		t.Fatalf("NewWriter: %s", err)
+	}
+	v4 := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 5353}
+	v6 := &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 5353}
+	if err = pw.WritePacket(time.Now(), v4, v6, nil); err == nil {
+		t.Errorf("Expected an error for mixed address families")
+	}
+	if err = pw.WritePacket(time.Now(), nil, v4, nil); err == nil {
+		t.Errorf("Expected an error for a nil address")
+	}
+}
@@ -0,0 +1,683 @@
+package rawmdns
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var recordTypeNames = map[RecordType]string{
+	TypeA:          "A",
+	TypeNS:         "NS",
+	TypeMD:         "MD",
+	TypeMF:         "MF",
+	TypeCNAME:      "CNAME",
+	TypeSOA:        "SOA",
+	TypeMB:         "MB",
+	TypeMG:         "MG",
+	TypeMR:         "MR",
+	TypeWKS:        "WKS",
+	TypePTR:        "PTR",
+	TypeHINFO:      "HINFO",
+	TypeMINFO:      "MINFO",
+	TypeMX:         "MX",
+	TypeTXT:        "TXT",
+	TypeRP:         "RP",
+	TypeAFSDB:      "AFSDB",
+	TypeX25:        "X25",
+	TypeNSAPPTR:    "NSAP-PTR",
+	TypeSIG:        "SIG",
+	TypeKEY:        "KEY",
+	TypeAAAA:       "AAAA",
+	TypeLOC:        "LOC",
+	TypeNXT:        "NXT",
+	TypeNIMLOC:     "NIMLOC",
+	TypeSRV:        "SRV",
+	TypeNAPTR:      "NAPTR",
+	TypeKX:         "KX",
+	TypeCERT:       "CERT",
+	TypeDNAME:      "DNAME",
+	TypeOPT:        "OPT",
+	TypeDS:         "DS",
+	TypeSSHFP:      "SSHFP",
+	TypeIPSECKEY:   "IPSECKEY",
+	TypeRRSIG:      "RRSIG",
+	TypeNSEC:       "NSEC",
+	TypeDNSKEY:     "DNSKEY",
+	TypeNSEC3:      "NSEC3",
+	TypeNSEC3PARAM: "NSEC3PARAM",
+	TypeTLSA:       "TLSA",
+	TypeSMIMEA:     "SMIMEA",
+	TypeOPENPGPKEY: "OPENPGPKEY",
+	TypeSVCB:       "SVCB",
+	TypeHTTPS:      "HTTPS",
+	TypeNID:        "NID",
+	TypeL32:        "L32",
+	TypeL64:        "L64",
+	TypeLP:         "LP",
+	TypeEUI48:      "EUI48",
+	TypeEUI64:      "EUI64",
+	TypeTKEY:       "TKEY",
+	TypeTSIG:       "TSIG",
+	TypeIXFR:       "IXFR",
+	TypeAXFR:       "AXFR",
+	TypeANY:        "ANY",
+	TypeURI:        "URI",
+	TypeCAA:        "CAA",
+}
+
+// String returns the mnemonic for the type, e.g. "SRV", or the RFC 3597
+// generic form "TYPEnnn" if it has none.
+func (rt RecordType) String() string {
+	if name, found := recordTypeNames[rt]; found {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", uint16(rt))
+}
+
+var recordClassNames = map[RecordClass]string{
+	ClassINET: "IN",
+	3:         "CH",
+	4:         "HS",
+	254:       "NONE",
+	ClassANY:  "ANY",
+}
+
+// String returns the mnemonic for the class, e.g. "IN", or the RFC 3597
+// generic form "CLASSnnn" if it has none.
+func (rc RecordClass) String() string {
+	if name, found := recordClassNames[rc]; found {
+		return name
+	}
+	return fmt.Sprintf("CLASS%d", uint16(rc))
+}
+
+var opCodeNames = map[OpCode]string{
+	OpCodeQuery:  "QUERY",
+	OpCodeIQuery: "IQUERY",
+	OpCodeStatus: "STATUS",
+	OpCodeNotify: "NOTIFY",
+	OpCodeUpdate: "UPDATE",
+}
+
+func (oc OpCode) String() string {
+	if name, found := opCodeNames[oc]; found {
+		return name
+	}
+	return fmt.Sprintf("OPCODE%d", uint8(oc))
+}
+
+var responseCodeNames = map[ResponseCode]string{
+	CodeSuccess:        "NOERROR",
+	CodeFormatError:    "FORMERR",
+	CodeServerFailure:  "SERVFAIL",
+	CodeNameError:      "NXDOMAIN",
+	CodeNotImplemented: "NOTIMP",
+	CodeRefused:        "REFUSED",
+	CodeYXDomain:       "YXDOMAIN",
+	CodeYXRrset:        "YXRRSET",
+	CodeNXRrset:        "NXRRSET",
+	CodeNotAuth:        "NOTAUTH",
+	CodeNotZone:        "NOTZONE",
+	// CodeBadVers shares 16 with CodeBadSig; only TSIG can carry a 16 in
+	// practice, as the header has just 4 bits for it
+	CodeBadSig:    "BADSIG",
+	CodeBadKey:    "BADKEY",
+	CodeBadTime:   "BADTIME",
+	CodeBadMode:   "BADMODE",
+	CodeBadName:   "BADNAME",
+	CodeBadAlg:    "BADALG",
+	CodeBadTrunc:  "BADTRUNC",
+	CodeBadCookie: "BADCOOKIE",
+}
+
+func (rc ResponseCode) String() string {
+	if name, found := responseCodeNames[rc]; found {
+		return name
+	}
+	return fmt.Sprintf("RCODE%d", uint8(rc))
+}
+
+// presentName returns name in master-file format: fully qualified with a
+// trailing dot, and with special or non-printable characters in its labels
+// escaped.
+func presentName(name string) string {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return "."
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '.':
+			buf.WriteByte(c)
+		case c == '"' || c == '(' || c == ')' || c == ';' || c == '@' || c == '$' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c <= ' ' || c >= 0x7f:
+			fmt.Fprintf(&buf, "\\%03d", c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('.')
+	return buf.String()
+}
+
+// presentCharacterString returns s as a quoted master-file
+// <character-string>.
+func presentCharacterString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < ' ' || c >= 0x7f:
+			fmt.Fprintf(&buf, "\\%03d", c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func presentHex(b []byte) string {
+	if len(b) == 0 {
+		return "-"
+	}
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+func presentTypes(types []RecordType) string {
+	var names []string
+	for _, t := range types {
+		names = append(names, t.String())
+	}
+	return strings.Join(names, " ")
+}
+
+// presentGenericRData returns RDATA in the RFC 3597 section 5 format used
+// for types without a specific presentation format.
+func presentGenericRData(rdata []byte) string {
+	if len(rdata) == 0 {
+		return `\# 0`
+	}
+	return fmt.Sprintf(`\# %d %s`, len(rdata), strings.ToUpper(hex.EncodeToString(rdata)))
+}
+
+// rrString formats a record as a single master-file line, with the mDNS
+// cache-flush bit noted in a trailing comment.
+func rrString(common ResourceRecordCommon, rdata string) string {
+	s := fmt.Sprintf("%s\t%d\t%s\t%s\t%s", presentName(common.Domain), common.TTL, common.Class, common.Type, rdata)
+	if common.CacheFlush {
+		s += "\t; cache-flush"
+	}
+	return s
+}
+
+// recordString formats any record, using its String method if it has one
+// and the RFC 3597 generic format otherwise.
+func recordString(rr DNSResourceRecord) string {
+	if s, ok := rr.(fmt.Stringer); ok {
+		return s.String()
+	}
+	rdata, err := rr.PackRData()
+	if err != nil {
+		return rrString(rr.GetCommon(), fmt.Sprintf("; PackRData: %s", err))
+	}
+	return rrString(rr.GetCommon(), presentGenericRData(rdata))
+}
+
+func (ar ARecord) String() string {
+	return rrString(ar.Common, ar.Addr.String())
+}
+
+func (aaaar AAAARecord) String() string {
+	return rrString(aaaar.Common, aaaar.Addr.String())
+}
+
+func (sr SRVRecord) String() string {
+	return rrString(sr.Common, fmt.Sprintf("%d %d %d %s", sr.Priority, sr.Weight, sr.Port, presentName(sr.Target)))
+}
+
+func (pr PTRRecord) String() string {
+	return rrString(pr.Common, presentName(pr.PtrDName))
+}
+
+func (tr TXTRecord) String() string {
+	if len(tr.texts) == 0 {
+		return rrString(tr.Common, `""`)
+	}
+	var texts []string
+	for _, t := range tr.texts {
+		texts = append(texts, presentCharacterString(t))
+	}
+	return rrString(tr.Common, strings.Join(texts, " "))
+}
+
+func (nsr NSECRecord) String() string {
+	rdata := presentName(nsr.NextDomainName)
+	if len(nsr.NextDomainTypes) > 0 {
+		rdata += " " + presentTypes(nsr.NextDomainTypes)
+	}
+	return rrString(nsr.Common, rdata)
+}
+
+func (nr NSEC3Record) String() string {
+	rdata := fmt.Sprintf("%d %d %d %s %s", nr.HashAlgorithm, nr.Flags, nr.Iterations, presentHex(nr.Salt), strings.ToUpper(nr.NextHashedOwner))
+	if len(nr.Types) > 0 {
+		rdata += " " + presentTypes(nr.Types)
+	}
+	return rrString(nr.Common, rdata)
+}
+
+func (npr NSEC3PARAMRecord) String() string {
+	return rrString(npr.Common, fmt.Sprintf("%d %d %d %s", npr.HashAlgorithm, npr.Flags, npr.Iterations, presentHex(npr.Salt)))
+}
+
+func presentSVCBRData(priority uint16, target string, params []SvcParam) string {
+	rdata := fmt.Sprintf("%d %s", priority, presentName(target))
+	for _, param := range params {
+		rdata += " " + svcParamString(param)
+	}
+	return rdata
+}
+
+func (sr SVCBRecord) String() string {
+	return rrString(sr.Common, presentSVCBRData(sr.Priority, sr.Target, sr.Params))
+}
+
+func (hr HTTPSRecord) String() string {
+	return rrString(hr.Common, presentSVCBRData(hr.Priority, hr.Target, hr.Params))
+}
+
+func (nr NAPTRRecord) String() string {
+	return rrString(nr.Common, fmt.Sprintf("%d %d %s %s %s %s", nr.Order, nr.Preference,
+		presentCharacterString(nr.Flags), presentCharacterString(nr.Service),
+		presentCharacterString(nr.Regexp), presentName(nr.Replacement)))
+}
+
+func (ur URIRecord) String() string {
+	return rrString(ur.Common, fmt.Sprintf("%d %d %s", ur.Priority, ur.Weight, presentCharacterString(ur.Target)))
+}
+
+func (sr SSHFPRecord) String() string {
+	return rrString(sr.Common, fmt.Sprintf("%d %d %s", sr.Algorithm, sr.FingerprintType, presentHex(sr.Fingerprint)))
+}
+
+func (tr TLSARecord) String() string {
+	return rrString(tr.Common, fmt.Sprintf("%d %d %d %s", tr.Usage, tr.Selector, tr.MatchingType, presentHex(tr.Data)))
+}
+
+func (sr SMIMEARecord) String() string {
+	return rrString(sr.Common, fmt.Sprintf("%d %d %d %s", sr.Usage, sr.Selector, sr.MatchingType, presentHex(sr.Data)))
+}
+
+func (or OPENPGPKEYRecord) String() string {
+	return rrString(or.Common, base64.StdEncoding.EncodeToString(or.PublicKey))
+}
+
+func (cr CAARecord) String() string {
+	return rrString(cr.Common, fmt.Sprintf("%d %s %s", cr.Flags, cr.Tag, presentCharacterString(string(cr.Value))))
+}
+
+func (tr TSIGRecord) String() string {
+	rdata := fmt.Sprintf("%s %d %d %d %s %d %s %d", presentName(tr.Algorithm), tr.TimeSigned, tr.Fudge,
+		len(tr.MAC), base64.StdEncoding.EncodeToString(tr.MAC), tr.OriginalID, ResponseCode(tr.Error), len(tr.OtherData))
+	if len(tr.OtherData) > 0 {
+		rdata += " " + base64.StdEncoding.EncodeToString(tr.OtherData)
+	}
+	return rrString(tr.Common, rdata)
+}
+
+func (lr LOCRecord) String() string {
+	return rrString(lr.Common, lr.RDataString())
+}
+
+func (cr CERTRecord) String() string {
+	return rrString(cr.Common, fmt.Sprintf("%d %d %d %s", cr.CertType, cr.KeyTag, cr.Algorithm, base64.StdEncoding.EncodeToString(cr.Certificate)))
+}
+
+func (ir IPSECKEYRecord) String() string {
+	var gateway string
+	switch ir.GatewayType {
+	case IPSECKEYGatewayIPv4, IPSECKEYGatewayIPv6:
+		gateway = ir.GatewayAddr.String()
+	case IPSECKEYGatewayDomain:
+		gateway = presentName(ir.GatewayDomain)
+	default:
+		gateway = "."
+	}
+	return rrString(ir.Common, fmt.Sprintf("%d %d %d %s %s", ir.Precedence, ir.GatewayType, ir.Algorithm, gateway, base64.StdEncoding.EncodeToString(ir.PublicKey)))
+}
+
+func (kr KXRecord) String() string {
+	return rrString(kr.Common, fmt.Sprintf("%d %s", kr.Preference, presentName(kr.Exchanger)))
+}
+
+func (wr WKSRecord) String() string {
+	rdata := fmt.Sprintf("%s %d", wr.Address, wr.Protocol)
+	for _, port := range wr.Ports {
+		rdata += fmt.Sprintf(" %d", port)
+	}
+	return rrString(wr.Common, rdata)
+}
+
+func (rr RPRecord) String() string {
+	return rrString(rr.Common, fmt.Sprintf("%s %s", presentName(rr.Mailbox), presentName(rr.TXTDomain)))
+}
+
+func (ar AFSDBRecord) String() string {
+	return rrString(ar.Common, fmt.Sprintf("%d %s", ar.Subtype, presentName(ar.Hostname)))
+}
+
+func (xr X25Record) String() string {
+	return rrString(xr.Common, presentCharacterString(xr.PSDNAddress))
+}
+
+func (nr NSAPPTRRecord) String() string {
+	return rrString(nr.Common, presentName(nr.PtrDName))
+}
+
+func (dr DNAMERecord) String() string {
+	return rrString(dr.Common, presentName(dr.Target))
+}
+
+// presentEUI formats an EUI as hyphen-separated hex pairs, per RFC 7043
+// section 3.2.
+func presentEUI(addr []byte) string {
+	var pairs []string
+	for _, b := range addr {
+		pairs = append(pairs, fmt.Sprintf("%02x", b))
+	}
+	return strings.Join(pairs, "-")
+}
+
+func (er EUI48Record) String() string {
+	return rrString(er.Common, presentEUI(er.Address))
+}
+
+func (er EUI64Record) String() string {
+	return rrString(er.Common, presentEUI(er.Address))
+}
+
+// presentILNP64 formats a 64-bit NodeID or Locator64 as four colon-separated
+// groups of 4 hex digits, per RFC 6742 section 2.
+func presentILNP64(v uint64) string {
+	return fmt.Sprintf("%04x:%04x:%04x:%04x", v>>48, (v>>32)&0xffff, (v>>16)&0xffff, v&0xffff)
+}
+
+func (nr NIDRecord) String() string {
+	return rrString(nr.Common, fmt.Sprintf("%d %s", nr.Preference, presentILNP64(nr.NodeID)))
+}
+
+func (lr L32Record) String() string {
+	return rrString(lr.Common, fmt.Sprintf("%d %s", lr.Preference, lr.Locator32))
+}
+
+func (lr L64Record) String() string {
+	return rrString(lr.Common, fmt.Sprintf("%d %s", lr.Preference, presentILNP64(lr.Locator64)))
+}
+
+func (lr LPRecord) String() string {
+	return rrString(lr.Common, fmt.Sprintf("%d %s", lr.Preference, presentName(lr.FQDN)))
+}
+
+// String formats the OPT pseudo-record in the RFC 3597 generic format, since
+// it has no master-file representation; DNSMessage.String shows it as a
+// pseudo-section instead.
+func (or OPTRecord) String() string {
+	rdata, err := or.PackRData()
+	if err != nil {
+		return rrString(or.Common, fmt.Sprintf("; PackRData: %s", err))
+	}
+	return rrString(or.Common, presentGenericRData(rdata))
+}
+
+// String shows the RFC 1035 types which decode as UnknownRecord, like MX and
+// SOA, in their own presentation format, and any other type, or RDATA which
+// doesn't fit its type, in the RFC 3597 generic format.
+func (ur UnknownRecord) String() string {
+	if rdata, err := presentRFC1035RData(ur.Common, ur.RData); err == nil {
+		return rrString(ur.Common, rdata)
+	}
+	return rrString(ur.Common, presentGenericRData(ur.RData))
+}
+
+// presentRFC1035RData returns the presentation format of the RDATA of the
+// RFC 1035 types listed in rfc1035RDataLayouts, or of HINFO.
+func presentRFC1035RData(common ResourceRecordCommon, rdata []byte) (string, error) {
+	if common.Type == TypeHINFO {
+		cpu, rest, err := readCharacterString(rdata)
+		if err != nil {
+			return "", err
+		}
+		os, rest, err := readCharacterString(rest)
+		if err != nil {
+			return "", err
+		}
+		if len(rest) > 0 {
+			return "", fmt.Errorf("%d trailing bytes after HINFO RDATA", len(rest))
+		}
+		return presentCharacterString(string(cpu)) + " " + presentCharacterString(string(os)), nil
+	}
+	layout, found := rfc1035RDataLayouts[common.Type]
+	if !found {
+		return "", fmt.Errorf("no presentation format for type %s", common.Type)
+	}
+	rd := NewRData(common, rdata)
+	var fields []string
+	off := 0
+	for _, size := range layout {
+		if off+size > len(rdata) {
+			return "", fmt.Errorf("%s RDATA too short (%d bytes)", common.Type, len(rdata))
+		}
+		switch {
+		case size == 0:
+			name, n, err := rd.ReadName(off)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, presentName(name))
+			off += n
+		case size == 2:
+			fields = append(fields, strconv.Itoa(int(binary.BigEndian.Uint16(rdata[off:]))))
+			off += 2
+		default:
+			// the SOA timers are the only wider fields, all 32 bits
+			for end := off + size; off < end; off += 4 {
+				fields = append(fields, strconv.FormatUint(uint64(binary.BigEndian.Uint32(rdata[off:])), 10))
+			}
+		}
+	}
+	if off != len(rdata) {
+		return "", fmt.Errorf("%d trailing bytes after %s RDATA", len(rdata)-off, common.Type)
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// String formats the question like the QUESTION SECTION of dig, minus the
+// leading ";", noting the mDNS unicast-response (QU) bit in a trailing
+// comment.
+func (dq DNSQuestion) String() string {
+	s := fmt.Sprintf("%s\t\t%s\t%s", presentName(dq.Domain), dq.Class, dq.Type)
+	if dq.AcceptUnicastResponse {
+		s += "\t; QU"
+	}
+	return s
+}
+
+// flagNames returns the dig-style names of the header flags which are set.
+func (dh DNSHeader) flagNames() []string {
+	var flags []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{dh.IsResponse, "qr"},
+		{dh.Authoritative, "aa"},
+		{dh.Truncated, "tc"},
+		{dh.RecursionDesired, "rd"},
+		{dh.RecursionAvailable, "ra"},
+		{dh.Reserved, "z"},
+		{dh.AuthenticatedData, "ad"},
+		{dh.CheckingDisabled, "cd"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	return flags
+}
+
+// String formats the header like the first two lines printed by dig.
+func (dh DNSHeader) String() string {
+	return fmt.Sprintf(";; ->>HEADER<<- opcode: %s, status: %s, id: %d\n;; flags: %s; QUERY: %d, ANSWER: %d, AUTHORITY: %d, ADDITIONAL: %d",
+		dh.OpCode, dh.ResponseCode, dh.ID, strings.Join(dh.flagNames(), " "),
+		dh.NumQuestions, dh.NumAnswers, dh.NumNameServers, dh.NumAddlRecords)
+}
+
+// String formats the message the way dig prints a response: the header,
+// then an OPT pseudo-section if there is an OPT record, then each non-empty
+// section with one record per line in master-file format.
+func (dm DNSMessage) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(dm.Hdr.String())
+	buf.WriteString("\n")
+
+	var additional []DNSResourceRecord
+	for _, rr := range dm.Additional {
+		if opt, ok := rr.(OPTRecord); ok {
+			buf.WriteString("\n;; OPT PSEUDOSECTION:\n")
+			buf.WriteString(optPseudoSection(opt))
+			continue
+		}
+		additional = append(additional, rr)
+	}
+
+	if len(dm.Questions) > 0 {
+		buf.WriteString("\n;; QUESTION SECTION:\n")
+		for _, dq := range dm.Questions {
+			buf.WriteString(";" + dq.String() + "\n")
+		}
+	}
+	sections := []struct {
+		name string
+		rrs  []DNSResourceRecord
+	}{
+		{"ANSWER", dm.Answers},
+		{"AUTHORITY", dm.Authority},
+		{"ADDITIONAL", additional},
+	}
+	for _, section := range sections {
+		if len(section.rrs) == 0 {
+			continue
+		}
+		buf.WriteString("\n;; " + section.name + " SECTION:\n")
+		for _, rr := range section.rrs {
+			buf.WriteString(recordString(rr) + "\n")
+		}
+	}
+	return buf.String()
+}
+
+// optPseudoSection describes an OPT record the way dig does. Its class holds
+// the UDP payload size, and its TTL the extended RCODE, version and flags
+// (RFC 6891 section 6.1.3).
+func optPseudoSection(opt OPTRecord) string {
+	ttl := opt.Common.TTL
+	flags := ""
+	if ttl&0x8000 != 0 {
+		flags = " do"
+	}
+	s := fmt.Sprintf("; EDNS: version: %d, flags:%s; udp: %d\n", (ttl>>16)&0xff, flags, uint16(opt.Common.Class))
+	var codes []uint16
+	for code := range opt.Options {
+		codes = append(codes, code)
+	}
+	sort.Sort(UInt16Slice(codes))
+	for _, code := range codes {
+		s += fmt.Sprintf("; OPT=%d: %s\n", code, presentHex(opt.Options[code]))
+	}
+	return s
+}
+
+// String returns the presentation name of the key, e.g. "alpn", or the
+// generic form "keyNNNNN" of RFC 9460 section 2.1.
+func (k SvcParamKey) String() string {
+	switch k {
+	case SvcParamKeyMandatory:
+		return "mandatory"
+	case SvcParamKeyALPN:
+		return "alpn"
+	case SvcParamKeyNoDefaultALPN:
+		return "no-default-alpn"
+	case SvcParamKeyPort:
+		return "port"
+	case SvcParamKeyIPv4Hint:
+		return "ipv4hint"
+	case SvcParamKeyECH:
+		return "ech"
+	case SvcParamKeyIPv6Hint:
+		return "ipv6hint"
+	default:
+		return "key" + strconv.Itoa(int(k))
+	}
+}
+
+// svcParamString formats a SvcParam in the key=value presentation format of
+// RFC 9460 section 2.1.
+func svcParamString(param SvcParam) string {
+	switch p := param.(type) {
+	case SvcMandatory:
+		var keys []string
+		for _, k := range p.Keys {
+			keys = append(keys, k.String())
+		}
+		return "mandatory=" + strings.Join(keys, ",")
+	case SvcALPN:
+		// Commas and backslashes inside an ID are escaped twice: once for the
+		// comma-separated list, once for the character-string holding it
+		var ids []string
+		for _, id := range p.IDs {
+			id = strings.Replace(id, `\`, `\\\\`, -1)
+			id = strings.Replace(id, `,`, `\\,`, -1)
+			ids = append(ids, id)
+		}
+		return "alpn=" + strings.Join(ids, ",")
+	case SvcNoDefaultALPN:
+		return "no-default-alpn"
+	case SvcPort:
+		return fmt.Sprintf("port=%d", p.Port)
+	case SvcIPv4Hint:
+		var addrs []string
+		for _, addr := range p.Addrs {
+			addrs = append(addrs, addr.String())
+		}
+		return "ipv4hint=" + strings.Join(addrs, ",")
+	case SvcECH:
+		return "ech=" + base64.StdEncoding.EncodeToString(p.Config)
+	case SvcIPv6Hint:
+		var addrs []string
+		for _, addr := range p.Addrs {
+			addrs = append(addrs, addr.String())
+		}
+		return "ipv6hint=" + strings.Join(addrs, ",")
+	default:
+		val, err := param.value()
+		if err != nil || len(val) == 0 {
+			return param.Key().String()
+		}
+		return param.Key().String() + "=" + presentCharacterString(string(val))
+	}
+}
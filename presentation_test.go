@@ -0,0 +1,117 @@
+package rawmdns
+
+import (
+	"net"
+	"testing"
+)
+
+func TestEnumStrings(t *testing.T) {
+	testCases := []struct {
+		actual   string
+		expected string
+	}{
+		{TypeSRV.String(), "SRV"},
+		{TypeNSAPPTR.String(), "NSAP-PTR"},
+		{RecordType(65280).String(), "TYPE65280"},
+		{ClassINET.String(), "IN"},
+		{RecordClass(1440).String(), "CLASS1440"},
+		{OpCodeUpdate.String(), "UPDATE"},
+		{OpCode(3).String(), "OPCODE3"},
+		{CodeNameError.String(), "NXDOMAIN"},
+		{ResponseCode(12).String(), "RCODE12"},
+		{SvcParamKeyIPv6Hint.String(), "ipv6hint"},
+		{SvcParamKey(667).String(), "key667"},
+	}
+	for _, tc := range testCases {
+		if tc.actual != tc.expected {
+			t.Errorf("%q != %q", tc.actual, tc.expected)
+		}
+	}
+}
+
+func TestRecordStrings(t *testing.T) {
+	common := func(name string, rt RecordType) ResourceRecordCommon {
+		return ResourceRecordCommon{Domain: name, Type: rt, Class: ClassINET, TTL: 120}
+	}
+	flush := common("printer.local", TypeA)
+	flush.CacheFlush = true
+
+	testCases := []struct {
+		rr       DNSResourceRecord
+		expected string
+	}{
+		{
+			ARecord{Common: flush, Addr: net.ParseIP("10.0.0.5")},
+			"printer.local.\t120\tIN\tA\t10.0.0.5\t; cache-flush",
+		},
+		{
+			SRVRecord{Common: common("My Printer._ipp._tcp.local", TypeSRV), Port: 631, Target: "printer.local"},
+			"My\\032Printer._ipp._tcp.local.\t120\tIN\tSRV\t0 0 631 printer.local.",
+		},
+		{
+			TXTRecord{Common: common("My Printer._ipp._tcp.local", TypeTXT), texts: []string{"txtvers=1", `note="lobby"`}},
+			"My\\032Printer._ipp._tcp.local.\t120\tIN\tTXT\t\"txtvers=1\" \"note=\\\"lobby\\\"\"",
+		},
+		{
+			NSECRecord{Common: common("printer.local", TypeNSEC), NextDomainName: "printer.local", NextDomainTypes: []RecordType{TypeA, TypeAAAA}},
+			"printer.local.\t120\tIN\tNSEC\tprinter.local. A AAAA",
+		},
+		{
+			HTTPSRecord{Common: common("lab.example", TypeHTTPS), Priority: 1, Target: ".", Params: []SvcParam{
+				SvcALPN{IDs: []string{"h2", "h3"}},
+				SvcPort{Port: 8443},
+			}},
+			"lab.example.\t120\tIN\tHTTPS\t1 . alpn=h2,h3 port=8443",
+		},
+		{
+			EUI48Record{Common: common("printer.local", TypeEUI48), Address: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}},
+			"printer.local.\t120\tIN\tEUI48\t00-11-22-33-44-55",
+		},
+		{
+			L64Record{Common: common("host.lab.example", TypeL64), Preference: 10, Locator64: 0x20010db811401000},
+			"host.lab.example.\t120\tIN\tL64\t10 2001:0db8:1140:1000",
+		},
+		{
+			UnknownRecord{Common: common("printer.local", 65280), RData: []byte{0xde, 0xad}},
+			"printer.local.\t120\tIN\tTYPE65280\t\\# 2 DEAD",
+		},
+	}
+	for _, tc := range testCases {
+		if s := recordString(tc.rr); s != tc.expected {
+			t.Errorf("%q != %q", s, tc.expected)
+		}
+	}
+}
+
+func TestDNSMessage_String(t *testing.T) {
+	dm := DNSMessage{
+		Hdr: DNSHeader{IsResponse: true, Authoritative: true, NumQuestions: 1, NumAnswers: 1, NumAddlRecords: 1},
+		Questions: []DNSQuestion{
+			{Domain: "printer.local", Type: TypeA, Class: ClassINET, AcceptUnicastResponse: true},
+		},
+		Answers: []DNSResourceRecord{
+			ARecord{
+				Common: ResourceRecordCommon{Domain: "printer.local", Type: TypeA, Class: ClassINET, TTL: 120, CacheFlush: true},
+				Addr:   net.ParseIP("10.0.0.5"),
+			},
+		},
+		Additional: []DNSResourceRecord{
+			OPTRecord{Common: ResourceRecordCommon{Type: TypeOPT, Class: 1440}},
+		},
+	}
+	expected := `;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 0
+;; flags: qr aa; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 1
+
+;; OPT PSEUDOSECTION:
+; EDNS: version: 0, flags:; udp: 1440
+
+;; QUESTION SECTION:
+;printer.local.		IN	A	; QU
+
+;; ANSWER SECTION:
+printer.local.	120	IN	A	10.0.0.5	; cache-flush
+`
+	if dm.String() != expected {
+		t.Errorf("DNSMessage.String() returned:\n%s\nexpected:\n%s", dm.String(), expected)
+	}
+}
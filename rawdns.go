@@ -4,15 +4,14 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"strings"
 )
 
 type DNSMessage struct {
 	Hdr        DNSHeader
 	Questions  []DNSQuestion
 	Answers    []DNSResourceRecord // any XYZRecord from this package
+	Authority  []DNSResourceRecord // any XYZRecord from this package
 	Additional []DNSResourceRecord // any XYZRecord from this package
-	//NameServers []DNSNSRecord
 }
 
 func (dm DNSMessage) ToBytes() ([]byte, error) {
@@ -30,20 +29,38 @@ func (dm DNSMessage) ToBytes() ([]byte, error) {
 	}
 
 	for _, answer := range dm.Answers {
-		rrr, err := answer.toRawDNSResourceRecord()
+		rrr, err := packRecord(answer)
 		if err != nil {
-			return nil, fmt.Errorf("DNSResourceRecord.torawDNSResourceRecord: %s", err)
+			return nil, fmt.Errorf("packRecord: %s", err)
 		}
 		ab, err := rrr.toBytes()
+		if err != nil {
+			return nil, fmt.Errorf("rawResourceRecord.toBytes: %s", err)
+		}
+		ret = append(ret, ab...)
+	}
+
+	for _, auth := range dm.Authority {
+		rrr, err := packRecord(auth)
+		if err != nil {
+			return nil, fmt.Errorf("packRecord: %s", err)
+		}
+		ab, err := rrr.toBytes()
+		if err != nil {
+			return nil, fmt.Errorf("rawResourceRecord.toBytes: %s", err)
+		}
 		ret = append(ret, ab...)
 	}
 
 	for _, addl := range dm.Additional {
-		rrr, err := addl.toRawDNSResourceRecord()
+		rrr, err := packRecord(addl)
 		if err != nil {
-			return nil, fmt.Errorf("DNSResourceRecord.torawDNSResourceRecord: %s", err)
+			return nil, fmt.Errorf("packRecord: %s", err)
 		}
 		ab, err := rrr.toBytes()
+		if err != nil {
+			return nil, fmt.Errorf("rawResourceRecord.toBytes: %s", err)
+		}
 		ret = append(ret, ab...)
 	}
 
@@ -83,6 +100,15 @@ func (rdh rawDNSHeader) toDNSHeader() DNSHeader {
 	if rdh.Flag[1]>>7 == 1 {
 		dh.RecursionAvailable = true
 	}
+	if rdh.Flag[1]&0x40 == 0x40 {
+		dh.Reserved = true
+	}
+	if rdh.Flag[1]&0x20 == 0x20 {
+		dh.AuthenticatedData = true
+	}
+	if rdh.Flag[1]&0x10 == 0x10 {
+		dh.CheckingDisabled = true
+	}
 	dh.ResponseCode = ResponseCode(rdh.Flag[1] & 0xF)
 
 	return dh
@@ -144,6 +170,15 @@ func (dh DNSHeader) toRaw() rawDNSHeader {
 	if dh.RecursionAvailable {
 		rdh.Flag[1] |= 0x80
 	}
+	if dh.Reserved {
+		rdh.Flag[1] |= 0x40
+	}
+	if dh.AuthenticatedData {
+		rdh.Flag[1] |= 0x20
+	}
+	if dh.CheckingDisabled {
+		rdh.Flag[1] |= 0x10
+	}
 	rdh.Flag[1] |= byte(dh.ResponseCode & 0xF)
 
 	return rdh
@@ -207,13 +242,7 @@ type DNSQuestion struct {
 
 func (q DNSQuestion) toRaw() rawDNSQuestion {
 	var rq rawDNSQuestion
-	for _, s := range strings.Split(q.Domain, ".") {
-		l := rawLabel{
-			length:  uint8(len(s)),
-			content: s,
-		}
-		rq.domainLabels = append(rq.domainLabels, l)
-	}
+	rq.domainLabels = domain(q.Domain).toRawLabels()
 	rq.static.Type = q.Type
 	rq.static.Class = q.Class
 	if q.AcceptUnicastResponse {
@@ -518,6 +518,68 @@ func (typ DNSQuestion) Generate(rand *rand.Rand, size int) reflect.Value {
 	return reflect.ValueOf(dq)
 }
 
+func TestDNSMessage_ToBytes_names(t *testing.T) {
+	// A trailing dot must not add an empty label, and the root is just the
+	// terminating 0-length label
+	dm := DNSMessage{
+		Hdr: DNSHeader{NumQuestions: 2, NumAnswers: 1},
+		Questions: []DNSQuestion{
+			{Domain: "foo.local.", Type: TypeA, Class: ClassINET},
+			{Domain: ".", Type: TypeNS, Class: ClassINET},
+		},
+		Answers: []DNSResourceRecord{
+			PTRRecord{
+				Common:   ResourceRecordCommon{Domain: "", Type: TypePTR, Class: ClassINET, TTL: 1},
+				PtrDName: "foo.local.",
+			},
+		},
+	}
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from ToBytes: %s", err)
+	}
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		3, 'f', 'o', 'o', 5, 'l', 'o', 'c', 'a', 'l', 0, 0x00, 0x01, 0x00, 0x01,
+		0, 0x00, 0x02, 0x00, 0x01,
+		0, 0x00, 0x0c, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x0b,
+		3, 'f', 'o', 'o', 5, 'l', 'o', 'c', 'a', 'l', 0,
+	}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("ToBytes returned\n%x, expected\n%x", b, expected)
+	}
+}
+
+func TestDNSMessage_authorityRoundtrip(t *testing.T) {
+	// A probe puts the records it intends to use in the Authority section
+	// (RFC 6762 section 8.2)
+	auth := ARecord{
+		Common: ResourceRecordCommon{Domain: "printer.local", Type: TypeA, Class: ClassINET, TTL: 120},
+		Addr:   net.ParseIP("169.254.1.1").To4(),
+	}
+	dm := DNSMessage{
+		Hdr:       DNSHeader{NumQuestions: 1, NumNameServers: 1},
+		Questions: []DNSQuestion{{Domain: "printer.local", Type: TypeANY, Class: ClassINET, AcceptUnicastResponse: true}},
+		Authority: []DNSResourceRecord{auth},
+	}
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from ToBytes: %s", err)
+	}
+	d := NewDecoder(bytes.NewReader(b))
+	decoded, err := d.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	if len(decoded.Answers) != 0 || len(decoded.Additional) != 0 || len(decoded.Authority) != 1 {
+		t.Fatalf("Decoded %d answers, %d authority and %d additional records, expected only 1 authority record",
+			len(decoded.Answers), len(decoded.Authority), len(decoded.Additional))
+	}
+	if same, reasons := auth.Equal(decoded.Authority[0]); !same {
+		t.Errorf("Authority record changed through the wire format: %v", reasons)
+	}
+}
+
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 func randString(n int) string {
@@ -546,3 +608,54 @@ func randString(n int) string {
 //	spew.Dump(dm)
 //	t.Fail()
 //}
+
+func TestDecoder_DecodeDNSMessage_authority(t *testing.T) {
+	// A probe: the question, and the proposed record in the Authority
+	// section, followed by an Additional record (RFC 6762 section 8.2)
+	b := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01,
+		// 0x0c: question printer.local ANY QU
+		7, 'p', 'r', 'i', 'n', 't', 'e', 'r', 5, 'l', 'o', 'c', 'a', 'l', 0,
+		0x00, 0xff, 0x80, 0x01,
+		// authority: printer.local A 10.0.0.5
+		0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x04,
+		10, 0, 0, 5,
+		// additional: printer.local AAAA fe80::5
+		0xc0, 0x0c, 0x00, 0x1c, 0x00, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x10,
+		0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5,
+	}
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	if len(dm.Answers) != 0 || len(dm.Authority) != 1 || len(dm.Additional) != 1 {
+		t.Fatalf("Decoded %d answers, %d authority and %d additional records, expected 0, 1 and 1",
+			len(dm.Answers), len(dm.Authority), len(dm.Additional))
+	}
+	expected := ARecord{
+		Common: ResourceRecordCommon{Domain: "printer.local", Type: TypeA, Class: ClassINET, TTL: 120},
+		Addr:   net.IP{10, 0, 0, 5},
+	}
+	if same, reasons := expected.Equal(dm.Authority[0]); !same {
+		t.Errorf("Authority record differs: %v", reasons)
+	}
+	if dm.Additional[0].GetCommon().Type != TypeAAAA {
+		t.Errorf("Additional record is %v, expected the AAAA record", dm.Additional[0])
+	}
+
+	// Authority records must be written back between the answers and the
+	// additional records
+	encoded, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from ToBytes: %s", err)
+	}
+	decoder = NewDecoder(bytes.NewReader(encoded))
+	again, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error decoding the re-encoded message: %s", err)
+	}
+	if diffs := DiffMessages(dm, again); diffs != nil {
+		t.Errorf("Message changed when re-encoded: %v", diffs)
+	}
+}
@@ -0,0 +1,136 @@
+package rawmdns
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+var (
+	recordFactoriesMtx sync.RWMutex
+	recordFactories    = map[RecordType]func() DNSResourceRecord{
+		TypeA:          func() DNSResourceRecord { return ARecord{} },
+		TypeAAAA:       func() DNSResourceRecord { return AAAARecord{} },
+		TypeSRV:        func() DNSResourceRecord { return SRVRecord{} },
+		TypePTR:        func() DNSResourceRecord { return PTRRecord{} },
+		TypeTXT:        func() DNSResourceRecord { return TXTRecord{} },
+		TypeNSEC:       func() DNSResourceRecord { return NSECRecord{} },
+		TypeNSEC3:      func() DNSResourceRecord { return NSEC3Record{} },
+		TypeNSEC3PARAM: func() DNSResourceRecord { return NSEC3PARAMRecord{} },
+		TypeSVCB:       func() DNSResourceRecord { return SVCBRecord{} },
+		TypeHTTPS:      func() DNSResourceRecord { return HTTPSRecord{} },
+		TypeNAPTR:      func() DNSResourceRecord { return NAPTRRecord{} },
+		TypeURI:        func() DNSResourceRecord { return URIRecord{} },
+		TypeSSHFP:      func() DNSResourceRecord { return SSHFPRecord{} },
+		TypeTLSA:       func() DNSResourceRecord { return TLSARecord{} },
+		TypeSMIMEA:     func() DNSResourceRecord { return SMIMEARecord{} },
+		TypeOPENPGPKEY: func() DNSResourceRecord { return OPENPGPKEYRecord{} },
+		TypeCAA:        func() DNSResourceRecord { return CAARecord{} },
+		TypeTSIG:       func() DNSResourceRecord { return TSIGRecord{} },
+		TypeLOC:        func() DNSResourceRecord { return LOCRecord{} },
+		TypeCERT:       func() DNSResourceRecord { return CERTRecord{} },
+		TypeIPSECKEY:   func() DNSResourceRecord { return IPSECKEYRecord{} },
+		TypeKX:         func() DNSResourceRecord { return KXRecord{} },
+		TypeWKS:        func() DNSResourceRecord { return WKSRecord{} },
+		TypeRP:         func() DNSResourceRecord { return RPRecord{} },
+		TypeAFSDB:      func() DNSResourceRecord { return AFSDBRecord{} },
+		TypeX25:        func() DNSResourceRecord { return X25Record{} },
+		TypeNSAPPTR:    func() DNSResourceRecord { return NSAPPTRRecord{} },
+		TypeDNAME:      func() DNSResourceRecord { return DNAMERecord{} },
+		TypeEUI48:      func() DNSResourceRecord { return EUI48Record{} },
+		TypeEUI64:      func() DNSResourceRecord { return EUI64Record{} },
+		TypeNID:        func() DNSResourceRecord { return NIDRecord{} },
+		TypeL32:        func() DNSResourceRecord { return L32Record{} },
+		TypeL64:        func() DNSResourceRecord { return L64Record{} },
+		TypeLP:         func() DNSResourceRecord { return LPRecord{} },
+		TypeOPT:        func() DNSResourceRecord { return OPTRecord{} },
+	}
+)
+
+// RegisterRecordType makes the Decoder decode records of type rt by calling
+// UnpackRData on the value returned by factory. Registering a type which is
+// already registered, including one built in to this package, replaces it.
+// Records of types which are not registered decode as an UnknownRecord.
+func RegisterRecordType(rt RecordType, factory func() DNSResourceRecord) {
+	if factory == nil {
+		panic("rawmdns: RegisterRecordType called with nil factory")
+	}
+	recordFactoriesMtx.Lock()
+	defer recordFactoriesMtx.Unlock()
+	recordFactories[rt] = factory
+}
+
+func recordForType(rt RecordType) DNSResourceRecord {
+	recordFactoriesMtx.RLock()
+	factory, found := recordFactories[rt]
+	recordFactoriesMtx.RUnlock()
+	if !found {
+		return UnknownRecord{}
+	}
+	return factory()
+}
+
+// RData is the RDATA of a record being decoded, passed to UnpackRData. Domain
+// names inside it may be compressed, so they must be read with ReadName
+// rather than directly from Bytes.
+type RData struct {
+	rrr rawResourceRecord
+	d   *Decoder
+}
+
+// NewRData builds an RData for a record which is not part of a DNS message,
+// e.g. one read from a zone file. Its domain names must not be compressed.
+func NewRData(common ResourceRecordCommon, rdata []byte) RData {
+	rrr := newRawResourceRecordFromCommon(common)
+	rrr.rData = rdata
+	rrr.static.RDataLength = uint16(len(rdata))
+	return RData{rrr: rrr, d: &Decoder{}}
+}
+
+// Common returns the owner name, type, class, cache-flush bit and TTL of the
+// record being decoded.
+func (rd RData) Common() ResourceRecordCommon {
+	return commonFromRawRR(rd.rrr)
+}
+
+// Bytes returns the raw RDATA.
+func (rd RData) Bytes() []byte {
+	return rd.rrr.rData
+}
+
+// ReadName decodes the domain name starting at offset off within the RDATA,
+// following any compression pointers into the rest of the message. It also
+// returns the number of RDATA bytes the name occupied.
+func (rd RData) ReadName(off int) (string, int, error) {
+	if off < 0 || off >= len(rd.rrr.rData) {
+		return "", 0, fmt.Errorf("offset %d out of range for %d bytes of RDATA", off, len(rd.rrr.rData))
+	}
+	rdr := bytes.NewReader(rd.rrr.rData[off:])
+	rlList, err := rd.d._nextRawLabelsFromReaderWithBaseOffset(rdr, rd.rrr.rDataOffsetInMsg+off)
+	if err != nil {
+		return "", 0, err
+	}
+	return rlList.toDomain(), len(rd.rrr.rData) - off - rdr.Len(), nil
+}
+
+// PackName returns the uncompressed wire format of a domain name, for use in
+// PackRData.
+func PackName(name string) []byte {
+	return domain(name).toRawLabels().toBytes()
+}
+
+// packRecord builds the wire format of any DNSResourceRecord, using its
+// GetCommon and PackRData methods.
+func packRecord(rr DNSResourceRecord) (rawResourceRecord, error) {
+	rrr := newRawResourceRecordFromCommon(rr.GetCommon())
+	rData, err := rr.PackRData()
+	if err != nil {
+		return rrr, err
+	}
+	if len(rData) > 65535 {
+		return rrr, fmt.Errorf("RDATA is %d bytes, may not exceed 65535", len(rData))
+	}
+	rrr.rData = rData
+	rrr.static.RDataLength = uint16(len(rData))
+	return rrr, nil
+}
@@ -0,0 +1,185 @@
+package rawmdns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+const typeVendorTest RecordType = 65280
+
+// vendorTestRecord stands in for a record type defined outside this package,
+// so it uses only the exported API.
+type vendorTestRecord struct {
+	Common ResourceRecordCommon
+	Weight uint16
+	Host   string
+}
+
+func (vr vendorTestRecord) GetCommon() ResourceRecordCommon {
+	return vr.Common
+}
+
+func (vr vendorTestRecord) PackRData() ([]byte, error) {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, vr.Weight)
+	return append(b, PackName(vr.Host)...), nil
+}
+
+func (vr vendorTestRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	v := vendorTestRecord{Common: rd.Common()}
+	if len(rd.Bytes()) < 3 {
+		return v, fmt.Errorf("RDATA too short (%d bytes)", len(rd.Bytes()))
+	}
+	v.Weight = binary.BigEndian.Uint16(rd.Bytes()[0:2])
+	host, n, err := rd.ReadName(2)
+	if err != nil {
+		return v, err
+	}
+	if 2+n != len(rd.Bytes()) {
+		return v, fmt.Errorf("%d trailing bytes after Host", len(rd.Bytes())-2-n)
+	}
+	v.Host = host
+	return v, nil
+}
+
+func (vr vendorTestRecord) Equal(ovr DNSResourceRecord) (bool, []string) {
+	other := ovr.(vendorTestRecord)
+	same, reasons := vr.Common.equal(other.Common)
+	if vr.Weight != other.Weight {
+		same = false
+		reasons = append(reasons, fmt.Sprintf("Weight: %d != %d", vr.Weight, other.Weight))
+	}
+	if vr.Host != other.Host {
+		same = false
+		reasons = append(reasons, fmt.Sprintf("Host: %q != %q", vr.Host, other.Host))
+	}
+	return same, reasons
+}
+
+func TestRegisterRecordType(t *testing.T) {
+	// The vendor record's Host is a compression pointer to the owner name of
+	// the A record, at offset 12 in the message
+	a := ARecord{
+		Common: ResourceRecordCommon{Domain: "host.lab.example", Type: TypeA, Class: ClassINET, TTL: 120},
+		Addr:   []byte{10, 0, 0, 1},
+	}
+	unknown := UnknownRecord{
+		Common: ResourceRecordCommon{Domain: "lab.example", Type: typeVendorTest, Class: ClassINET, TTL: 120},
+		RData:  []byte{0x00, 0x07, 0xC0, 0x0C},
+	}
+	dm := DNSMessage{
+		Hdr:     DNSHeader{IsResponse: true, NumAnswers: 2},
+		Answers: []DNSResourceRecord{a, unknown},
+	}
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	decoded, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	same, reasons := unknown.Equal(decoded.Answers[1])
+	if !same {
+		t.Error("Unregistered type did not decode as the original UnknownRecord:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+
+	RegisterRecordType(typeVendorTest, func() DNSResourceRecord { return vendorTestRecord{} })
+	decoder = NewDecoder(bytes.NewReader(b))
+	decoded, err = decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	expected := vendorTestRecord{Common: unknown.Common, Weight: 7, Host: "host.lab.example"}
+	same, reasons = expected.Equal(decoded.Answers[1])
+	if !same {
+		t.Error("Registered type decoded differently than expected:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestNewRData(t *testing.T) {
+	common := ResourceRecordCommon{Domain: "_http._tcp.lab.example", Type: TypeSRV, Class: ClassINET, TTL: 120}
+	rdata := append([]byte{0, 1, 0, 2, 0x1f, 0x90}, PackName("web.lab.example")...)
+	rr, err := SRVRecord{}.UnpackRData(NewRData(common, rdata))
+	if err != nil {
+		t.Fatalf("Unexpected error from UnpackRData: %s", err)
+	}
+	expected := SRVRecord{Common: common, Priority: 1, Weight: 2, Port: 8080, Target: "web.lab.example"}
+	same, reasons := expected.Equal(rr)
+	if !same {
+		t.Error("Expected and actual SRVRecords differ:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+
+	packed, err := rr.PackRData()
+	if err != nil {
+		t.Fatalf("Unexpected error from PackRData: %s", err)
+	}
+	if !bytes.Equal(packed, rdata) {
+		t.Errorf("PackRData: %x != %x", packed, rdata)
+	}
+}
+
+func TestUnknownRecord_decompressesRFC1035Types(t *testing.T) {
+	b := []byte{
+		0x00, 0x00, 0x84, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00,
+		// 0x0c: foo.local CNAME bar.local, with "local" at 0x10
+		3, 'f', 'o', 'o', 5, 'l', 'o', 'c', 'a', 'l', 0,
+		0x00, 0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x06,
+		3, 'b', 'a', 'r', 0xc0, 0x10,
+		// foo.local MX 10 mail.local
+		0xc0, 0x0c, 0x00, 0x0f, 0x00, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x09,
+		0x00, 0x0a, 4, 'm', 'a', 'i', 'l', 0xc0, 0x10,
+	}
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	expected := []UnknownRecord{
+		{
+			Common: ResourceRecordCommon{Domain: "foo.local", Type: TypeCNAME, Class: ClassINET, TTL: 120},
+			RData:  PackName("bar.local"),
+		},
+		{
+			Common: ResourceRecordCommon{Domain: "foo.local", Type: TypeMX, Class: ClassINET, TTL: 120},
+			RData:  append([]byte{0x00, 0x0a}, PackName("mail.local")...),
+		},
+	}
+	for i := range expected {
+		same, reasons := expected[i].Equal(dm.Answers[i])
+		if !same {
+			t.Errorf("Answer %d was not decompressed: %v", i, reasons)
+		}
+	}
+
+	// Encoding and decoding again must give the same records, as the RDATA
+	// no longer refers to the original message
+	reencoded, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from ToBytes: %s", err)
+	}
+	decoder = NewDecoder(bytes.NewReader(reencoded))
+	again, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error decoding the re-encoded message: %s", err)
+	}
+	for i := range expected {
+		same, reasons := expected[i].Equal(again.Answers[i])
+		if !same {
+			t.Errorf("Answer %d changed when re-encoded: %v", i, reasons)
+		}
+	}
+}
@@ -2,11 +2,17 @@ package rawmdns
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base32"
 	"encoding/binary"
 	"fmt"
 	"net"
 	"sort"
 	"reflect"
+	"strings"
 )
 
 // bufWriteAttempter is used to cut down on boilerplate error-handling in code
@@ -29,6 +35,20 @@ func (bwa *bufWriteAttempter) attemptBinaryWrite(order binary.ByteOrder, i inter
 	}
 	bwa.err = binary.Write(bwa.buf, order, i)
 }
+
+// attemptWriteCharacterString writes s as a length-prefixed
+// <character-string> (RFC 1035 section 3.3), which may not exceed 255 bytes.
+func (bwa *bufWriteAttempter) attemptWriteCharacterString(s string) {
+	if bwa.err != nil {
+		return
+	}
+	if len(s) > 255 {
+		bwa.err = fmt.Errorf("character-string is %d bytes, may not exceed 255", len(s))
+		return
+	}
+	bwa.attemptWrite([]byte{uint8(len(s))})
+	bwa.attemptWrite([]byte(s))
+}
 func newBufWriteAttempter() bufWriteAttempter {
 	return bufWriteAttempter{
 		buf: &bytes.Buffer{},
@@ -133,17 +153,18 @@ type ARecord struct {
 	Addr   net.IP
 }
 
-func (ar ARecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
-	rrr := newRawResourceRecordFromCommon(ar.Common)
-	rrr.static.RDataLength = 4
-	rrr.rData = []byte(ar.Addr.To4())
-	return rrr, nil
-}
-
 func (ar ARecord) GetCommon() ResourceRecordCommon {
 	return ar.Common
 }
 
+func (ar ARecord) PackRData() ([]byte, error) {
+	return []byte(ar.Addr.To4()), nil
+}
+
+func (ar ARecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newARecordFromRawRR(rd.rrr)
+}
+
 func (ar ARecord) Equal(oar DNSResourceRecord) (bool, []string) {
 	other := oar.(ARecord)
 	same, reasons := ar.Common.equal(other.Common)
@@ -160,17 +181,18 @@ type AAAARecord struct {
 	Addr   net.IP
 }
 
-func (aaaar AAAARecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
-	rrr := newRawResourceRecordFromCommon(aaaar.Common)
-	rrr.static.RDataLength = 16
-	rrr.rData = []byte(aaaar.Addr.To16())
-	return rrr, nil
-}
-
 func (aaaar AAAARecord) GetCommon() ResourceRecordCommon {
 	return aaaar.Common
 }
 
+func (aaaar AAAARecord) PackRData() ([]byte, error) {
+	return []byte(aaaar.Addr.To16()), nil
+}
+
+func (aaaar AAAARecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newAAAARecordFromRawRR(rd.rrr)
+}
+
 func (aaaar AAAARecord) Equal(oaaaar DNSResourceRecord) (bool, []string) {
 	other := oaaaar.(AAAARecord)
 	same, reasons := aaaar.Common.equal(other.Common)
@@ -190,8 +212,11 @@ type SRVRecord struct {
 	Target   string
 }
 
-func (sr SRVRecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
-	rrr := newRawResourceRecordFromCommon(sr.Common)
+func (sr SRVRecord) GetCommon() ResourceRecordCommon {
+	return sr.Common
+}
+
+func (sr SRVRecord) PackRData() ([]byte, error) {
 	bwa := newBufWriteAttempter()
 	bwa.attemptBinaryWrite(binary.BigEndian, sr.Priority)
 	bwa.attemptBinaryWrite(binary.BigEndian, sr.Weight)
@@ -199,18 +224,17 @@ func (sr SRVRecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
 	targetBytes := domain(sr.Target).toRawLabels().toBytes()
 	bwa.attemptWrite(targetBytes)
 	if bwa.err != nil {
-		return rrr, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
 	}
 
-	rrr.static.RDataLength = uint16(bwa.buf.Len())
-	rrr.rData = make([]byte, bwa.buf.Len())
-	copy(rrr.rData, bwa.buf.Bytes())
+	rData := make([]byte, bwa.buf.Len())
+	copy(rData, bwa.buf.Bytes())
 
-	return rrr, nil
+	return rData, nil
 }
 
-func (sr SRVRecord) GetCommon() ResourceRecordCommon {
-	return sr.Common
+func (sr SRVRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newSRVRecordFromRawRR(rd.rrr)
 }
 
 func (sr SRVRecord) Equal(osr DNSResourceRecord) (bool, []string) {
@@ -244,18 +268,16 @@ type PTRRecord struct {
 	PtrDName string
 }
 
-func (pr PTRRecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
-	rrr := newRawResourceRecordFromCommon(pr.Common)
-	ptrDNameBytes := domain(pr.PtrDName).toRawLabels().toBytes()
-	rrr.static.RDataLength = uint16(len(ptrDNameBytes))
-	rrr.rData = make([]byte, rrr.static.RDataLength)
-	copy(rrr.rData, ptrDNameBytes)
+func (pr PTRRecord) GetCommon() ResourceRecordCommon {
+	return pr.Common
+}
 
-	return rrr, nil
+func (pr PTRRecord) PackRData() ([]byte, error) {
+	return domain(pr.PtrDName).toRawLabels().toBytes(), nil
 }
 
-func (pr PTRRecord) GetCommon() ResourceRecordCommon {
-	return pr.Common
+func (pr PTRRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newPTRRecordFromRawRR(rd.rrr)
 }
 
 func (pr PTRRecord) Equal(opr DNSResourceRecord) (bool, []string) {
@@ -274,23 +296,22 @@ type TXTRecord struct {
 	texts  []string
 }
 
-func (tr TXTRecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
-	rrr := newRawResourceRecordFromCommon(tr.Common)
+func (tr TXTRecord) GetCommon() ResourceRecordCommon {
+	return tr.Common
+}
 
+func (tr TXTRecord) PackRData() ([]byte, error) {
 	rDataBuf := newBufWriteAttempter()
 	for _, t := range tr.texts {
 		rDataBuf.attemptWrite([]byte{uint8(len(t))})
 		rDataBuf.attemptWrite([]byte(t))
 	}
 
-	rrr.static.RDataLength = uint16(rDataBuf.buf.Len())
-	rrr.rData = rDataBuf.buf.Bytes()
-
-	return rrr, rDataBuf.err
+	return rDataBuf.buf.Bytes(), rDataBuf.err
 }
 
-func (tr TXTRecord) GetCommon() ResourceRecordCommon {
-	return tr.Common
+func (tr TXTRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newTXTRecordFromRawRR(rd.rrr), nil
 }
 
 func (tr TXTRecord) Equal(otr DNSResourceRecord) (bool, []string) {
@@ -312,34 +333,9 @@ func (tr TXTRecord) Equal(otr DNSResourceRecord) (bool, []string) {
 	return same, reasons
 }
 
-type NSECRecord struct {
-	Common          ResourceRecordCommon
-	NextDomainName  string
-	NextDomainTypes []RecordType
-}
-
-func (nsr NSECRecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
-	rrr := newRawResourceRecordFromCommon(nsr.Common)
-
-	////// Fill a buffer with the RDATA section //////
-	rDataBuf := newBufWriteAttempter()
-	// Write the Next Domain Name field and terminating NULL
-	rDataBuf.attemptWrite(domain(nsr.NextDomainName).toRawLabels().toBytes())
-	// Write the Type Bit maps field
-	nsr._writeBitMap(&rDataBuf)
-
-	if rDataBuf.err != nil {
-		return rrr, fmt.Errorf("bytes.Buffer.Write(): %s", rDataBuf.err)
-	}
-
-	rrr.static.RDataLength = uint16(rDataBuf.buf.Len())
-	rrr.rData = rDataBuf.buf.Bytes()
-
-	return rrr, nil
-}
-func (nsr NSECRecord) _writeBitMap(rDataBuf *bufWriteAttempter) {
-	/* NSEC records' RDATA contains a bitmap of all the types declared as
-	present by the NSEC record. The format is described in section 4.1.2
+func writeTypeBitMap(rDataBuf *bufWriteAttempter, types []RecordType) {
+	/* NSEC and NSEC3 records' RDATA contains a bitmap of all the types
+	declared as present by the record. The format is described in section 4.1.2
 	of the RFC: https://www.ietf.org/rfc/rfc4034.txt .
 
 	The example in section 4.3 of that same RFC is a great explanation; I
@@ -357,8 +353,11 @@ func (nsr NSECRecord) _writeBitMap(rDataBuf *bufWriteAttempter) {
 		octet uint8
 		bit   uint8
 	}
+	sorted := make([]RecordType, len(types))
+	copy(sorted, types)
+	sort.Sort(recordTypes(sorted))
 	var typeDecls []domainTypeDecl
-	for _, typ := range nsr.NextDomainTypes {
+	for _, typ := range sorted {
 		typeDecls = append(typeDecls, domainTypeDecl{
 			group: uint8(typ / 256),
 			octet: uint8((typ % 256) / 8),
@@ -444,10 +443,34 @@ func (nsr NSECRecord) _writeBitMap(rDataBuf *bufWriteAttempter) {
 	}
 }
 
+type NSECRecord struct {
+	Common          ResourceRecordCommon
+	NextDomainName  string
+	NextDomainTypes []RecordType
+}
+
+func (nsr NSECRecord) PackRData() ([]byte, error) {
+	////// Fill a buffer with the RDATA section //////
+	rDataBuf := newBufWriteAttempter()
+	// Write the Next Domain Name field and terminating NULL
+	rDataBuf.attemptWrite(domain(nsr.NextDomainName).toRawLabels().toBytes())
+	// Write the Type Bit maps field
+	writeTypeBitMap(&rDataBuf, nsr.NextDomainTypes)
+
+	if rDataBuf.err != nil {
+		return nil, fmt.Errorf("bytes.Buffer.Write(): %s", rDataBuf.err)
+	}
+
+	return rDataBuf.buf.Bytes(), nil
+}
 func (nsr NSECRecord) GetCommon() ResourceRecordCommon {
 	return nsr.Common
 }
 
+func (nsr NSECRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newNSECRecordFromRawRR(rd.rrr)
+}
+
 func (nsr NSECRecord) Equal(onsr DNSResourceRecord) (bool, []string) {
 	other := onsr.(NSECRecord)
 	same, reasons := nsr.Common.equal(other.Common)
@@ -464,52 +487,1886 @@ func (nsr NSECRecord) Equal(onsr DNSResourceRecord) (bool, []string) {
 	return same, reasons
 }
 
-type OPTRecord struct {
-	Common  ResourceRecordCommon
-	Options map[uint16][]byte
+// NSEC3HashSHA1 is the only NSEC3 hash algorithm defined; see RFC 5155
+// section 11.
+const NSEC3HashSHA1 uint8 = 1
+
+// NSEC3FlagOptOut is the Opt-Out flag of an NSEC3 record; see RFC 5155
+// section 3.1.2.1.
+const NSEC3FlagOptOut uint8 = 0x01
+
+// nsec3Encoding is the "Base 32 Encoding with Extended Hex Alphabet" from RFC
+// 4648, without padding, as used to present NSEC3 hashed owner names.
+var nsec3Encoding = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+// NSEC3Record is the hashed authenticated denial-of-existence record from RFC
+// 5155. NextHashedOwner is presented in unpadded base32hex, as it would appear
+// as the first label of an owner name.
+type NSEC3Record struct {
+	Common          ResourceRecordCommon
+	HashAlgorithm   uint8
+	Flags           uint8
+	Iterations      uint16
+	Salt            []byte
+	NextHashedOwner string
+	Types           []RecordType
 }
 
-func (or OPTRecord) toRawDNSResourceRecord() (rawResourceRecord, error) {
-	rrr := newRawResourceRecordFromCommon(or.Common)
+func (n3r NSEC3Record) GetCommon() ResourceRecordCommon {
+	return n3r.Common
+}
 
-	var keys []uint16
-	for key, _ := range or.Options {
-		keys = append(keys, key)
+func (n3r NSEC3Record) PackRData() ([]byte, error) {
+	if len(n3r.Salt) > 255 {
+		return nil, fmt.Errorf("Salt is %d bytes, may not exceed 255", len(n3r.Salt))
 	}
-	sort.Sort(UInt16Slice(keys))
+	nextHashed, err := nsec3Encoding.DecodeString(strings.ToUpper(n3r.NextHashedOwner))
+	if err != nil {
+		return nil, fmt.Errorf("NextHashedOwner: base32hex decode: %s", err)
+	}
+	if len(nextHashed) > 255 {
+		return nil, fmt.Errorf("NextHashedOwner is %d bytes, may not exceed 255", len(nextHashed))
+	}
+
 	rDataBuf := newBufWriteAttempter()
-	for _, key := range keys {
-		rDataBuf.attemptBinaryWrite(binary.BigEndian, key)
-		optLen := uint16(len(or.Options[key]))
-		rDataBuf.attemptBinaryWrite(binary.BigEndian, optLen)
-		rDataBuf.attemptWrite(or.Options[key])
+	rDataBuf.attemptWrite([]byte{n3r.HashAlgorithm, n3r.Flags})
+	rDataBuf.attemptBinaryWrite(binary.BigEndian, n3r.Iterations)
+	rDataBuf.attemptWrite([]byte{uint8(len(n3r.Salt))})
+	rDataBuf.attemptWrite(n3r.Salt)
+	rDataBuf.attemptWrite([]byte{uint8(len(nextHashed))})
+	rDataBuf.attemptWrite(nextHashed)
+	writeTypeBitMap(&rDataBuf, n3r.Types)
+
+	if rDataBuf.err != nil {
+		return nil, fmt.Errorf("bytes.Buffer.Write(): %s", rDataBuf.err)
 	}
 
-	rrr.static.RDataLength = uint16(rDataBuf.buf.Len())
-	rrr.rData = rDataBuf.buf.Bytes()
+	return rDataBuf.buf.Bytes(), nil
+}
+
+func (n3r NSEC3Record) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newNSEC3RecordFromRawRR(rd.rrr)
+}
+
+func (n3r NSEC3Record) Equal(on3r DNSResourceRecord) (bool, []string) {
+	other := on3r.(NSEC3Record)
+	same, reasons := n3r.Common.equal(other.Common)
+	if n3r.HashAlgorithm != other.HashAlgorithm {
+		same = false
+		reason := fmt.Sprintf("HashAlgorithm: %d != %d", n3r.HashAlgorithm, other.HashAlgorithm)
+		reasons = append(reasons, reason)
+	}
+	if n3r.Flags != other.Flags {
+		same = false
+		reason := fmt.Sprintf("Flags: %d != %d", n3r.Flags, other.Flags)
+		reasons = append(reasons, reason)
+	}
+	if n3r.Iterations != other.Iterations {
+		same = false
+		reason := fmt.Sprintf("Iterations: %d != %d", n3r.Iterations, other.Iterations)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(n3r.Salt, other.Salt) {
+		same = false
+		reason := fmt.Sprintf("Salt: %x != %x", n3r.Salt, other.Salt)
+		reasons = append(reasons, reason)
+	}
+	if !strings.EqualFold(n3r.NextHashedOwner, other.NextHashedOwner) {
+		same = false
+		reason := fmt.Sprintf("NextHashedOwner: %q != %q", n3r.NextHashedOwner, other.NextHashedOwner)
+		reasons = append(reasons, reason)
+	}
+	if !reflect.DeepEqual(n3r.Types, other.Types) {
+		same = false
+		reason := fmt.Sprintf("Types: %v != %v", n3r.Types, other.Types)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
 
-	return rrr, rDataBuf.err
+// NSEC3PARAMRecord carries the hash parameters an authoritative server uses
+// to compute NSEC3 records for its zone; see RFC 5155 section 4.
+type NSEC3PARAMRecord struct {
+	Common        ResourceRecordCommon
+	HashAlgorithm uint8
+	Flags         uint8
+	Iterations    uint16
+	Salt          []byte
 }
 
-func (or OPTRecord) GetCommon() ResourceRecordCommon {
-	return or.Common
+func (n3pr NSEC3PARAMRecord) GetCommon() ResourceRecordCommon {
+	return n3pr.Common
 }
 
-func (or OPTRecord) Equal(oor DNSResourceRecord) (bool, []string) {
-	other := oor.(OPTRecord)
-	same, reasons := or.Common.equal(other.Common)
-	if !reflect.DeepEqual(or.Options, other.Options) {
+func (n3pr NSEC3PARAMRecord) PackRData() ([]byte, error) {
+	if len(n3pr.Salt) > 255 {
+		return nil, fmt.Errorf("Salt is %d bytes, may not exceed 255", len(n3pr.Salt))
+	}
+
+	rDataBuf := newBufWriteAttempter()
+	rDataBuf.attemptWrite([]byte{n3pr.HashAlgorithm, n3pr.Flags})
+	rDataBuf.attemptBinaryWrite(binary.BigEndian, n3pr.Iterations)
+	rDataBuf.attemptWrite([]byte{uint8(len(n3pr.Salt))})
+	rDataBuf.attemptWrite(n3pr.Salt)
+
+	return rDataBuf.buf.Bytes(), rDataBuf.err
+}
+
+func (n3pr NSEC3PARAMRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newNSEC3PARAMRecordFromRawRR(rd.rrr)
+}
+
+func (n3pr NSEC3PARAMRecord) Equal(on3pr DNSResourceRecord) (bool, []string) {
+	other := on3pr.(NSEC3PARAMRecord)
+	same, reasons := n3pr.Common.equal(other.Common)
+	if n3pr.HashAlgorithm != other.HashAlgorithm {
 		same = false
-		reason := fmt.Sprintf("Options: %v != %v", or.Options, other.Options)
+		reason := fmt.Sprintf("HashAlgorithm: %d != %d", n3pr.HashAlgorithm, other.HashAlgorithm)
+		reasons = append(reasons, reason)
+	}
+	if n3pr.Flags != other.Flags {
+		same = false
+		reason := fmt.Sprintf("Flags: %d != %d", n3pr.Flags, other.Flags)
+		reasons = append(reasons, reason)
+	}
+	if n3pr.Iterations != other.Iterations {
+		same = false
+		reason := fmt.Sprintf("Iterations: %d != %d", n3pr.Iterations, other.Iterations)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(n3pr.Salt, other.Salt) {
+		same = false
+		reason := fmt.Sprintf("Salt: %x != %x", n3pr.Salt, other.Salt)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// NSEC3Hash computes the iterated hash of name described in RFC 5155 section
+// 5, i.e. IH(salt, canonical(name), iterations).
+func NSEC3Hash(name string, hashAlgorithm uint8, iterations uint16, salt []byte) ([]byte, error) {
+	if hashAlgorithm != NSEC3HashSHA1 {
+		return nil, fmt.Errorf("Unsupported NSEC3 hash algorithm: %d", hashAlgorithm)
+	}
+
+	h := sha1.New()
+	h.Write(domain(name).toCanonicalBytes())
+	h.Write(salt)
+	digest := h.Sum(nil)
+	for i := 0; i < int(iterations); i++ {
+		h.Reset()
+		h.Write(digest)
+		h.Write(salt)
+		digest = h.Sum(digest[:0])
+	}
+	return digest, nil
+}
+
+// NSEC3HashedOwnerName returns the owner name of the NSEC3 record covering
+// name within zone, i.e. the base32hex-encoded NSEC3Hash of name prepended as
+// a label to zone, e.g. "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example".
+func NSEC3HashedOwnerName(name, zone string, hashAlgorithm uint8, iterations uint16, salt []byte) (string, error) {
+	digest, err := NSEC3Hash(name, hashAlgorithm, iterations, salt)
+	if err != nil {
+		return "", err
+	}
+	label := strings.ToLower(nsec3Encoding.EncodeToString(digest))
+	zone = strings.TrimSuffix(zone, ".")
+	if zone == "" {
+		return label, nil
+	}
+	return label + "." + zone, nil
+}
+
+// SVCBRecord is a service binding record from RFC 9460. A Priority of 0
+// means AliasMode, in which case Params should be empty; a Target of "" or
+// "." means the owner name (ServiceMode) or "no such service" (AliasMode).
+type SVCBRecord struct {
+	Common   ResourceRecordCommon
+	Priority uint16
+	Target   string
+	Params   []SvcParam
+}
+
+func (sr SVCBRecord) GetCommon() ResourceRecordCommon {
+	return sr.Common
+}
+
+func (sr SVCBRecord) PackRData() ([]byte, error) {
+	rData, err := packSVCBRData(sr.Priority, sr.Target, sr.Params)
+	if err != nil {
+		return nil, fmt.Errorf("packSVCBRData: %s", err)
+	}
+	return rData, nil
+}
+
+func (sr SVCBRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newSVCBRecordFromRawRR(rd.rrr)
+}
+
+func (sr SVCBRecord) Equal(osr DNSResourceRecord) (bool, []string) {
+	other := osr.(SVCBRecord)
+	same, reasons := sr.Common.equal(other.Common)
+	rdSame, rdReasons := svcbRDataEqual(sr.Priority, sr.Target, sr.Params, other.Priority, other.Target, other.Params)
+	return same && rdSame, append(reasons, rdReasons...)
+}
+
+// HTTPSRecord is the HTTPS-specific variant of SVCBRecord; its RDATA format
+// is identical. See RFC 9460 section 9.
+type HTTPSRecord struct {
+	Common   ResourceRecordCommon
+	Priority uint16
+	Target   string
+	Params   []SvcParam
+}
+
+func (hr HTTPSRecord) GetCommon() ResourceRecordCommon {
+	return hr.Common
+}
+
+func (hr HTTPSRecord) PackRData() ([]byte, error) {
+	rData, err := packSVCBRData(hr.Priority, hr.Target, hr.Params)
+	if err != nil {
+		return nil, fmt.Errorf("packSVCBRData: %s", err)
+	}
+	return rData, nil
+}
+
+func (hr HTTPSRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newHTTPSRecordFromRawRR(rd.rrr)
+}
+
+func (hr HTTPSRecord) Equal(ohr DNSResourceRecord) (bool, []string) {
+	other := ohr.(HTTPSRecord)
+	same, reasons := hr.Common.equal(other.Common)
+	rdSame, rdReasons := svcbRDataEqual(hr.Priority, hr.Target, hr.Params, other.Priority, other.Target, other.Params)
+	return same && rdSame, append(reasons, rdReasons...)
+}
+
+func packSVCBRData(priority uint16, target string, params []SvcParam) ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptBinaryWrite(binary.BigEndian, priority)
+	bwa.attemptWrite(domain(target).toRawLabels().toBytes())
+	paramBytes, err := packSvcParams(params)
+	if err != nil {
+		return nil, err
+	}
+	bwa.attemptWrite(paramBytes)
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+	return bwa.buf.Bytes(), nil
+}
+
+func svcbRDataEqual(priority uint16, target string, params []SvcParam, oPriority uint16, oTarget string, oParams []SvcParam) (bool, []string) {
+	same := true
+	var reasons []string
+	if priority != oPriority {
+		same = false
+		reason := fmt.Sprintf("Priority: %d != %d", priority, oPriority)
+		reasons = append(reasons, reason)
+	}
+	if strings.TrimSuffix(target, ".") != strings.TrimSuffix(oTarget, ".") {
+		same = false
+		reason := fmt.Sprintf("Target: %q != %q", target, oTarget)
+		reasons = append(reasons, reason)
+	}
+	paramsSame, paramsReasons := svcParamsEqual(params, oParams)
+	if !paramsSame {
+		same = false
+		reasons = append(reasons, paramsReasons...)
+	}
+	return same, reasons
+}
+
+// NAPTRRecord is a Naming Authority Pointer record, as used by the Dynamic
+// Delegation Discovery System (e.g. ENUM and SIP). See RFC 3403 section 4.1.
+type NAPTRRecord struct {
+	Common      ResourceRecordCommon
+	Order       uint16
+	Preference  uint16
+	Flags       string
+	Service     string
+	Regexp      string
+	Replacement string
+}
+
+func (nr NAPTRRecord) GetCommon() ResourceRecordCommon {
+	return nr.Common
+}
+
+func (nr NAPTRRecord) PackRData() ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptBinaryWrite(binary.BigEndian, nr.Order)
+	bwa.attemptBinaryWrite(binary.BigEndian, nr.Preference)
+	bwa.attemptWriteCharacterString(nr.Flags)
+	bwa.attemptWriteCharacterString(nr.Service)
+	bwa.attemptWriteCharacterString(nr.Regexp)
+	bwa.attemptWrite(domain(nr.Replacement).toRawLabels().toBytes())
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (nr NAPTRRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newNAPTRRecordFromRawRR(rd.rrr)
+}
+
+func (nr NAPTRRecord) Equal(onr DNSResourceRecord) (bool, []string) {
+	other := onr.(NAPTRRecord)
+	same, reasons := nr.Common.equal(other.Common)
+	if nr.Order != other.Order {
+		same = false
+		reason := fmt.Sprintf("Order: %d != %d", nr.Order, other.Order)
+		reasons = append(reasons, reason)
+	}
+	if nr.Preference != other.Preference {
+		same = false
+		reason := fmt.Sprintf("Preference: %d != %d", nr.Preference, other.Preference)
+		reasons = append(reasons, reason)
+	}
+	if nr.Flags != other.Flags {
+		same = false
+		reason := fmt.Sprintf("Flags: %q != %q", nr.Flags, other.Flags)
+		reasons = append(reasons, reason)
+	}
+	if nr.Service != other.Service {
+		same = false
+		reason := fmt.Sprintf("Service: %q != %q", nr.Service, other.Service)
+		reasons = append(reasons, reason)
+	}
+	if nr.Regexp != other.Regexp {
+		same = false
+		reason := fmt.Sprintf("Regexp: %q != %q", nr.Regexp, other.Regexp)
+		reasons = append(reasons, reason)
+	}
+	// "." and "" are both the root, which ENUM uses for no replacement
+	if strings.TrimSuffix(nr.Replacement, ".") != strings.TrimSuffix(other.Replacement, ".") {
+		same = false
+		reason := fmt.Sprintf("Replacement: %q != %q", nr.Replacement, other.Replacement)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// URIRecord maps a service name (e.g. "_ftp._tcp.example.com") to a URI; see
+// RFC 7553.
+type URIRecord struct {
+	Common   ResourceRecordCommon
+	Priority uint16
+	Weight   uint16
+	Target   string
+}
+
+func (ur URIRecord) GetCommon() ResourceRecordCommon {
+	return ur.Common
+}
+
+func (ur URIRecord) PackRData() ([]byte, error) {
+	if len(ur.Target) == 0 {
+		return nil, fmt.Errorf("Target may not be empty")
+	}
+	bwa := newBufWriteAttempter()
+	bwa.attemptBinaryWrite(binary.BigEndian, ur.Priority)
+	bwa.attemptBinaryWrite(binary.BigEndian, ur.Weight)
+	// The target is not a <character-string>; it occupies the remainder of
+	// the RDATA with no length prefix.
+	bwa.attemptWrite([]byte(ur.Target))
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (ur URIRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newURIRecordFromRawRR(rd.rrr)
+}
+
+func (ur URIRecord) Equal(our DNSResourceRecord) (bool, []string) {
+	other := our.(URIRecord)
+	same, reasons := ur.Common.equal(other.Common)
+	if ur.Priority != other.Priority {
+		same = false
+		reason := fmt.Sprintf("Priority: %d != %d", ur.Priority, other.Priority)
+		reasons = append(reasons, reason)
+	}
+	if ur.Weight != other.Weight {
+		same = false
+		reason := fmt.Sprintf("Weight: %d != %d", ur.Weight, other.Weight)
+		reasons = append(reasons, reason)
+	}
+	if ur.Target != other.Target {
+		same = false
+		reason := fmt.Sprintf("Target: %q != %q", ur.Target, other.Target)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+const (
+	// SSHFPAlgorithmRSA comment only here to shut the linter up, see RFC 4255 / IANA for real information.
+	SSHFPAlgorithmRSA uint8 = 1
+	// SSHFPAlgorithmDSA comment only here to shut the linter up, see RFC 4255 / IANA for real information.
+	SSHFPAlgorithmDSA uint8 = 2
+	// SSHFPAlgorithmECDSA comment only here to shut the linter up, see RFC 6594 for real information.
+	SSHFPAlgorithmECDSA uint8 = 3
+	// SSHFPAlgorithmEd25519 comment only here to shut the linter up, see RFC 7479 for real information.
+	SSHFPAlgorithmEd25519 uint8 = 4
+	// SSHFPAlgorithmEd448 comment only here to shut the linter up, see RFC 8709 for real information.
+	SSHFPAlgorithmEd448 uint8 = 6
+)
+const (
+	// SSHFPTypeSHA1 comment only here to shut the linter up, see RFC 4255 for real information.
+	SSHFPTypeSHA1 uint8 = 1
+	// SSHFPTypeSHA256 comment only here to shut the linter up, see RFC 6594 for real information.
+	SSHFPTypeSHA256 uint8 = 2
+)
+
+// SSHFPRecord publishes the fingerprint of an SSH host key; see RFC 4255.
+type SSHFPRecord struct {
+	Common          ResourceRecordCommon
+	Algorithm       uint8
+	FingerprintType uint8
+	Fingerprint     []byte
+}
+
+func (sr SSHFPRecord) GetCommon() ResourceRecordCommon {
+	return sr.Common
+}
+
+func (sr SSHFPRecord) PackRData() ([]byte, error) {
+	return append([]byte{sr.Algorithm, sr.FingerprintType}, sr.Fingerprint...), nil
+}
+
+func (sr SSHFPRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newSSHFPRecordFromRawRR(rd.rrr)
+}
+
+func (sr SSHFPRecord) Equal(osr DNSResourceRecord) (bool, []string) {
+	other := osr.(SSHFPRecord)
+	same, reasons := sr.Common.equal(other.Common)
+	if sr.Algorithm != other.Algorithm {
+		same = false
+		reason := fmt.Sprintf("Algorithm: %d != %d", sr.Algorithm, other.Algorithm)
+		reasons = append(reasons, reason)
+	}
+	if sr.FingerprintType != other.FingerprintType {
+		same = false
+		reason := fmt.Sprintf("FingerprintType: %d != %d", sr.FingerprintType, other.FingerprintType)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(sr.Fingerprint, other.Fingerprint) {
+		same = false
+		reason := fmt.Sprintf("Fingerprint: %x != %x", sr.Fingerprint, other.Fingerprint)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// SSHFPFingerprint computes the SSHFP algorithm number and fingerprint for an
+// SSH public key, given in its wire-format blob (i.e. the base64-decoded
+// second field of an authorized_keys or known_hosts line).
+func SSHFPFingerprint(keyBlob []byte, fingerprintType uint8) (uint8, []byte, error) {
+	if len(keyBlob) < 4 {
+		return 0, nil, fmt.Errorf("key blob too short (%d bytes)", len(keyBlob))
+	}
+	nameLen := binary.BigEndian.Uint32(keyBlob[0:4])
+	if uint32(len(keyBlob)-4) < nameLen {
+		return 0, nil, fmt.Errorf("key type length %d exceeds blob", nameLen)
+	}
+	keyType := string(keyBlob[4 : 4+nameLen])
+
+	var algorithm uint8
+	switch {
+	case keyType == "ssh-rsa":
+		algorithm = SSHFPAlgorithmRSA
+	case keyType == "ssh-dss":
+		algorithm = SSHFPAlgorithmDSA
+	case strings.HasPrefix(keyType, "ecdsa-sha2-"):
+		algorithm = SSHFPAlgorithmECDSA
+	case keyType == "ssh-ed25519":
+		algorithm = SSHFPAlgorithmEd25519
+	case keyType == "ssh-ed448":
+		algorithm = SSHFPAlgorithmEd448
+	default:
+		return 0, nil, fmt.Errorf("Unsupported SSH key type: %q", keyType)
+	}
+
+	switch fingerprintType {
+	case SSHFPTypeSHA1:
+		sum := sha1.Sum(keyBlob)
+		return algorithm, sum[:], nil
+	case SSHFPTypeSHA256:
+		sum := sha256.Sum256(keyBlob)
+		return algorithm, sum[:], nil
+	default:
+		return 0, nil, fmt.Errorf("Unsupported SSHFP fingerprint type: %d", fingerprintType)
+	}
+}
+
+const (
+	// TLSAUsagePKIXTA comment only here to shut the linter up, see RFC 6698 / 7218 for real information.
+	TLSAUsagePKIXTA uint8 = 0
+	// TLSAUsagePKIXEE comment only here to shut the linter up, see RFC 6698 / 7218 for real information.
+	TLSAUsagePKIXEE uint8 = 1
+	// TLSAUsageDANETA comment only here to shut the linter up, see RFC 6698 / 7218 for real information.
+	TLSAUsageDANETA uint8 = 2
+	// TLSAUsageDANEEE comment only here to shut the linter up, see RFC 6698 / 7218 for real information.
+	TLSAUsageDANEEE uint8 = 3
+)
+const (
+	// TLSASelectorCert comment only here to shut the linter up, see RFC 6698 for real information.
+	TLSASelectorCert uint8 = 0
+	// TLSASelectorSPKI comment only here to shut the linter up, see RFC 6698 for real information.
+	TLSASelectorSPKI uint8 = 1
+)
+const (
+	// TLSAMatchingFull comment only here to shut the linter up, see RFC 6698 for real information.
+	TLSAMatchingFull uint8 = 0
+	// TLSAMatchingSHA256 comment only here to shut the linter up, see RFC 6698 for real information.
+	TLSAMatchingSHA256 uint8 = 1
+	// TLSAMatchingSHA512 comment only here to shut the linter up, see RFC 6698 for real information.
+	TLSAMatchingSHA512 uint8 = 2
+)
+
+// TLSARecord is a DANE certificate association record; see RFC 6698.
+type TLSARecord struct {
+	Common       ResourceRecordCommon
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+func (tr TLSARecord) GetCommon() ResourceRecordCommon {
+	return tr.Common
+}
+
+func (tr TLSARecord) PackRData() ([]byte, error) {
+	return append([]byte{tr.Usage, tr.Selector, tr.MatchingType}, tr.Data...), nil
+}
+
+func (tr TLSARecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newTLSARecordFromRawRR(rd.rrr)
+}
+
+func (tr TLSARecord) Equal(otr DNSResourceRecord) (bool, []string) {
+	other := otr.(TLSARecord)
+	same, reasons := tr.Common.equal(other.Common)
+	rdSame, rdReasons := certAssociationEqual(tr.Usage, tr.Selector, tr.MatchingType, tr.Data, other.Usage, other.Selector, other.MatchingType, other.Data)
+	return same && rdSame, append(reasons, rdReasons...)
+}
+
+// SMIMEARecord associates an S/MIME certificate with an email address; its
+// RDATA has the same format as TLSARecord. See RFC 8162.
+type SMIMEARecord struct {
+	Common       ResourceRecordCommon
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+func (sr SMIMEARecord) GetCommon() ResourceRecordCommon {
+	return sr.Common
+}
+
+func (sr SMIMEARecord) PackRData() ([]byte, error) {
+	return append([]byte{sr.Usage, sr.Selector, sr.MatchingType}, sr.Data...), nil
+}
+
+func (sr SMIMEARecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newSMIMEARecordFromRawRR(rd.rrr)
+}
+
+func (sr SMIMEARecord) Equal(osr DNSResourceRecord) (bool, []string) {
+	other := osr.(SMIMEARecord)
+	same, reasons := sr.Common.equal(other.Common)
+	rdSame, rdReasons := certAssociationEqual(sr.Usage, sr.Selector, sr.MatchingType, sr.Data, other.Usage, other.Selector, other.MatchingType, other.Data)
+	return same && rdSame, append(reasons, rdReasons...)
+}
+
+func certAssociationEqual(usage, selector, matchingType uint8, data []byte, oUsage, oSelector, oMatchingType uint8, oData []byte) (bool, []string) {
+	same := true
+	var reasons []string
+	if usage != oUsage {
+		same = false
+		reason := fmt.Sprintf("Usage: %d != %d", usage, oUsage)
+		reasons = append(reasons, reason)
+	}
+	if selector != oSelector {
+		same = false
+		reason := fmt.Sprintf("Selector: %d != %d", selector, oSelector)
+		reasons = append(reasons, reason)
+	}
+	if matchingType != oMatchingType {
+		same = false
+		reason := fmt.Sprintf("MatchingType: %d != %d", matchingType, oMatchingType)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(data, oData) {
+		same = false
+		reason := fmt.Sprintf("Data: %x != %x", data, oData)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// TLSAAssociation computes the "certificate association data" field of a
+// TLSA or SMIMEA record for cert, given the record's selector and matching
+// type.
+func TLSAAssociation(cert *x509.Certificate, selector, matchingType uint8) ([]byte, error) {
+	var selected []byte
+	switch selector {
+	case TLSASelectorCert:
+		selected = cert.Raw
+	case TLSASelectorSPKI:
+		selected = cert.RawSubjectPublicKeyInfo
+	default:
+		return nil, fmt.Errorf("Unsupported TLSA selector: %d", selector)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("Certificate has no raw DER data for selector %d", selector)
+	}
+
+	switch matchingType {
+	case TLSAMatchingFull:
+		ret := make([]byte, len(selected))
+		copy(ret, selected)
+		return ret, nil
+	case TLSAMatchingSHA256:
+		sum := sha256.Sum256(selected)
+		return sum[:], nil
+	case TLSAMatchingSHA512:
+		sum := sha512.Sum512(selected)
+		return sum[:], nil
+	default:
+		return nil, fmt.Errorf("Unsupported TLSA matching type: %d", matchingType)
+	}
+}
+
+// OPENPGPKEYRecord publishes an OpenPGP transferable public key, in its
+// binary (not ASCII-armored) form; see RFC 7929.
+type OPENPGPKEYRecord struct {
+	Common    ResourceRecordCommon
+	PublicKey []byte
+}
+
+func (opr OPENPGPKEYRecord) GetCommon() ResourceRecordCommon {
+	return opr.Common
+}
+
+func (opr OPENPGPKEYRecord) PackRData() ([]byte, error) {
+	rData := make([]byte, len(opr.PublicKey))
+	copy(rData, opr.PublicKey)
+	return rData, nil
+}
+
+func (opr OPENPGPKEYRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newOPENPGPKEYRecordFromRawRR(rd.rrr), nil
+}
+
+func (opr OPENPGPKEYRecord) Equal(oopr DNSResourceRecord) (bool, []string) {
+	other := oopr.(OPENPGPKEYRecord)
+	same, reasons := opr.Common.equal(other.Common)
+	if !bytes.Equal(opr.PublicKey, other.PublicKey) {
+		same = false
+		reason := fmt.Sprintf("PublicKey: %x != %x", opr.PublicKey, other.PublicKey)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// CAAFlagIssuerCritical is the "Issuer Critical" bit in a CAA record's flags;
+// see RFC 8659 section 4.1.
+const CAAFlagIssuerCritical uint8 = 0x80
+
+// CAARecord is a Certification Authority Authorization record; see RFC 8659.
+// Value is kept as raw bytes because its syntax depends on Tag; see
+// ParseCAAIssueValue for the "issue" and "issuewild" tags.
+type CAARecord struct {
+	Common ResourceRecordCommon
+	Flags  uint8
+	Tag    string
+	Value  []byte
+}
+
+// IsCritical reports whether the Issuer Critical flag is set.
+func (cr CAARecord) IsCritical() bool {
+	return cr.Flags&CAAFlagIssuerCritical == CAAFlagIssuerCritical
+}
+
+func (cr CAARecord) GetCommon() ResourceRecordCommon {
+	return cr.Common
+}
+
+func (cr CAARecord) PackRData() ([]byte, error) {
+	if err := validateCAATag(cr.Tag); err != nil {
+		return nil, err
+	}
+	bwa := newBufWriteAttempter()
+	bwa.attemptWrite([]byte{cr.Flags})
+	bwa.attemptWriteCharacterString(cr.Tag)
+	bwa.attemptWrite(cr.Value)
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (cr CAARecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newCAARecordFromRawRR(rd.rrr)
+}
+
+func (cr CAARecord) Equal(ocr DNSResourceRecord) (bool, []string) {
+	other := ocr.(CAARecord)
+	same, reasons := cr.Common.equal(other.Common)
+	if cr.Flags != other.Flags {
+		same = false
+		reason := fmt.Sprintf("Flags: %d != %d", cr.Flags, other.Flags)
+		reasons = append(reasons, reason)
+	}
+	if cr.Tag != other.Tag {
+		same = false
+		reason := fmt.Sprintf("Tag: %q != %q", cr.Tag, other.Tag)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(cr.Value, other.Value) {
+		same = false
+		reason := fmt.Sprintf("Value: %q != %q", cr.Value, other.Value)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// validateCAATag enforces RFC 8659 section 4.1: a tag is 1-15 ASCII letters
+// and digits.
+func validateCAATag(tag string) error {
+	if len(tag) < 1 || len(tag) > 15 {
+		return fmt.Errorf("CAA tag %q must be 1-15 characters", tag)
+	}
+	for _, c := range []byte(tag) {
+		if !isASCIIAlphaNum(c) {
+			return fmt.Errorf("CAA tag %q contains non-alphanumeric character %q", tag, c)
+		}
+	}
+	return nil
+}
+
+func isASCIIAlphaNum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// CAAParameter is a single "tag=value" pair from an issue or issuewild value.
+type CAAParameter struct {
+	Tag   string
+	Value string
+}
+
+// CAAIssueValue is the parsed form of the value of a CAA "issue" or
+// "issuewild" property. An empty IssuerDomainName means no CA is authorized.
+type CAAIssueValue struct {
+	IssuerDomainName string
+	Parameters       []CAAParameter
+}
+
+// ParseCAAIssueValue parses the value of an "issue" or "issuewild" CAA
+// property according to the grammar in RFC 8659 section 4.2, e.g.
+// "ca.example.net; account=230123".
+func ParseCAAIssueValue(value []byte) (CAAIssueValue, error) {
+	var iv CAAIssueValue
+	// Parameter values may not contain ';', so this split is unambiguous
+	parts := strings.Split(string(value), ";")
+
+	iv.IssuerDomainName = strings.Trim(parts[0], " \t")
+	if iv.IssuerDomainName != "" {
+		for _, label := range strings.Split(iv.IssuerDomainName, ".") {
+			if !isCAALabel(label) {
+				return iv, fmt.Errorf("Invalid issuer-domain-name %q", iv.IssuerDomainName)
+			}
+		}
+	}
+
+	for i, part := range parts[1:] {
+		part = strings.Trim(part, " \t")
+		if part == "" && i == len(parts)-2 {
+			// A trailing ";" with no parameters is permitted
+			break
+		}
+		eq := strings.IndexByte(part, '=')
+		if eq < 0 {
+			return iv, fmt.Errorf("Parameter %q has no '='", part)
+		}
+		param := CAAParameter{
+			Tag:   strings.Trim(part[:eq], " \t"),
+			Value: strings.Trim(part[eq+1:], " \t"),
+		}
+		if !isCAALabel(param.Tag) {
+			return iv, fmt.Errorf("Invalid parameter tag %q", param.Tag)
+		}
+		for _, c := range []byte(param.Value) {
+			if c < 0x21 || c > 0x7E {
+				return iv, fmt.Errorf("Parameter %q value contains invalid character %q", param.Tag, c)
+			}
+		}
+		iv.Parameters = append(iv.Parameters, param)
+	}
+
+	return iv, nil
+}
+
+// isCAALabel matches the "label" and "tag" productions of RFC 8659 section
+// 4.2: letters and digits, with interior hyphens.
+func isCAALabel(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if !isASCIIAlphaNum(c) && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// TSIGRecord is a transaction signature meta-record; see RFC 8945 section
+// 4.2. TimeSigned is in seconds since the UNIX epoch and is 48 bits wide on
+// the wire. Error holds a ResponseCode such as CodeBadSig, but is 16 bits
+// wide on the wire. Use SignTSIG and VerifyTSIG rather than building these
+// by hand.
+type TSIGRecord struct {
+	Common     ResourceRecordCommon
+	Algorithm  string
+	TimeSigned uint64
+	Fudge      uint16
+	MAC        []byte
+	OriginalID uint16
+	Error      uint16
+	OtherData  []byte
+}
+
+// timersBytes returns the "TSIG Timers" (Time Signed and Fudge) in wire format.
+func (tr TSIGRecord) timersBytes() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b[0:2], uint16(tr.TimeSigned>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(tr.TimeSigned))
+	binary.BigEndian.PutUint16(b[6:8], tr.Fudge)
+	return b
+}
+
+func (tr TSIGRecord) GetCommon() ResourceRecordCommon {
+	return tr.Common
+}
+
+func (tr TSIGRecord) PackRData() ([]byte, error) {
+	if tr.TimeSigned>>48 != 0 {
+		return nil, fmt.Errorf("TimeSigned %d does not fit in 48 bits", tr.TimeSigned)
+	}
+	if len(tr.MAC) > 65535 || len(tr.OtherData) > 65535 {
+		return nil, fmt.Errorf("MAC or OtherData exceeds 65535 bytes")
+	}
+	bwa := newBufWriteAttempter()
+	bwa.attemptWrite(domain(tr.Algorithm).toRawLabels().toBytes())
+	bwa.attemptWrite(tr.timersBytes())
+	bwa.attemptBinaryWrite(binary.BigEndian, uint16(len(tr.MAC)))
+	bwa.attemptWrite(tr.MAC)
+	bwa.attemptBinaryWrite(binary.BigEndian, tr.OriginalID)
+	bwa.attemptBinaryWrite(binary.BigEndian, tr.Error)
+	bwa.attemptBinaryWrite(binary.BigEndian, uint16(len(tr.OtherData)))
+	bwa.attemptWrite(tr.OtherData)
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (tr TSIGRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newTSIGRecordFromRawRR(rd.rrr)
+}
+
+func (tr TSIGRecord) Equal(otr DNSResourceRecord) (bool, []string) {
+	other := otr.(TSIGRecord)
+	same, reasons := tr.Common.equal(other.Common)
+	if !strings.EqualFold(strings.TrimSuffix(tr.Algorithm, "."), strings.TrimSuffix(other.Algorithm, ".")) {
+		same = false
+		reason := fmt.Sprintf("Algorithm: %q != %q", tr.Algorithm, other.Algorithm)
+		reasons = append(reasons, reason)
+	}
+	if tr.TimeSigned != other.TimeSigned {
+		same = false
+		reason := fmt.Sprintf("TimeSigned: %d != %d", tr.TimeSigned, other.TimeSigned)
+		reasons = append(reasons, reason)
+	}
+	if tr.Fudge != other.Fudge {
+		same = false
+		reason := fmt.Sprintf("Fudge: %d != %d", tr.Fudge, other.Fudge)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(tr.MAC, other.MAC) {
+		same = false
+		reason := fmt.Sprintf("MAC: %x != %x", tr.MAC, other.MAC)
+		reasons = append(reasons, reason)
+	}
+	if tr.OriginalID != other.OriginalID {
+		same = false
+		reason := fmt.Sprintf("OriginalID: %d != %d", tr.OriginalID, other.OriginalID)
+		reasons = append(reasons, reason)
+	}
+	if tr.Error != other.Error {
+		same = false
+		reason := fmt.Sprintf("Error: %d != %d", tr.Error, other.Error)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(tr.OtherData, other.OtherData) {
+		same = false
+		reason := fmt.Sprintf("OtherData: %x != %x", tr.OtherData, other.OtherData)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// LOCRecord gives the geographic location of its owner; see RFC 1876.
+// Latitude and Longitude are in degrees (north and east positive) and
+// Altitude is in meters relative to the WGS 84 reference spheroid. Size,
+// HorizPre and VertPre are in RFC 1876's exponent-mantissa encoding; use
+// LOCPrecision and LOCPrecisionMeters to convert them.
+type LOCRecord struct {
+	Common    ResourceRecordCommon
+	Version   uint8
+	Size      uint8
+	HorizPre  uint8
+	VertPre   uint8
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+func (lr LOCRecord) GetCommon() ResourceRecordCommon {
+	return lr.Common
+}
+
+func (lr LOCRecord) PackRData() ([]byte, error) {
+	lat, lon, alt, err := lr.wireCoordinates()
+	if err != nil {
+		return nil, err
+	}
+	if err = lr.checkPrecisions(); err != nil {
+		return nil, err
+	}
+	bwa := newBufWriteAttempter()
+	bwa.attemptWrite([]byte{lr.Version, lr.Size, lr.HorizPre, lr.VertPre})
+	bwa.attemptBinaryWrite(binary.BigEndian, lat)
+	bwa.attemptBinaryWrite(binary.BigEndian, lon)
+	bwa.attemptBinaryWrite(binary.BigEndian, alt)
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (lr LOCRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newLOCRecordFromRawRR(rd.rrr)
+}
+
+// Equal compares coordinates at the resolution they have on the wire, so
+// that float rounding during a round-trip doesn't register as a difference.
+func (lr LOCRecord) Equal(olr DNSResourceRecord) (bool, []string) {
+	other := olr.(LOCRecord)
+	same, reasons := lr.Common.equal(other.Common)
+	if lr.Version != other.Version {
+		same = false
+		reason := fmt.Sprintf("Version: %d != %d", lr.Version, other.Version)
+		reasons = append(reasons, reason)
+	}
+	if lr.Size != other.Size {
+		same = false
+		reason := fmt.Sprintf("Size: 0x%02x != 0x%02x", lr.Size, other.Size)
+		reasons = append(reasons, reason)
+	}
+	if lr.HorizPre != other.HorizPre {
+		same = false
+		reason := fmt.Sprintf("HorizPre: 0x%02x != 0x%02x", lr.HorizPre, other.HorizPre)
+		reasons = append(reasons, reason)
+	}
+	if lr.VertPre != other.VertPre {
+		same = false
+		reason := fmt.Sprintf("VertPre: 0x%02x != 0x%02x", lr.VertPre, other.VertPre)
+		reasons = append(reasons, reason)
+	}
+	lat, lon, alt, _ := lr.wireCoordinates()
+	oLat, oLon, oAlt, _ := other.wireCoordinates()
+	if lat != oLat {
+		same = false
+		reason := fmt.Sprintf("Latitude: %f != %f", lr.Latitude, other.Latitude)
+		reasons = append(reasons, reason)
+	}
+	if lon != oLon {
+		same = false
+		reason := fmt.Sprintf("Longitude: %f != %f", lr.Longitude, other.Longitude)
+		reasons = append(reasons, reason)
+	}
+	if alt != oAlt {
+		same = false
+		reason := fmt.Sprintf("Altitude: %f != %f", lr.Altitude, other.Altitude)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// CERTRecord stores a certificate or certificate revocation list; see RFC
+// 4398. Type is e.g. 1 for PKIX (X.509); Algorithm uses the DNSSEC algorithm
+// numbers.
+type CERTRecord struct {
+	Common      ResourceRecordCommon
+	CertType    uint16
+	KeyTag      uint16
+	Algorithm   uint8
+	Certificate []byte
+}
+
+func (cr CERTRecord) GetCommon() ResourceRecordCommon {
+	return cr.Common
+}
+
+func (cr CERTRecord) PackRData() ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptBinaryWrite(binary.BigEndian, cr.CertType)
+	bwa.attemptBinaryWrite(binary.BigEndian, cr.KeyTag)
+	bwa.attemptWrite([]byte{cr.Algorithm})
+	bwa.attemptWrite(cr.Certificate)
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (cr CERTRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newCERTRecordFromRawRR(rd.rrr)
+}
+
+func (cr CERTRecord) Equal(ocr DNSResourceRecord) (bool, []string) {
+	other := ocr.(CERTRecord)
+	same, reasons := cr.Common.equal(other.Common)
+	if cr.CertType != other.CertType {
+		same = false
+		reason := fmt.Sprintf("CertType: %d != %d", cr.CertType, other.CertType)
+		reasons = append(reasons, reason)
+	}
+	if cr.KeyTag != other.KeyTag {
+		same = false
+		reason := fmt.Sprintf("KeyTag: %d != %d", cr.KeyTag, other.KeyTag)
+		reasons = append(reasons, reason)
+	}
+	if cr.Algorithm != other.Algorithm {
+		same = false
+		reason := fmt.Sprintf("Algorithm: %d != %d", cr.Algorithm, other.Algorithm)
+		reasons = append(reasons, reason)
+	}
+	if !bytes.Equal(cr.Certificate, other.Certificate) {
+		same = false
+		reason := fmt.Sprintf("Certificate: %x != %x", cr.Certificate, other.Certificate)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+const (
+	// IPSECKEYGatewayNone comment only here to shut the linter up, see RFC 4025 for real information.
+	IPSECKEYGatewayNone uint8 = 0
+	// IPSECKEYGatewayIPv4 comment only here to shut the linter up, see RFC 4025 for real information.
+	IPSECKEYGatewayIPv4 uint8 = 1
+	// IPSECKEYGatewayIPv6 comment only here to shut the linter up, see RFC 4025 for real information.
+	IPSECKEYGatewayIPv6 uint8 = 2
+	// IPSECKEYGatewayDomain comment only here to shut the linter up, see RFC 4025 for real information.
+	IPSECKEYGatewayDomain uint8 = 3
+)
+
+// IPSECKEYRecord publishes an IPsec public key and the gateway to use with
+// it; see RFC 4025. GatewayType selects which of GatewayAddr (IPv4 or IPv6)
+// or GatewayDomain is meaningful; for IPSECKEYGatewayNone both are ignored.
+type IPSECKEYRecord struct {
+	Common        ResourceRecordCommon
+	Precedence    uint8
+	GatewayType   uint8
+	Algorithm     uint8
+	GatewayAddr   net.IP
+	GatewayDomain string
+	PublicKey     []byte
+}
+
+func (ir IPSECKEYRecord) GetCommon() ResourceRecordCommon {
+	return ir.Common
+}
+
+func (ir IPSECKEYRecord) PackRData() ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptWrite([]byte{ir.Precedence, ir.GatewayType, ir.Algorithm})
+	switch ir.GatewayType {
+	case IPSECKEYGatewayNone:
+	case IPSECKEYGatewayIPv4:
+		v4 := ir.GatewayAddr.To4()
+		if v4 == nil {
+			return nil, fmt.Errorf("GatewayAddr %s is not an IPv4 address", ir.GatewayAddr)
+		}
+		bwa.attemptWrite(v4)
+	case IPSECKEYGatewayIPv6:
+		v6 := ir.GatewayAddr.To16()
+		if v6 == nil || ir.GatewayAddr.To4() != nil {
+			return nil, fmt.Errorf("GatewayAddr %s is not an IPv6 address", ir.GatewayAddr)
+		}
+		bwa.attemptWrite(v6)
+	case IPSECKEYGatewayDomain:
+		bwa.attemptWrite(domain(ir.GatewayDomain).toRawLabels().toBytes())
+	default:
+		return nil, fmt.Errorf("Unknown GatewayType %d", ir.GatewayType)
+	}
+	bwa.attemptWrite(ir.PublicKey)
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (ir IPSECKEYRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newIPSECKEYRecordFromRawRR(rd.rrr)
+}
+
+func (ir IPSECKEYRecord) Equal(oir DNSResourceRecord) (bool, []string) {
+	other := oir.(IPSECKEYRecord)
+	same, reasons := ir.Common.equal(other.Common)
+	if ir.Precedence != other.Precedence {
+		same = false
+		reason := fmt.Sprintf("Precedence: %d != %d", ir.Precedence, other.Precedence)
+		reasons = append(reasons, reason)
+	}
+	if ir.GatewayType != other.GatewayType {
+		same = false
+		reason := fmt.Sprintf("GatewayType: %d != %d", ir.GatewayType, other.GatewayType)
+		reasons = append(reasons, reason)
+	}
+	if ir.Algorithm != other.Algorithm {
+		same = false
+		reason := fmt.Sprintf("Algorithm: %d != %d", ir.Algorithm, other.Algorithm)
+		reasons = append(reasons, reason)
+	}
+	switch ir.GatewayType {
+	case IPSECKEYGatewayIPv4, IPSECKEYGatewayIPv6:
+		if !ir.GatewayAddr.Equal(other.GatewayAddr) {
+			same = false
+			reason := fmt.Sprintf("GatewayAddr: %s != %s", ir.GatewayAddr, other.GatewayAddr)
+			reasons = append(reasons, reason)
+		}
+	case IPSECKEYGatewayDomain:
+		if ir.GatewayDomain != other.GatewayDomain {
+			same = false
+			reason := fmt.Sprintf("GatewayDomain: %q != %q", ir.GatewayDomain, other.GatewayDomain)
+			reasons = append(reasons, reason)
+		}
+	}
+	if !bytes.Equal(ir.PublicKey, other.PublicKey) {
+		same = false
+		reason := fmt.Sprintf("PublicKey: %x != %x", ir.PublicKey, other.PublicKey)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// KXRecord names a host willing to act as a key exchanger for its owner;
+// see RFC 2230.
+type KXRecord struct {
+	Common     ResourceRecordCommon
+	Preference uint16
+	Exchanger  string
+}
+
+func (kr KXRecord) GetCommon() ResourceRecordCommon {
+	return kr.Common
+}
+
+func (kr KXRecord) PackRData() ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptBinaryWrite(binary.BigEndian, kr.Preference)
+	bwa.attemptWrite(domain(kr.Exchanger).toRawLabels().toBytes())
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (kr KXRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newKXRecordFromRawRR(rd.rrr)
+}
+
+func (kr KXRecord) Equal(okr DNSResourceRecord) (bool, []string) {
+	other := okr.(KXRecord)
+	same, reasons := kr.Common.equal(other.Common)
+	if kr.Preference != other.Preference {
+		same = false
+		reason := fmt.Sprintf("Preference: %d != %d", kr.Preference, other.Preference)
+		reasons = append(reasons, reason)
+	}
+	if kr.Exchanger != other.Exchanger {
+		same = false
+		reason := fmt.Sprintf("Exchanger: %q != %q", kr.Exchanger, other.Exchanger)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// WKSRecord is an obsolete "well known services" record, listing the ports
+// on which Address offers service over IP protocol Protocol (e.g. 6 for TCP);
+// see RFC 1035 section 3.4.2. The wire bitmap holds each port once, so a
+// decoded record's Ports are sorted and unique; Equal compares Ports as a set.
+type WKSRecord struct {
+	Common   ResourceRecordCommon
+	Address  net.IP
+	Protocol uint8
+	Ports    []uint16
+}
+
+func (wr WKSRecord) GetCommon() ResourceRecordCommon {
+	return wr.Common
+}
+
+func (wr WKSRecord) PackRData() ([]byte, error) {
+	v4 := wr.Address.To4()
+	if v4 == nil {
+		return nil, fmt.Errorf("Address %s is not an IPv4 address", wr.Address)
+	}
+
+	// Bit N of the bitmap, counting from the most significant bit of the
+	// first octet, represents port N
+	var bitmap []byte
+	for _, port := range wr.Ports {
+		octet := int(port / 8)
+		for len(bitmap) <= octet {
+			bitmap = append(bitmap, 0x00)
+		}
+		bitmap[octet] |= 0x80 >> (port % 8)
+	}
+
+	rData := append(append([]byte{}, v4...), wr.Protocol)
+	rData = append(rData, bitmap...)
+	return rData, nil
+}
+
+func (wr WKSRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newWKSRecordFromRawRR(rd.rrr)
+}
+
+func (wr WKSRecord) Equal(owr DNSResourceRecord) (bool, []string) {
+	other := owr.(WKSRecord)
+	same, reasons := wr.Common.equal(other.Common)
+	if !wr.Address.Equal(other.Address) {
+		same = false
+		reason := fmt.Sprintf("Address: %s != %s", wr.Address, other.Address)
+		reasons = append(reasons, reason)
+	}
+	if wr.Protocol != other.Protocol {
+		same = false
+		reason := fmt.Sprintf("Protocol: %d != %d", wr.Protocol, other.Protocol)
+		reasons = append(reasons, reason)
+	}
+	if !reflect.DeepEqual(wksPortSet(wr.Ports), wksPortSet(other.Ports)) {
+		same = false
+		reason := fmt.Sprintf("Ports: %v != %v", wr.Ports, other.Ports)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// wksPortSet returns ports sorted and without duplicates, as they would be
+// after a trip through the wire bitmap.
+func wksPortSet(ports []uint16) []uint16 {
+	set := append([]uint16{}, ports...)
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	unique := set[:0]
+	for _, port := range set {
+		if len(unique) == 0 || port != unique[len(unique)-1] {
+			unique = append(unique, port)
+		}
+	}
+	return unique
+}
+
+// RPRecord names the person responsible for its owner; see RFC 1183
+// section 2.2. Mailbox is a domain name encoding an email address (the first
+// label is the local part), and TXTDomain names a TXT record with further
+// information. Either may be "" (the root) if not available.
+type RPRecord struct {
+	Common    ResourceRecordCommon
+	Mailbox   string
+	TXTDomain string
+}
+
+func (rr RPRecord) GetCommon() ResourceRecordCommon {
+	return rr.Common
+}
+
+func (rr RPRecord) PackRData() ([]byte, error) {
+	rData := domain(rr.Mailbox).toRawLabels().toBytes()
+	rData = append(rData, domain(rr.TXTDomain).toRawLabels().toBytes()...)
+	return rData, nil
+}
+
+func (rr RPRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newRPRecordFromRawRR(rd.rrr)
+}
+
+func (rr RPRecord) Equal(orr DNSResourceRecord) (bool, []string) {
+	other := orr.(RPRecord)
+	same, reasons := rr.Common.equal(other.Common)
+	if rr.Mailbox != other.Mailbox {
+		same = false
+		reason := fmt.Sprintf("Mailbox: %q != %q", rr.Mailbox, other.Mailbox)
+		reasons = append(reasons, reason)
+	}
+	if rr.TXTDomain != other.TXTDomain {
+		same = false
+		reason := fmt.Sprintf("TXTDomain: %q != %q", rr.TXTDomain, other.TXTDomain)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// AFSDBRecord locates an AFS cell database server (Subtype 1) or a DCE
+// authenticated name server (Subtype 2); see RFC 1183 section 1.
+type AFSDBRecord struct {
+	Common   ResourceRecordCommon
+	Subtype  uint16
+	Hostname string
+}
+
+func (ar AFSDBRecord) GetCommon() ResourceRecordCommon {
+	return ar.Common
+}
+
+func (ar AFSDBRecord) PackRData() ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptBinaryWrite(binary.BigEndian, ar.Subtype)
+	bwa.attemptWrite(domain(ar.Hostname).toRawLabels().toBytes())
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (ar AFSDBRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newAFSDBRecordFromRawRR(rd.rrr)
+}
+
+func (ar AFSDBRecord) Equal(oar DNSResourceRecord) (bool, []string) {
+	other := oar.(AFSDBRecord)
+	same, reasons := ar.Common.equal(other.Common)
+	if ar.Subtype != other.Subtype {
+		same = false
+		reason := fmt.Sprintf("Subtype: %d != %d", ar.Subtype, other.Subtype)
+		reasons = append(reasons, reason)
+	}
+	if ar.Hostname != other.Hostname {
+		same = false
+		reason := fmt.Sprintf("Hostname: %q != %q", ar.Hostname, other.Hostname)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// X25Record maps its owner to an X.121 Public Switched Data Network address,
+// e.g. "311061700956"; see RFC 1183 section 3.1.
+type X25Record struct {
+	Common      ResourceRecordCommon
+	PSDNAddress string
+}
+
+func (xr X25Record) GetCommon() ResourceRecordCommon {
+	return xr.Common
+}
+
+func (xr X25Record) PackRData() ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptWriteCharacterString(xr.PSDNAddress)
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (xr X25Record) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newX25RecordFromRawRR(rd.rrr)
+}
+
+func (xr X25Record) Equal(oxr DNSResourceRecord) (bool, []string) {
+	other := oxr.(X25Record)
+	same, reasons := xr.Common.equal(other.Common)
+	if xr.PSDNAddress != other.PSDNAddress {
+		same = false
+		reason := fmt.Sprintf("PSDNAddress: %q != %q", xr.PSDNAddress, other.PSDNAddress)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// NSAPPTRRecord is the obsolete NSAP-PTR record, which maps an NSAP address
+// to a domain name in the same way as PTRRecord; see RFC 1348.
+type NSAPPTRRecord struct {
+	Common   ResourceRecordCommon
+	PtrDName string
+}
+
+func (npr NSAPPTRRecord) GetCommon() ResourceRecordCommon {
+	return npr.Common
+}
+
+func (npr NSAPPTRRecord) PackRData() ([]byte, error) {
+	return domain(npr.PtrDName).toRawLabels().toBytes(), nil
+}
+
+func (npr NSAPPTRRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newNSAPPTRRecordFromRawRR(rd.rrr)
+}
+
+func (npr NSAPPTRRecord) Equal(onpr DNSResourceRecord) (bool, []string) {
+	other := onpr.(NSAPPTRRecord)
+	same, reasons := npr.Common.equal(other.Common)
+	if npr.PtrDName != other.PtrDName {
+		same = false
+		reason := fmt.Sprintf("PtrDName: %q != %q", npr.PtrDName, other.PtrDName)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// EUI48Record publishes a 48-bit Extended Unique Identifier, such as an
+// Ethernet MAC address; see RFC 7043.
+type EUI48Record struct {
+	Common  ResourceRecordCommon
+	Address net.HardwareAddr
+}
+
+func (er EUI48Record) GetCommon() ResourceRecordCommon {
+	return er.Common
+}
+
+func (er EUI48Record) PackRData() ([]byte, error) {
+	if len(er.Address) != 6 {
+		return nil, fmt.Errorf("Address %s is not a 48-bit EUI", er.Address)
+	}
+	return append([]byte{}, er.Address...), nil
+}
+
+func (er EUI48Record) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newEUI48RecordFromRawRR(rd.rrr)
+}
+
+func (er EUI48Record) Equal(oer DNSResourceRecord) (bool, []string) {
+	other := oer.(EUI48Record)
+	same, reasons := er.Common.equal(other.Common)
+	if !bytes.Equal(er.Address, other.Address) {
+		same = false
+		reason := fmt.Sprintf("Address: %s != %s", er.Address, other.Address)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// EUI64Record publishes a 64-bit Extended Unique Identifier; see RFC 7043.
+type EUI64Record struct {
+	Common  ResourceRecordCommon
+	Address net.HardwareAddr
+}
+
+func (er EUI64Record) GetCommon() ResourceRecordCommon {
+	return er.Common
+}
+
+func (er EUI64Record) PackRData() ([]byte, error) {
+	if len(er.Address) != 8 {
+		return nil, fmt.Errorf("Address %s is not a 64-bit EUI", er.Address)
+	}
+	return append([]byte{}, er.Address...), nil
+}
+
+func (er EUI64Record) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newEUI64RecordFromRawRR(rd.rrr)
+}
+
+func (er EUI64Record) Equal(oer DNSResourceRecord) (bool, []string) {
+	other := oer.(EUI64Record)
+	same, reasons := er.Common.equal(other.Common)
+	if !bytes.Equal(er.Address, other.Address) {
+		same = false
+		reason := fmt.Sprintf("Address: %s != %s", er.Address, other.Address)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// NIDRecord holds an ILNP Node Identifier; see RFC 6742 section 2.1.
+type NIDRecord struct {
+	Common     ResourceRecordCommon
+	Preference uint16
+	NodeID     uint64
+}
+
+func (nr NIDRecord) GetCommon() ResourceRecordCommon {
+	return nr.Common
+}
+
+func (nr NIDRecord) PackRData() ([]byte, error) {
+	rData := make([]byte, 10)
+	binary.BigEndian.PutUint16(rData[0:2], nr.Preference)
+	binary.BigEndian.PutUint64(rData[2:10], nr.NodeID)
+	return rData, nil
+}
+
+func (nr NIDRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newNIDRecordFromRawRR(rd.rrr)
+}
+
+func (nr NIDRecord) Equal(onr DNSResourceRecord) (bool, []string) {
+	other := onr.(NIDRecord)
+	same, reasons := nr.Common.equal(other.Common)
+	if nr.Preference != other.Preference {
+		same = false
+		reason := fmt.Sprintf("Preference: %d != %d", nr.Preference, other.Preference)
+		reasons = append(reasons, reason)
+	}
+	if nr.NodeID != other.NodeID {
+		same = false
+		reason := fmt.Sprintf("NodeID: %016x != %016x", nr.NodeID, other.NodeID)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// L32Record holds a 32-bit ILNP Locator, written like an IPv4 address; see
+// RFC 6742 section 2.2.
+type L32Record struct {
+	Common     ResourceRecordCommon
+	Preference uint16
+	Locator32  net.IP
+}
+
+func (lr L32Record) GetCommon() ResourceRecordCommon {
+	return lr.Common
+}
+
+func (lr L32Record) PackRData() ([]byte, error) {
+	v4 := lr.Locator32.To4()
+	if v4 == nil {
+		return nil, fmt.Errorf("Locator32 %s is not a 32-bit locator", lr.Locator32)
+	}
+	rData := make([]byte, 6)
+	binary.BigEndian.PutUint16(rData[0:2], lr.Preference)
+	copy(rData[2:6], v4)
+	return rData, nil
+}
+
+func (lr L32Record) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newL32RecordFromRawRR(rd.rrr)
+}
+
+func (lr L32Record) Equal(olr DNSResourceRecord) (bool, []string) {
+	other := olr.(L32Record)
+	same, reasons := lr.Common.equal(other.Common)
+	if lr.Preference != other.Preference {
+		same = false
+		reason := fmt.Sprintf("Preference: %d != %d", lr.Preference, other.Preference)
+		reasons = append(reasons, reason)
+	}
+	if !lr.Locator32.Equal(other.Locator32) {
+		same = false
+		reason := fmt.Sprintf("Locator32: %s != %s", lr.Locator32, other.Locator32)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// L64Record holds a 64-bit ILNP Locator; see RFC 6742 section 2.3.
+type L64Record struct {
+	Common     ResourceRecordCommon
+	Preference uint16
+	Locator64  uint64
+}
+
+func (lr L64Record) GetCommon() ResourceRecordCommon {
+	return lr.Common
+}
+
+func (lr L64Record) PackRData() ([]byte, error) {
+	rData := make([]byte, 10)
+	binary.BigEndian.PutUint16(rData[0:2], lr.Preference)
+	binary.BigEndian.PutUint64(rData[2:10], lr.Locator64)
+	return rData, nil
+}
+
+func (lr L64Record) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newL64RecordFromRawRR(rd.rrr)
+}
+
+func (lr L64Record) Equal(olr DNSResourceRecord) (bool, []string) {
+	other := olr.(L64Record)
+	same, reasons := lr.Common.equal(other.Common)
+	if lr.Preference != other.Preference {
+		same = false
+		reason := fmt.Sprintf("Preference: %d != %d", lr.Preference, other.Preference)
+		reasons = append(reasons, reason)
+	}
+	if lr.Locator64 != other.Locator64 {
+		same = false
+		reason := fmt.Sprintf("Locator64: %016x != %016x", lr.Locator64, other.Locator64)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// LPRecord names a host whose L32/L64 records hold the locators for its
+// owner; see RFC 6742 section 2.4.
+type LPRecord struct {
+	Common     ResourceRecordCommon
+	Preference uint16
+	FQDN       string
+}
+
+func (lr LPRecord) GetCommon() ResourceRecordCommon {
+	return lr.Common
+}
+
+func (lr LPRecord) PackRData() ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	bwa.attemptBinaryWrite(binary.BigEndian, lr.Preference)
+	bwa.attemptWrite(domain(lr.FQDN).toRawLabels().toBytes())
+	if bwa.err != nil {
+		return nil, fmt.Errorf("bufWriteAttempter.err is %s", bwa.err)
+	}
+
+	return bwa.buf.Bytes(), nil
+}
+
+func (lr LPRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newLPRecordFromRawRR(rd.rrr)
+}
+
+func (lr LPRecord) Equal(olr DNSResourceRecord) (bool, []string) {
+	other := olr.(LPRecord)
+	same, reasons := lr.Common.equal(other.Common)
+	if lr.Preference != other.Preference {
+		same = false
+		reason := fmt.Sprintf("Preference: %d != %d", lr.Preference, other.Preference)
+		reasons = append(reasons, reason)
+	}
+	if lr.FQDN != other.FQDN {
+		same = false
+		reason := fmt.Sprintf("FQDN: %q != %q", lr.FQDN, other.FQDN)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// DNAMERecord redirects every name below its owner to the corresponding
+// name below Target; the owner name itself is not redirected. See RFC 6672.
+type DNAMERecord struct {
+	Common ResourceRecordCommon
+	Target string
+}
+
+func (dr DNAMERecord) GetCommon() ResourceRecordCommon {
+	return dr.Common
+}
+
+func (dr DNAMERecord) PackRData() ([]byte, error) {
+	// RFC 6672 section 2.5 forbids compressing the target, which is fine as
+	// we never compress anything
+	return domain(dr.Target).toRawLabels().toBytes(), nil
+}
+
+func (dr DNAMERecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newDNAMERecordFromRawRR(rd.rrr)
+}
+
+func (dr DNAMERecord) Equal(odr DNSResourceRecord) (bool, []string) {
+	other := odr.(DNAMERecord)
+	same, reasons := dr.Common.equal(other.Common)
+	if dr.Target != other.Target {
+		same = false
+		reason := fmt.Sprintf("Target: %q != %q", dr.Target, other.Target)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// DNAMESubstitute returns the name that qname is redirected to by dr, as
+// described in RFC 6672 section 2.2: the owner of dr is stripped from the end
+// of qname and replaced with dr.Target. This is the target of the CNAME a
+// resolver synthesizes when it follows a DNAME.
+//
+// qname must be strictly below the owner of dr, compared case-insensitively.
+// If the substituted name would exceed 255 octets in wire format, an error is
+// returned; a server would answer such a query with YXDOMAIN.
+func DNAMESubstitute(qname string, dr DNAMERecord) (string, error) {
+	qLabels := domain(qname).toRawLabels()
+	ownerLabels := domain(dr.Common.Domain).toRawLabels()
+	if len(qLabels) <= len(ownerLabels) {
+		return "", fmt.Errorf("%q is not below DNAME owner %q", qname, dr.Common.Domain)
+	}
+	prefixLen := len(qLabels) - len(ownerLabels)
+	for i, rl := range ownerLabels {
+		if !strings.EqualFold(rl.content, qLabels[prefixLen+i].content) {
+			return "", fmt.Errorf("%q is not below DNAME owner %q", qname, dr.Common.Domain)
+		}
+	}
+
+	substituted := append(rawLabels{}, qLabels[:prefixLen]...)
+	substituted = append(substituted, domain(dr.Target).toRawLabels()...)
+	if wireLen := len(substituted.toBytes()); wireLen > 255 {
+		return "", fmt.Errorf("substituted name is %d octets, may not exceed 255", wireLen)
+	}
+	return substituted.toDomain(), nil
+}
+
+type OPTRecord struct {
+	Common  ResourceRecordCommon
+	Options map[uint16][]byte
+}
+
+func (or OPTRecord) GetCommon() ResourceRecordCommon {
+	return or.Common
+}
+
+func (or OPTRecord) PackRData() ([]byte, error) {
+	var keys []uint16
+	for key, _ := range or.Options {
+		keys = append(keys, key)
+	}
+	sort.Sort(UInt16Slice(keys))
+	rDataBuf := newBufWriteAttempter()
+	for _, key := range keys {
+		rDataBuf.attemptBinaryWrite(binary.BigEndian, key)
+		optLen := uint16(len(or.Options[key]))
+		rDataBuf.attemptBinaryWrite(binary.BigEndian, optLen)
+		rDataBuf.attemptWrite(or.Options[key])
+	}
+
+	return rDataBuf.buf.Bytes(), rDataBuf.err
+}
+
+func (or OPTRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	return rd.d.newOPTRecordFromRawRR(rd.rrr), nil
+}
+
+func (or OPTRecord) Equal(oor DNSResourceRecord) (bool, []string) {
+	other := oor.(OPTRecord)
+	same, reasons := or.Common.equal(other.Common)
+	if !reflect.DeepEqual(or.Options, other.Options) {
+		same = false
+		reason := fmt.Sprintf("Options: %v != %v", or.Options, other.Options)
+		reasons = append(reasons, reason)
+	}
+	return same, reasons
+}
+
+// UnknownRecord holds a record of any type which has not been registered
+// with RegisterRecordType, with its RDATA kept as opaque bytes (RFC 3597).
+// RFC 3597 section 4 forbids compressing names in the RDATA of types defined
+// after RFC 1035, so such RDATA is kept as it is. The RFC 1035 types whose
+// RDATA holds names, such as CNAME and MX, may be compressed, so their names
+// are decompressed, leaving RDATA which means the same outside the message.
+type UnknownRecord struct {
+	Common ResourceRecordCommon
+	RData  []byte
+}
+
+// rfc1035RDataLayouts describes the RDATA of the RFC 1035 types holding
+// domain names, which UnknownRecord must decompress and CanonicalSort must
+// lower-case: each 0 is a name, and each other value that many bytes of
+// fixed-size fields.
+var rfc1035RDataLayouts = map[RecordType][]int{
+	TypeNS:    {0},
+	TypeMD:    {0},
+	TypeMF:    {0},
+	TypeCNAME: {0},
+	TypeSOA:   {0, 0, 20},
+	TypeMB:    {0},
+	TypeMG:    {0},
+	TypeMR:    {0},
+	TypeMINFO: {0, 0},
+	TypeMX:    {2, 0},
+}
+
+func (ur UnknownRecord) GetCommon() ResourceRecordCommon {
+	return ur.Common
+}
+
+func (ur UnknownRecord) PackRData() ([]byte, error) {
+	return ur.RData, nil
+}
+
+func (ur UnknownRecord) UnpackRData(rd RData) (DNSResourceRecord, error) {
+	u := UnknownRecord{Common: rd.Common()}
+	layout, found := rfc1035RDataLayouts[u.Common.Type]
+	if !found {
+		u.RData = make([]byte, len(rd.Bytes()))
+		copy(u.RData, rd.Bytes())
+		return u, nil
+	}
+
+	rdata := rd.Bytes()
+	off := 0
+	for _, size := range layout {
+		if size > 0 {
+			if off+size > len(rdata) {
+				return u, fmt.Errorf("%s RDATA too short (%d bytes)", u.Common.Type, len(rdata))
+			}
+			u.RData = append(u.RData, rdata[off:off+size]...)
+			off += size
+			continue
+		}
+		name, n, err := rd.ReadName(off)
+		if err != nil {
+			return u, err
+		}
+		u.RData = append(u.RData, PackName(name)...)
+		off += n
+	}
+	if off != len(rdata) {
+		return u, fmt.Errorf("%d trailing bytes after %s RDATA", len(rdata)-off, u.Common.Type)
+	}
+	return u, nil
+}
+
+func (ur UnknownRecord) Equal(our DNSResourceRecord) (bool, []string) {
+	other := our.(UnknownRecord)
+	same, reasons := ur.Common.equal(other.Common)
+	if !bytes.Equal(ur.RData, other.RData) {
+		same = false
+		reason := fmt.Sprintf("RData: %x != %x", ur.RData, other.RData)
 		reasons = append(reasons, reason)
 	}
 	return same, reasons
 }
 
+// DNSResourceRecord is implemented by every record type in this package, and
+// by any type registered with RegisterRecordType.
 type DNSResourceRecord interface {
-	toRawDNSResourceRecord() (rawResourceRecord, error)
 	GetCommon() ResourceRecordCommon
 	Equal(rr DNSResourceRecord) (bool, []string)
+	// PackRData returns the wire-format RDATA of the record, uncompressed.
+	PackRData() ([]byte, error)
+	// UnpackRData returns a new record of the same type as the receiver,
+	// decoded from rd; the receiver itself is not modified, so it is
+	// usually the zero value returned by the registered factory.
+	UnpackRData(rd RData) (DNSResourceRecord, error)
 }
 
 type UInt16Slice []uint16
@@ -2,8 +2,16 @@ package rawmdns
 
 import (
 	"bytes"
-	"testing"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
 )
 
 ////// Below cut/pasted from RFC 4034 section 4.3: //////
@@ -25,7 +33,7 @@ import (
 // 0x00 0x00 0x00 0x00 0x00 0x00 0x00 0x00
 // 0x00 0x00 0x00 0x00 0x00 0x00 0x00 0x00
 // 0x00 0x00 0x00 0x00 0x20
-func TestNSECRecord_toRawDNSResourceRecord(t *testing.T) {
+func TestNSECRecord_PackRData(t *testing.T) {
 	expectedRData := []byte{
 		0x04, 'h', 'o', 's', 't',
 		0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e',
@@ -49,16 +57,16 @@ func TestNSECRecord_toRawDNSResourceRecord(t *testing.T) {
 		NextDomainTypes: []RecordType{TypeA, TypeMX, TypeRRSIG, TypeNSEC, 1234},
 	}
 
-	rdrr, err := nsr.toRawDNSResourceRecord()
+	rData, err := nsr.PackRData()
 	if err != nil {
-		t.Errorf("Unexpected error from toRawDNSResourceRecord: %s", err)
+		t.Errorf("Unexpected error from PackRData: %s", err)
 	}
-	if !bytes.Equal(expectedRData, rdrr.rData) {
-		t.Error("expectedRData != rdrr.rData")
+	if !bytes.Equal(expectedRData, rData) {
+		t.Error("expectedRData != rData")
 	}
 }
 
-func TestOPTRecord_toRawDNSResourceRecord(t *testing.T) {
+func TestOPTRecord_PackRData(t *testing.T) {
 	/* This expected data was pulled from a packet cap. The only parts we're
 	actually interested are the leading 4 bytes: the code and the length.
 	The rest is left in here because it might somehow be interesting, but
@@ -83,16 +91,16 @@ func TestOPTRecord_toRawDNSResourceRecord(t *testing.T) {
 		},
 	}
 
-	rdrr, err := or.toRawDNSResourceRecord()
+	rData, err := or.PackRData()
 	if err != nil {
-		t.Errorf("Unexpected error from toRawDNSResourceRecord: %s", err)
+		t.Errorf("Unexpected error from PackRData: %s", err)
 	}
-	if !bytes.Equal(expectedRData, rdrr.rData) {
-		t.Error("expectedRData != rdrr.rData")
+	if !bytes.Equal(expectedRData, rData) {
+		t.Error("expectedRData != rData")
 	}
 }
 
-func TestTXTRecord_toRawDNSResourceRecord(t *testing.T) {
+func TestTXTRecord_PackRData(t *testing.T) {
 	expectedRData := []byte{
 		0x03, 0x30, 0x3d, 0x31, 0x03, 0x61, 0x3d, 0x62,
 	}
@@ -102,12 +110,12 @@ func TestTXTRecord_toRawDNSResourceRecord(t *testing.T) {
 			"a=b",
 		},
 	}
-	rdrr, err := tr.toRawDNSResourceRecord()
+	rData, err := tr.PackRData()
 	if err != nil {
-		t.Errorf("Unexpected error from toRawDNSResourceRecord: %s", err)
+		t.Errorf("Unexpected error from PackRData: %s", err)
 	}
-	if !bytes.Equal(expectedRData, rdrr.rData) {
-		t.Error("expectedRData != rdrr.rData")
+	if !bytes.Equal(expectedRData, rData) {
+		t.Error("expectedRData != rData")
 	}
 }
 
@@ -354,3 +362,1407 @@ func TestNSECRecord_roundtrip(t *testing.T) {
 		}
 	}
 }
+
+func TestNSEC3Record_roundtrip(t *testing.T) {
+	n := NSEC3Record{
+		Common: ResourceRecordCommon{
+			Domain:     "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example",
+			Type:       TypeNSEC3,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		HashAlgorithm:   NSEC3HashSHA1,
+		Flags:           NSEC3FlagOptOut,
+		Iterations:      12,
+		Salt:            []byte{0xaa, 0xbb, 0xcc, 0xdd},
+		NextHashedOwner: "2t7b4g4vsa5smi47k61mv5bv1a22bojr",
+		Types:           []RecordType{TypeNS, TypeSOA, TypeMX, TypeRRSIG, TypeDNSKEY, TypeNSEC3PARAM},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			n,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	n2 := dm2.Answers[0].(NSEC3Record)
+	same, reasons := n.Equal(n2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestNSEC3PARAMRecord_roundtrip(t *testing.T) {
+	n := NSEC3PARAMRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "example",
+			Type:       TypeNSEC3PARAM,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		HashAlgorithm: NSEC3HashSHA1,
+		Flags:         0,
+		Iterations:    12,
+		Salt:          []byte{0xaa, 0xbb, 0xcc, 0xdd},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			n,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	n2 := dm2.Answers[0].(NSEC3PARAMRecord)
+	same, reasons := n.Equal(n2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+// Expected values below are from the example zone in RFC 5155 appendix A.
+func TestNSEC3HashedOwnerName(t *testing.T) {
+	salt := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	testCases := map[string]string{
+		"example":       "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example",
+		"a.example":     "35mthgpgcu1qg68fab165klnsnk3dpvl.example",
+		"ai.example":    "gjeqe526plbf1g8mklp59enfd789njgi.example",
+		"ns1.example":   "2t7b4g4vsa5smi47k61mv5bv1a22bojr.example",
+		"*.w.example":   "r53bq7cc2uvmubfu5ocmm6pers9tk9en.example",
+		"xx.example":    "t644ebqk9bibcna874givr6joj62mlhv.example",
+		"X.W.Example.":  "b4um86eghhds6nea196smvmlo4ors995.example",
+		"x.y.w.example": "2vptu5timamqttgl4luu9kg21e0aor3s.example",
+	}
+	for name, expected := range testCases {
+		hashed, err := NSEC3HashedOwnerName(name, "example", NSEC3HashSHA1, 12, salt)
+		if err != nil {
+			t.Fatalf("Unexpected error from NSEC3HashedOwnerName(%q): %s", name, err)
+		}
+		if hashed != expected {
+			t.Errorf("NSEC3HashedOwnerName(%q): %q != %q", name, hashed, expected)
+		}
+	}
+}
+
+func TestSVCBRecord_roundtrip(t *testing.T) {
+	s := SVCBRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "_printer._tcp.local",
+			Type:       TypeSVCB,
+			Class:      ClassINET,
+			CacheFlush: true,
+			TTL:        120,
+		},
+		Priority: 1,
+		Target:   "printer.local",
+		Params: []SvcParam{
+			SvcMandatory{Keys: []SvcParamKey{SvcParamKeyALPN, SvcParamKeyPort}},
+			SvcALPN{IDs: []string{"h2", "h3"}},
+			SvcNoDefaultALPN{},
+			SvcPort{Port: 631},
+			SvcIPv4Hint{Addrs: []net.IP{net.ParseIP("10.0.0.5"), net.ParseIP("10.0.0.6")}},
+			SvcECH{Config: []byte{0x00, 0x04, 0xfe, 0x0d, 0x00, 0x00}},
+			SvcIPv6Hint{Addrs: []net.IP{net.ParseIP("2001:db8::5")}},
+			SvcUnknown{Code: 667, Value: []byte("hello")},
+		},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			s,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	s2 := dm2.Answers[0].(SVCBRecord)
+	same, reasons := s.Equal(s2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestHTTPSRecord_roundtrip(t *testing.T) {
+	h := HTTPSRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "printer.local",
+			Type:       TypeHTTPS,
+			Class:      ClassINET,
+			CacheFlush: true,
+			TTL:        120,
+		},
+		Priority: 1,
+		Target:   ".",
+		Params: []SvcParam{
+			SvcALPN{IDs: []string{"h3"}},
+			SvcPort{Port: 8443},
+		},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			h,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	h2 := dm2.Answers[0].(HTTPSRecord)
+	same, reasons := h.Equal(h2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestNAPTRRecord_roundtrip(t *testing.T) {
+	n := NAPTRRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "4.3.2.1.5.5.5.0.0.8.1.e164.arpa",
+			Type:       TypeNAPTR,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Order:      100,
+		Preference: 10,
+		Flags:      "u",
+		Service:    "E2U+sip",
+		Regexp:     "!^.*$!sip:information@foo.se!i",
+	}
+	// the root is the usual Replacement, which decodes as ""
+	for _, replacement := range []string{"", ".", "sip.example.com."} {
+		n.Replacement = replacement
+		checkNAPTRRoundtrip(t, n)
+	}
+}
+
+func checkNAPTRRoundtrip(t *testing.T, n NAPTRRecord) {
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			n,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	n2 := dm2.Answers[0].(NAPTRRecord)
+	same, reasons := n.Equal(n2)
+	if !same {
+		t.Errorf("Replacement %q: before/after not the same:", n.Replacement)
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestURIRecord_roundtrip(t *testing.T) {
+	u := URIRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "_ftp._tcp.example.com",
+			Type:       TypeURI,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Priority: 10,
+		Weight:   1,
+		Target:   "ftp://ftp1.example.com/public",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			u,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	u2 := dm2.Answers[0].(URIRecord)
+	same, reasons := u.Equal(u2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestSSHFPRecord_roundtrip(t *testing.T) {
+	s := SSHFPRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "printer.local",
+			Type:       TypeSSHFP,
+			Class:      ClassINET,
+			CacheFlush: true,
+			TTL:        120,
+		},
+		Algorithm:       SSHFPAlgorithmEd25519,
+		FingerprintType: SSHFPTypeSHA256,
+		Fingerprint: []byte{
+			0x2f, 0x6d, 0x1b, 0x5c, 0x4e, 0x11, 0x0a, 0x9b, 0x73, 0x40, 0x8c, 0x2e, 0x1f, 0x5b, 0xd6, 0x37,
+			0x91, 0x0e, 0x44, 0xa2, 0x6d, 0xc8, 0x3b, 0x55, 0x09, 0xe1, 0x7a, 0x0c, 0x64, 0xbe, 0x23, 0xf8,
+		},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			s,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	s2 := dm2.Answers[0].(SSHFPRecord)
+	same, reasons := s.Equal(s2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestTLSARecord_roundtrip(t *testing.T) {
+	tr := TLSARecord{
+		Common: ResourceRecordCommon{
+			Domain:     "_443._tcp.printer.local",
+			Type:       TypeTLSA,
+			Class:      ClassINET,
+			CacheFlush: true,
+			TTL:        120,
+		},
+		Usage:        TLSAUsageDANEEE,
+		Selector:     TLSASelectorSPKI,
+		MatchingType: TLSAMatchingSHA256,
+		Data: []byte{
+			0x0c, 0x72, 0xac, 0x70, 0xb7, 0x45, 0xac, 0x19, 0x99, 0x8e, 0x5c, 0x12, 0x3a, 0x6a, 0x3c, 0x5d,
+			0x0d, 0x4e, 0x61, 0x88, 0x2b, 0x1a, 0x33, 0x44, 0x2c, 0x2d, 0x11, 0x9e, 0xb6, 0x7d, 0x4b, 0xfe,
+		},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			tr,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	tr2 := dm2.Answers[0].(TLSARecord)
+	same, reasons := tr.Equal(tr2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestSMIMEARecord_roundtrip(t *testing.T) {
+	s := SMIMEARecord{
+		Common: ResourceRecordCommon{
+			Domain:     "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._smimecert.example.com",
+			Type:       TypeSMIMEA,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Usage:        TLSAUsageDANEEE,
+		Selector:     TLSASelectorCert,
+		MatchingType: TLSAMatchingFull,
+		Data:         []byte{0x30, 0x82, 0x01, 0x0a, 0x02, 0x82, 0x01, 0x01},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			s,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	s2 := dm2.Answers[0].(SMIMEARecord)
+	same, reasons := s.Equal(s2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestOPENPGPKEYRecord_roundtrip(t *testing.T) {
+	o := OPENPGPKEYRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._openpgpkey.example.com",
+			Type:       TypeOPENPGPKEY,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		PublicKey: []byte{0x98, 0x33, 0x04, 0x5a, 0x1b, 0x2c, 0x3d, 0x16, 0x09, 0x2b},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			o,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	o2 := dm2.Answers[0].(OPENPGPKEYRecord)
+	same, reasons := o.Equal(o2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestSSHFPFingerprint(t *testing.T) {
+	// An ssh-ed25519 public key blob: string "ssh-ed25519", string key
+	pub := bytes.Repeat([]byte{0x42}, 32)
+	var blob []byte
+	blob = append(blob, 0x00, 0x00, 0x00, 0x0b)
+	blob = append(blob, []byte("ssh-ed25519")...)
+	blob = append(blob, 0x00, 0x00, 0x00, 0x20)
+	blob = append(blob, pub...)
+
+	alg, fp, err := SSHFPFingerprint(blob, SSHFPTypeSHA256)
+	if err != nil {
+		t.Fatalf("Unexpected error from SSHFPFingerprint: %s", err)
+	}
+	if alg != SSHFPAlgorithmEd25519 {
+		t.Errorf("alg: %d != %d", alg, SSHFPAlgorithmEd25519)
+	}
+	expected := sha256.Sum256(blob)
+	if !bytes.Equal(fp, expected[:]) {
+		t.Errorf("fp: %x != %x", fp, expected)
+	}
+
+	_, _, err = SSHFPFingerprint(blob, 99)
+	if err == nil {
+		t.Error("Expected error for unknown fingerprint type, got none")
+	}
+}
+
+func TestTLSAAssociation(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rnd)
+	if err != nil {
+		t.Fatalf("Unexpected error from ed25519.GenerateKey: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "printer.local"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rnd, template, template, pub, priv)
+	if err != nil {
+		t.Fatalf("Unexpected error from x509.CreateCertificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Unexpected error from x509.ParseCertificate: %s", err)
+	}
+
+	full, err := TLSAAssociation(cert, TLSASelectorCert, TLSAMatchingFull)
+	if err != nil {
+		t.Fatalf("Unexpected error from TLSAAssociation: %s", err)
+	}
+	if !bytes.Equal(full, der) {
+		t.Error("full certificate association != DER certificate")
+	}
+
+	spkiHash, err := TLSAAssociation(cert, TLSASelectorSPKI, TLSAMatchingSHA256)
+	if err != nil {
+		t.Fatalf("Unexpected error from TLSAAssociation: %s", err)
+	}
+	expected := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	if !bytes.Equal(spkiHash, expected[:]) {
+		t.Errorf("spkiHash: %x != %x", spkiHash, expected)
+	}
+}
+
+func TestCAARecord_roundtrip(t *testing.T) {
+	c := CAARecord{
+		Common: ResourceRecordCommon{
+			Domain:     "example.com",
+			Type:       TypeCAA,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Flags: CAAFlagIssuerCritical,
+		Tag:   "issue",
+		Value: []byte("ca.example.net; account=230123"),
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			c,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	c2 := dm2.Answers[0].(CAARecord)
+	same, reasons := c.Equal(c2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestCAARecord_PackRData_badTag(t *testing.T) {
+	for _, tag := range []string{"", "issue-wild", "thistagiswaytoolong"} {
+		c := CAARecord{
+			Common: ResourceRecordCommon{
+				Domain: "example.com",
+				Type:   TypeCAA,
+				Class:  ClassINET,
+			},
+			Tag:   tag,
+			Value: []byte("ca.example.net"),
+		}
+		_, err := c.PackRData()
+		if err == nil {
+			t.Errorf("Expected error for tag %q, got none", tag)
+		}
+	}
+}
+
+func TestParseCAAIssueValue(t *testing.T) {
+	iv, err := ParseCAAIssueValue([]byte(" ca.example.net ;account=230123; policy = ev "))
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseCAAIssueValue: %s", err)
+	}
+	expected := CAAIssueValue{
+		IssuerDomainName: "ca.example.net",
+		Parameters: []CAAParameter{
+			{Tag: "account", Value: "230123"},
+			{Tag: "policy", Value: "ev"},
+		},
+	}
+	if !reflect.DeepEqual(iv, expected) {
+		t.Errorf("%+v != %+v", iv, expected)
+	}
+
+	iv, err = ParseCAAIssueValue([]byte(";"))
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseCAAIssueValue: %s", err)
+	}
+	if iv.IssuerDomainName != "" || len(iv.Parameters) != 0 {
+		t.Errorf("Expected empty issue value, got %+v", iv)
+	}
+
+	for _, bad := range []string{"-ca.example.net", "ca.example.net; account", "ca.example.net; ; a=b", "ca..example.net"} {
+		_, err = ParseCAAIssueValue([]byte(bad))
+		if err == nil {
+			t.Errorf("Expected error for %q, got none", bad)
+		}
+	}
+}
+
+func TestLOCRecord_roundtrip(t *testing.T) {
+	l := LOCRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "printer.lab.local",
+			Type:       TypeLOC,
+			Class:      ClassINET,
+			CacheFlush: true,
+			TTL:        120,
+		},
+		Version:   0,
+		Size:      0x12,
+		HorizPre:  0x16,
+		VertPre:   0x13,
+		Latitude:  52.37305556,
+		Longitude: 4.89222222,
+		Altitude:  -2,
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			l,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	l2 := dm2.Answers[0].(LOCRecord)
+	same, reasons := l.Equal(l2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestCERTRecord_roundtrip(t *testing.T) {
+	c := CERTRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "printer.lab.example",
+			Type:       TypeCERT,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		CertType:    1,
+		KeyTag:      12345,
+		Algorithm:   8,
+		Certificate: []byte{0x30, 0x82, 0x03, 0x0d, 0x30, 0x82, 0x01, 0xf5},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			c,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	c2 := dm2.Answers[0].(CERTRecord)
+	same, reasons := c.Equal(c2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestIPSECKEYRecord_roundtrip(t *testing.T) {
+	i := IPSECKEYRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "38.2.0.192.in-addr.arpa",
+			Type:       TypeIPSECKEY,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Precedence:  10,
+		GatewayType: IPSECKEYGatewayIPv4,
+		Algorithm:   2,
+		GatewayAddr: net.ParseIP("192.0.2.38"),
+		PublicKey:   []byte{0x01, 0x03, 0x51, 0x53, 0x79, 0x86, 0xed, 0x35},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			i,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	i2 := dm2.Answers[0].(IPSECKEYRecord)
+	same, reasons := i.Equal(i2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestIPSECKEYRecord_gatewayTypes(t *testing.T) {
+	testCases := []IPSECKEYRecord{
+		{GatewayType: IPSECKEYGatewayNone},
+		{GatewayType: IPSECKEYGatewayIPv6, GatewayAddr: net.ParseIP("2001:db8:0:8002::2000:1")},
+		{GatewayType: IPSECKEYGatewayDomain, GatewayDomain: "mygateway.example.com"},
+	}
+	for _, i := range testCases {
+		i.Common = ResourceRecordCommon{
+			Domain: "38.2.0.192.in-addr.arpa",
+			Type:   TypeIPSECKEY,
+			Class:  ClassINET,
+			TTL:    7200,
+		}
+		i.Precedence = 10
+		i.Algorithm = 2
+		i.PublicKey = []byte{0x01, 0x03, 0x51, 0x53, 0x79, 0x86, 0xed, 0x35}
+		dm := DNSMessage{
+			Hdr: DNSHeader{
+				NumAnswers: 1,
+			},
+			Answers: []DNSResourceRecord{
+				i,
+			},
+		}
+
+		b, err := dm.ToBytes()
+		if err != nil {
+			t.Fatalf("GatewayType %d: Unexpected error from dm.ToBytes: %s", i.GatewayType, err)
+		}
+
+		decoder := NewDecoder(bytes.NewReader(b))
+		dm2, err := decoder.DecodeDNSMessage()
+		if err != nil {
+			t.Fatalf("GatewayType %d: Unexpected error from DecodeDNSMessage: %s", i.GatewayType, err)
+		}
+		i2 := dm2.Answers[0].(IPSECKEYRecord)
+		same, reasons := i.Equal(i2)
+		if !same {
+			t.Errorf("GatewayType %d: Before/after not the same:", i.GatewayType)
+			for _, reason := range reasons {
+				t.Log(reason)
+			}
+		}
+	}
+}
+
+func TestKXRecord_roundtrip(t *testing.T) {
+	k := KXRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "lab.example",
+			Type:       TypeKX,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Preference: 10,
+		Exchanger:  "kx.lab.example",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			k,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	k2 := dm2.Answers[0].(KXRecord)
+	same, reasons := k.Equal(k2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestWKSRecord_roundtrip(t *testing.T) {
+	w := WKSRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "oldhost.lab.example",
+			Type:       TypeWKS,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Address:  net.ParseIP("10.0.0.9"),
+		Protocol: 6,
+		// out of order, with a duplicate: the bitmap holds a set
+		Ports: []uint16{513, 21, 23, 25, 79, 25},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			w,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	w2 := dm2.Answers[0].(WKSRecord)
+	same, reasons := w.Equal(w2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestWKSRecord_PackRData(t *testing.T) {
+	// Ports 0, 9 and 15: octet 0 bit 0, octet 1 bits 1 and 7
+	expectedRData := []byte{10, 0, 0, 9, 17, 0x80, 0x41}
+	w := WKSRecord{
+		Address:  net.ParseIP("10.0.0.9"),
+		Protocol: 17,
+		Ports:    []uint16{0, 9, 15},
+	}
+	rData, err := w.PackRData()
+	if err != nil {
+		t.Errorf("Unexpected error from PackRData: %s", err)
+	}
+	if !bytes.Equal(expectedRData, rData) {
+		t.Errorf("expectedRData != rData: % x", rData)
+	}
+}
+
+func TestRPRecord_roundtrip(t *testing.T) {
+	r := RPRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "lab.example",
+			Type:       TypeRP,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Mailbox:   "admin.lab.example",
+		TXTDomain: "contact.lab.example",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			r,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	r2 := dm2.Answers[0].(RPRecord)
+	same, reasons := r.Equal(r2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestAFSDBRecord_roundtrip(t *testing.T) {
+	a := AFSDBRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "lab.example",
+			Type:       TypeAFSDB,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Subtype:  1,
+		Hostname: "afsdb1.lab.example",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			a,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	a2 := dm2.Answers[0].(AFSDBRecord)
+	same, reasons := a.Equal(a2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestX25Record_roundtrip(t *testing.T) {
+	x := X25Record{
+		Common: ResourceRecordCommon{
+			Domain:     "relay.lab.example",
+			Type:       TypeX25,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		PSDNAddress: "311061700956",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			x,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	x2 := dm2.Answers[0].(X25Record)
+	same, reasons := x.Equal(x2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestNSAPPTRRecord_roundtrip(t *testing.T) {
+	n := NSAPPTRRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "foo.lab.example",
+			Type:       TypeNSAPPTR,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		PtrDName: "bar.lab.example",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			n,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	n2 := dm2.Answers[0].(NSAPPTRRecord)
+	same, reasons := n.Equal(n2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestEUI48Record_roundtrip(t *testing.T) {
+	e := EUI48Record{
+		Common: ResourceRecordCommon{
+			Domain:     "printer.local",
+			Type:       TypeEUI48,
+			Class:      ClassINET,
+			CacheFlush: true,
+			TTL:        120,
+		},
+		Address: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			e,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	e2 := dm2.Answers[0].(EUI48Record)
+	same, reasons := e.Equal(e2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestEUI64Record_roundtrip(t *testing.T) {
+	e := EUI64Record{
+		Common: ResourceRecordCommon{
+			Domain:     "sensor.local",
+			Type:       TypeEUI64,
+			Class:      ClassINET,
+			CacheFlush: true,
+			TTL:        120,
+		},
+		Address: net.HardwareAddr{0x00, 0x11, 0x22, 0xff, 0xfe, 0x33, 0x44, 0x55},
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			e,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	e2 := dm2.Answers[0].(EUI64Record)
+	same, reasons := e.Equal(e2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestNIDRecord_roundtrip(t *testing.T) {
+	n := NIDRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "host.lab.example",
+			Type:       TypeNID,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Preference: 10,
+		NodeID:     0x0014_4fff_ff20_ee64,
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			n,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	n2 := dm2.Answers[0].(NIDRecord)
+	same, reasons := n.Equal(n2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestL32Record_roundtrip(t *testing.T) {
+	l := L32Record{
+		Common: ResourceRecordCommon{
+			Domain:     "host.lab.example",
+			Type:       TypeL32,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Preference: 10,
+		Locator32:  net.ParseIP("10.1.2.0"),
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			l,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	l2 := dm2.Answers[0].(L32Record)
+	same, reasons := l.Equal(l2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestL64Record_roundtrip(t *testing.T) {
+	l := L64Record{
+		Common: ResourceRecordCommon{
+			Domain:     "host.lab.example",
+			Type:       TypeL64,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Preference: 10,
+		Locator64:  0x2001_0db8_1140_1000,
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			l,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	l2 := dm2.Answers[0].(L64Record)
+	same, reasons := l.Equal(l2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestLPRecord_roundtrip(t *testing.T) {
+	l := LPRecord{
+		Common: ResourceRecordCommon{
+			Domain:     "host.lab.example",
+			Type:       TypeLP,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Preference: 10,
+		FQDN:       "l64-subnet1.lab.example",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			l,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	l2 := dm2.Answers[0].(LPRecord)
+	same, reasons := l.Equal(l2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestDNAMERecord_roundtrip(t *testing.T) {
+	dn := DNAMERecord{
+		Common: ResourceRecordCommon{
+			Domain:     "corp.internal",
+			Type:       TypeDNAME,
+			Class:      ClassINET,
+			CacheFlush: false,
+			TTL:        3600,
+		},
+		Target: "corp.example.com",
+	}
+	dm := DNSMessage{
+		Hdr: DNSHeader{
+			NumAnswers: 1,
+		},
+		Answers: []DNSResourceRecord{
+			dn,
+		},
+	}
+
+	b, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(b))
+	dm2, err := decoder.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error from DecodeDNSMessage: %s", err)
+	}
+	dn2 := dm2.Answers[0].(DNAMERecord)
+	same, reasons := dn.Equal(dn2)
+	if !same {
+		t.Error("Before/after not the same:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+}
+
+func TestDNAMESubstitute(t *testing.T) {
+	dr := DNAMERecord{
+		Common: ResourceRecordCommon{Domain: "Corp.Internal", Type: TypeDNAME, Class: ClassINET},
+		Target: "corp.example.com",
+	}
+	testCases := []struct {
+		qname       string
+		expected    string
+		expectedErr bool
+	}{
+		{"www.corp.internal", "www.corp.example.com", false},
+		{"a.b.CORP.internal.", "a.b.corp.example.com", false},
+		{"corp.internal", "", true},
+		{"www.other.internal", "", true},
+		{"internal", "", true},
+	}
+	for _, tc := range testCases {
+		result, err := DNAMESubstitute(tc.qname, dr)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", tc.qname, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: Unexpected error: %s", tc.qname, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("%q: %q != %q", tc.qname, result, tc.expected)
+		}
+	}
+
+	// qname is exactly 255 octets, which no longer fits once the longer
+	// target is substituted in
+	label := strings.Repeat("x", 63)
+	qname := label + "." + label + "." + label + "." + strings.Repeat("y", 47) + ".corp.internal"
+	if len(domain(qname).toRawLabels().toBytes()) != 255 {
+		t.Fatal("test qname is not 255 octets")
+	}
+	if _, err := DNAMESubstitute(qname, dr); err == nil {
+		t.Error("Expected error for a substitution longer than 255 octets, got none")
+	}
+}
@@ -0,0 +1,273 @@
+package rawmdns
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RRset is a group of records which share an owner name, type and class; see
+// RFC 2181 section 5. Domain keeps the case of the first record grouped into
+// the set.
+type RRset struct {
+	Domain  string
+	Type    RecordType
+	Class   RecordClass
+	Records []DNSResourceRecord
+}
+
+type rrsetKey struct {
+	domain string
+	rtype  RecordType
+	class  RecordClass
+}
+
+func rrsetKeyFor(common ResourceRecordCommon) rrsetKey {
+	return rrsetKey{
+		domain: strings.ToLower(strings.TrimSuffix(common.Domain, ".")),
+		rtype:  common.Type,
+		class:  common.Class,
+	}
+}
+
+// GroupRRsets groups records into RRsets, comparing owner names
+// case-insensitively. The RRsets are returned in the order their first record
+// appears in rrs, and records keep their relative order within each RRset.
+func GroupRRsets(rrs []DNSResourceRecord) []RRset {
+	var sets []RRset
+	indexes := make(map[rrsetKey]int)
+	for _, rr := range rrs {
+		common := rr.GetCommon()
+		key := rrsetKeyFor(common)
+		i, found := indexes[key]
+		if !found {
+			i = len(sets)
+			indexes[key] = i
+			sets = append(sets, RRset{Domain: common.Domain, Type: common.Type, Class: common.Class})
+		}
+		sets[i].Records = append(sets[i].Records, rr)
+	}
+	return sets
+}
+
+// TTL returns the lowest TTL of any record in the set, or 0 if it is empty.
+func (rs RRset) TTL() uint32 {
+	var ttl uint32
+	for i, rr := range rs.Records {
+		if i == 0 || rr.GetCommon().TTL < ttl {
+			ttl = rr.GetCommon().TTL
+		}
+	}
+	return ttl
+}
+
+// NormalizeTTL returns a copy of rs in which every record has the lowest TTL
+// of any record in the set, which is how RFC 2181 section 5.2 says a client
+// should treat an RRset whose TTLs differ. It returns an error if a record of
+// a type registered from outside the package cannot be rebuilt with the new
+// TTL.
+func (rs RRset) NormalizeTTL() (RRset, error) {
+	ttl := rs.TTL()
+	normalized := rs
+	normalized.Records = make([]DNSResourceRecord, len(rs.Records))
+	for i, rr := range rs.Records {
+		common := rr.GetCommon()
+		common.TTL = ttl
+		var err error
+		normalized.Records[i], err = withCommon(rr, common)
+		if err != nil {
+			return rs, fmt.Errorf("Records[%d]: withCommon: %s", i, err)
+		}
+	}
+	return normalized, nil
+}
+
+// Dedup returns a copy of rs with duplicate records removed, keeping the
+// first of each. Records are duplicates if Compare finds them the same, i.e.
+// their RDATA is identical; TTLs and the cache-flush bit are not considered.
+// Records whose RDATA cannot be packed are all duplicates of each other, as
+// Compare finds them the same.
+func (rs RRset) Dedup() RRset {
+	deduped := rs
+	deduped.Records = nil
+	for _, rr := range rs.Records {
+		dup := false
+		for _, kept := range deduped.Records {
+			if Compare(rr, kept) == 0 {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			deduped.Records = append(deduped.Records, rr)
+		}
+	}
+	return deduped
+}
+
+// Equal reports whether rs and other are the same set: the same owner name
+// (compared case-insensitively), type and class, holding the same RDATA
+// regardless of order or duplicates. TTLs are not compared, and records whose
+// RDATA cannot be packed match each other, as for Dedup.
+func (rs RRset) Equal(other RRset) (bool, []string) {
+	same := true
+	var reasons []string
+	if !strings.EqualFold(strings.TrimSuffix(rs.Domain, "."), strings.TrimSuffix(other.Domain, ".")) {
+		same = false
+		reason := fmt.Sprintf("Domain: %q != %q", rs.Domain, other.Domain)
+		reasons = append(reasons, reason)
+	}
+	if rs.Type != other.Type {
+		same = false
+		reason := fmt.Sprintf("Type: %d != %d", rs.Type, other.Type)
+		reasons = append(reasons, reason)
+	}
+	if rs.Class != other.Class {
+		same = false
+		reason := fmt.Sprintf("Class: %d != %d", rs.Class, other.Class)
+		reasons = append(reasons, reason)
+	}
+	ours := rs.Dedup().Records
+	theirs := other.Dedup().Records
+	for _, rr := range ours {
+		if !containsRData(theirs, rr) {
+			same = false
+			reason := fmt.Sprintf("Records: %v missing from other", rr)
+			reasons = append(reasons, reason)
+		}
+	}
+	for _, rr := range theirs {
+		if !containsRData(ours, rr) {
+			same = false
+			reason := fmt.Sprintf("Records: %v missing from this", rr)
+			reasons = append(reasons, reason)
+		}
+	}
+	return same, reasons
+}
+
+func containsRData(rrs []DNSResourceRecord, rr DNSResourceRecord) bool {
+	for _, candidate := range rrs {
+		if Compare(candidate, rr) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalSort returns a copy of rs with its records in the canonical order
+// of RFC 4034 section 6.3, as needed to sign or verify it: sorted by their
+// canonical RDATA, in which the domain names of the types listed in RFC 4034
+// section 6.2 (as amended by RFC 6840 section 5.1) are lower-cased.
+func (rs RRset) CanonicalSort() (RRset, error) {
+	type keyedRecord struct {
+		rr    DNSResourceRecord
+		rdata []byte
+	}
+	keyed := make([]keyedRecord, len(rs.Records))
+	for i, rr := range rs.Records {
+		rdata, err := canonicalRData(rr)
+		if err != nil {
+			return rs, fmt.Errorf("canonicalRData: %s", err)
+		}
+		keyed[i] = keyedRecord{rr: rr, rdata: rdata}
+	}
+	sort.SliceStable(keyed, func(i, j int) bool {
+		return bytes.Compare(keyed[i].rdata, keyed[j].rdata) < 0
+	})
+
+	sorted := rs
+	sorted.Records = make([]DNSResourceRecord, len(keyed))
+	for i, kr := range keyed {
+		sorted.Records[i] = kr.rr
+	}
+	return sorted, nil
+}
+
+// canonicalRData returns the canonical form of the RDATA of rr, per RFC 4034
+// section 6.2.
+func canonicalRData(rr DNSResourceRecord) ([]byte, error) {
+	switch r := rr.(type) {
+	case PTRRecord:
+		r.PtrDName = strings.ToLower(r.PtrDName)
+		return r.PackRData()
+	case RPRecord:
+		r.Mailbox = strings.ToLower(r.Mailbox)
+		r.TXTDomain = strings.ToLower(r.TXTDomain)
+		return r.PackRData()
+	case AFSDBRecord:
+		r.Hostname = strings.ToLower(r.Hostname)
+		return r.PackRData()
+	case NAPTRRecord:
+		r.Replacement = strings.ToLower(r.Replacement)
+		return r.PackRData()
+	case KXRecord:
+		r.Exchanger = strings.ToLower(r.Exchanger)
+		return r.PackRData()
+	case SRVRecord:
+		r.Target = strings.ToLower(r.Target)
+		return r.PackRData()
+	case DNAMERecord:
+		r.Target = strings.ToLower(r.Target)
+		return r.PackRData()
+	case UnknownRecord:
+		// NS, CNAME, SOA, MX and the rest of RFC 1035's types decode as
+		// UnknownRecord, but their names still have to be lower-cased
+		layout, found := rfc1035RDataLayouts[r.Common.Type]
+		if !found {
+			return r.PackRData()
+		}
+		return lowerRDataNames(r.Common.Type, r.RData, layout)
+	default:
+		return rr.PackRData()
+	}
+}
+
+// lowerRDataNames returns a copy of rdata, laid out as for
+// rfc1035RDataLayouts, with the US-ASCII letters in its uncompressed names
+// lower-cased.
+func lowerRDataNames(rrType RecordType, rdata []byte, layout []int) ([]byte, error) {
+	lowered := make([]byte, len(rdata))
+	copy(lowered, rdata)
+	off := 0
+	for _, size := range layout {
+		if size > 0 {
+			off += size
+			continue
+		}
+		for off < len(lowered) && lowered[off] != 0 {
+			end := off + 1 + int(lowered[off])
+			if end > len(lowered) {
+				return nil, fmt.Errorf("%s RDATA too short (%d bytes)", rrType, len(rdata))
+			}
+			for i := off + 1; i < end; i++ {
+				if lowered[i] >= 'A' && lowered[i] <= 'Z' {
+					lowered[i] += 'a' - 'A'
+				}
+			}
+			off = end
+		}
+		// skip the root label
+		off++
+	}
+	if off > len(lowered) {
+		return nil, fmt.Errorf("%s RDATA too short (%d bytes)", rrType, len(rdata))
+	}
+	return lowered, nil
+}
+
+// withCommon returns a copy of rr with its common fields replaced, rebuilt
+// through PackRData and UnpackRData so that it works the same for every
+// registered type.
+func withCommon(rr DNSResourceRecord, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	rdata, err := rr.PackRData()
+	if err != nil {
+		return rr, fmt.Errorf("PackRData: %s", err)
+	}
+	rebuilt, err := rr.UnpackRData(NewRData(common, rdata))
+	if err != nil {
+		return rr, fmt.Errorf("UnpackRData: %s", err)
+	}
+	return rebuilt, nil
+}
@@ -0,0 +1,180 @@
+package rawmdns
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+func newRRsetTestA(name, addr string, ttl uint32) ARecord {
+	return ARecord{
+		Common: ResourceRecordCommon{Domain: name, Type: TypeA, Class: ClassINET, TTL: ttl},
+		Addr:   net.ParseIP(addr).To4(),
+	}
+}
+
+func TestGroupRRsets(t *testing.T) {
+	srv := SRVRecord{
+		Common: ResourceRecordCommon{Domain: "_ipp._tcp.local", Type: TypeSRV, Class: ClassINET, TTL: 120},
+		Port:   631,
+		Target: "printer.local",
+	}
+	rrs := []DNSResourceRecord{
+		newRRsetTestA("printer.local", "169.254.1.1", 120),
+		srv,
+		newRRsetTestA("Printer.LOCAL.", "169.254.1.2", 120),
+		newRRsetTestA("scanner.local", "169.254.1.3", 120),
+	}
+	sets := GroupRRsets(rrs)
+	if len(sets) != 3 {
+		t.Fatalf("len(sets) is %d, expected 3", len(sets))
+	}
+	expected := []struct {
+		domain  string
+		rtype   RecordType
+		records int
+	}{
+		{"printer.local", TypeA, 2},
+		{"_ipp._tcp.local", TypeSRV, 1},
+		{"scanner.local", TypeA, 1},
+	}
+	for i, e := range expected {
+		if sets[i].Domain != e.domain || sets[i].Type != e.rtype || len(sets[i].Records) != e.records {
+			t.Errorf("sets[%d] is %q/%d with %d records, expected %q/%d with %d", i,
+				sets[i].Domain, sets[i].Type, len(sets[i].Records), e.domain, e.rtype, e.records)
+		}
+	}
+}
+
+func TestRRset_NormalizeTTL(t *testing.T) {
+	rs := RRset{Domain: "printer.local", Type: TypeA, Class: ClassINET, Records: []DNSResourceRecord{
+		newRRsetTestA("printer.local", "169.254.1.1", 4500),
+		newRRsetTestA("printer.local", "169.254.1.2", 120),
+	}}
+	normalized, err := rs.NormalizeTTL()
+	if err != nil {
+		t.Fatalf("NormalizeTTL: %s", err)
+	}
+	for i, rr := range normalized.Records {
+		if rr.GetCommon().TTL != 120 {
+			t.Errorf("Records[%d].TTL is %d, expected 120", i, rr.GetCommon().TTL)
+		}
+		if Compare(rr, rs.Records[i]) != 0 {
+			t.Errorf("Records[%d] RDATA changed: %+v", i, rr)
+		}
+	}
+	if rs.Records[0].GetCommon().TTL != 4500 {
+		t.Error("NormalizeTTL modified the original RRset")
+	}
+}
+
+func TestRRset_NormalizeTTL_registered(t *testing.T) {
+	common := ResourceRecordCommon{Domain: "lab.example", Type: typeVendorTest, Class: ClassINET, TTL: 4500}
+	rs := RRset{Domain: "lab.example", Type: typeVendorTest, Class: ClassINET, Records: []DNSResourceRecord{
+		vendorTestRecord{Common: common, Weight: 1, Host: "a.lab.example"},
+		vendorTestRecord{Common: common, Weight: 2, Host: "b.lab.example"},
+	}}
+	low := rs.Records[1].(vendorTestRecord)
+	low.Common.TTL = 120
+	rs.Records[1] = low
+
+	normalized, err := rs.NormalizeTTL()
+	if err != nil {
+		t.Fatalf("NormalizeTTL: %s", err)
+	}
+	first := normalized.Records[0].(vendorTestRecord)
+	if first.Common.TTL != 120 || first.Weight != 1 || first.Host != "a.lab.example" {
+		t.Errorf("Records[0] is %+v, expected TTL 120, Weight 1 and Host a.lab.example", first)
+	}
+
+	// a 64-byte label packs, but can't be read back
+	bad := vendorTestRecord{Common: common, Host: strings.Repeat("x", 64) + ".lab.example"}
+	rs.Records = append(rs.Records, bad)
+	if _, err := rs.NormalizeTTL(); err == nil {
+		t.Error("NormalizeTTL succeeded on a record that can't be rebuilt")
+	}
+}
+
+func TestRRset_DedupEqual(t *testing.T) {
+	a := RRset{Domain: "printer.local", Type: TypeA, Class: ClassINET, Records: []DNSResourceRecord{
+		newRRsetTestA("printer.local", "169.254.1.1", 120),
+		newRRsetTestA("printer.local", "169.254.1.2", 120),
+		newRRsetTestA("printer.local", "169.254.1.1", 4500),
+	}}
+	if deduped := a.Dedup(); len(deduped.Records) != 2 {
+		t.Errorf("len(Dedup().Records) is %d, expected 2", len(deduped.Records))
+	}
+
+	b := RRset{Domain: "PRINTER.local", Type: TypeA, Class: ClassINET, Records: []DNSResourceRecord{
+		newRRsetTestA("PRINTER.local", "169.254.1.2", 10),
+		newRRsetTestA("PRINTER.local", "169.254.1.1", 10),
+	}}
+	same, reasons := a.Equal(b)
+	if !same {
+		t.Error("Expected RRsets to be equal:")
+		for _, reason := range reasons {
+			t.Log(reason)
+		}
+	}
+
+	b.Records = b.Records[:1]
+	if same, _ = a.Equal(b); same {
+		t.Error("Expected RRsets with different records to differ")
+	}
+}
+
+func TestRRset_CanonicalSort(t *testing.T) {
+	newPTR := func(target string) PTRRecord {
+		return PTRRecord{
+			Common:   ResourceRecordCommon{Domain: "_ipp._tcp.local", Type: TypePTR, Class: ClassINET, TTL: 4500},
+			PtrDName: target,
+		}
+	}
+	// By raw bytes "Zebra" would sort first, but canonically it is "zebra"
+	rs := RRset{Domain: "_ipp._tcp.local", Type: TypePTR, Class: ClassINET, Records: []DNSResourceRecord{
+		newPTR("Zebra._ipp._tcp.local"),
+		newPTR("apple._ipp._tcp.local"),
+		newPTR("mango._ipp._tcp.local"),
+	}}
+	sorted, err := rs.CanonicalSort()
+	if err != nil {
+		t.Fatalf("Unexpected error from CanonicalSort: %s", err)
+	}
+	expected := []string{"apple._ipp._tcp.local", "mango._ipp._tcp.local", "Zebra._ipp._tcp.local"}
+	for i, rr := range sorted.Records {
+		if rr.(PTRRecord).PtrDName != expected[i] {
+			t.Errorf("Records[%d] is %q, expected %q", i, rr.(PTRRecord).PtrDName, expected[i])
+		}
+	}
+}
+
+func TestRRset_CanonicalSort_rfc1035(t *testing.T) {
+	newMX := func(exchange string) UnknownRecord {
+		return UnknownRecord{
+			Common: ResourceRecordCommon{Domain: "example.local", Type: TypeMX, Class: ClassINET, TTL: 4500},
+			RData:  append([]byte{0x00, 0x0a}, PackName(exchange)...),
+		}
+	}
+	// By raw bytes "B" sorts before "a", but canonically it is "b"
+	rs := RRset{Domain: "example.local", Type: TypeMX, Class: ClassINET, Records: []DNSResourceRecord{
+		newMX("B.local"),
+		newMX("a.local"),
+	}}
+	sorted, err := rs.CanonicalSort()
+	if err != nil {
+		t.Fatalf("Unexpected error from CanonicalSort: %s", err)
+	}
+	for i, expected := range []string{"a.local", "B.local"} {
+		if rdata := sorted.Records[i].(UnknownRecord).RData; !bytes.Equal(rdata, newMX(expected).RData) {
+			t.Errorf("Records[%d] is % x, expected MX %s", i, rdata, expected)
+		}
+	}
+
+	truncated := newMX("a.local")
+	truncated.RData = truncated.RData[:4]
+	rs.Records = append(rs.Records, truncated)
+	if _, err := rs.CanonicalSort(); err == nil {
+		t.Error("CanonicalSort succeeded on truncated MX RDATA")
+	}
+}
@@ -0,0 +1,163 @@
+package rawmdns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary formats a message on one line, in the style of tcpdump's DNS
+// output, for logging. A query looks like
+//
+//	0 [2q] PTR (QM)? _airplay._tcp.local. SRV (QU)? Foo._airplay._tcp.local.
+//
+// with the ID, "+" if recursion is desired, the count of any section other
+// than a single question in brackets, then each question with "(QU)" if it
+// asks for a unicast response and "(QM)" otherwise. A response looks like
+//
+//	0*- 4/0/3 PTR Foo._airplay._tcp.local., (Cache flush) SRV foo.local.:7000 0 0, ...
+//
+// with the ID, "*" if authoritative, "-" if recursion is not available and
+// "|" if truncated, any error response code, the count of records in the
+// answer, authority and additional sections, and then the answers. Records
+// with the cache-flush bit set are marked "(Cache flush)", and those with a
+// TTL of 0 "(Goodbye)". The questions of a response, if any, follow "q:".
+//
+// If maxLen is positive, questions and answers are left off the end so that
+// the summary is no longer than maxLen bytes, and " ..." marks where they
+// were; the ID, flags and counts are always included, so a summary may only
+// exceed maxLen if they alone do.
+func (dm DNSMessage) Summary(maxLen int) string {
+	var head strings.Builder
+	fmt.Fprintf(&head, "%d", dm.Hdr.ID)
+	var parts []summaryPart
+
+	if dm.Hdr.IsResponse {
+		if dm.Hdr.Authoritative {
+			head.WriteByte('*')
+		}
+		if !dm.Hdr.RecursionAvailable {
+			head.WriteByte('-')
+		}
+		if dm.Hdr.Truncated {
+			head.WriteByte('|')
+		}
+		if dm.Hdr.OpCode != OpCodeQuery {
+			fmt.Fprintf(&head, " %s", dm.Hdr.OpCode)
+		}
+		if dm.Hdr.ResponseCode != CodeSuccess {
+			fmt.Fprintf(&head, " %s", dm.Hdr.ResponseCode)
+		}
+		for i, q := range dm.Questions {
+			text := " " + summarizeQuestion(q)
+			if i == 0 {
+				text = " q:" + text
+			}
+			parts = append(parts, summaryPart{text: text})
+		}
+		counts := fmt.Sprintf(" %d/%d/%d", len(dm.Answers), len(dm.Authority), len(dm.Additional))
+		parts = append(parts, summaryPart{text: counts, always: true})
+		for i, rr := range dm.Answers {
+			text := " " + summarizeRecord(rr)
+			if i > 0 {
+				text = "," + text
+			}
+			parts = append(parts, summaryPart{text: text})
+		}
+	} else {
+		if dm.Hdr.RecursionDesired {
+			head.WriteByte('+')
+		}
+		if dm.Hdr.OpCode != OpCodeQuery {
+			fmt.Fprintf(&head, " %s", dm.Hdr.OpCode)
+		}
+		if len(dm.Questions) != 1 {
+			fmt.Fprintf(&head, " [%dq]", len(dm.Questions))
+		}
+		if len(dm.Answers) > 0 {
+			fmt.Fprintf(&head, " [%da]", len(dm.Answers))
+		}
+		if len(dm.Authority) > 0 {
+			fmt.Fprintf(&head, " [%dn]", len(dm.Authority))
+		}
+		if len(dm.Additional) > 0 {
+			fmt.Fprintf(&head, " [%dau]", len(dm.Additional))
+		}
+		for _, q := range dm.Questions {
+			parts = append(parts, summaryPart{text: " " + summarizeQuestion(q)})
+		}
+	}
+
+	const more = " ..."
+	s := head.String()
+	for i, part := range parts {
+		if part.always {
+			s += part.text
+			continue
+		}
+		// leave room for the parts which are always included, and to mark
+		// that parts were left off unless this is the last that can be
+		needed := len(part.text)
+		lastOptional := true
+		for _, later := range parts[i+1:] {
+			if later.always {
+				needed += len(later.text)
+			} else {
+				lastOptional = false
+			}
+		}
+		if !lastOptional {
+			needed += len(more)
+		}
+		if maxLen > 0 && len(s)+needed > maxLen {
+			s += more
+			for _, later := range parts[i+1:] {
+				if later.always {
+					s += later.text
+				}
+			}
+			return s
+		}
+		s += part.text
+	}
+	return s
+}
+
+// summaryPart is a piece of a summary, which is left off if there's no room
+// for it unless always is set.
+type summaryPart struct {
+	text   string
+	always bool
+}
+
+func summarizeQuestion(q DNSQuestion) string {
+	mode := "QM"
+	if q.AcceptUnicastResponse {
+		mode = "QU"
+	}
+	return fmt.Sprintf("%s (%s)? %s", q.Type, mode, presentName(q.Domain))
+}
+
+// summarizeRecord formats a record's type and RDATA, like tcpdump, without
+// its owner name.
+func summarizeRecord(rr DNSResourceRecord) string {
+	common := rr.GetCommon()
+	var marks string
+	if common.CacheFlush {
+		marks += "(Cache flush) "
+	}
+	if common.TTL == 0 && common.Type != TypeOPT {
+		marks += "(Goodbye) "
+	}
+
+	switch r := rr.(type) {
+	case SRVRecord:
+		return fmt.Sprintf("%sSRV %s:%d %d %d", marks, presentName(r.Target), r.Port, r.Priority, r.Weight)
+	case OPTRecord:
+		// the class of an OPT record is the sender's UDP payload size
+		return fmt.Sprintf("OPT UDPsize=%d", uint16(r.Common.Class))
+	}
+	if rdata, ok := presentRData(rr); ok {
+		return fmt.Sprintf("%s%s %s", marks, common.Type, rdata)
+	}
+	return fmt.Sprintf("%s%s", marks, common.Type)
+}
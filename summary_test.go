@@ -0,0 +1,76 @@
+package rawmdns
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestSummary_capture(t *testing.T) {
+	b, err := os.ReadFile("testdata/airplay-question.cap")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %s", err)
+	}
+	d := NewDecoder(bytes.NewReader(b))
+	dm, err := d.DecodeDNSMessage()
+	if err != nil {
+		t.Fatalf("DecodeDNSMessage: %s", err)
+	}
+	expected := "0 [2q] PTR (QU)? _raop._tcp.local. PTR (QU)? _airplay._tcp.local."
+	if s := dm.Summary(0); s != expected {
+		t.Errorf("Summary is %q, expected %q", s, expected)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	common := func(domain string, rt RecordType, ttl uint32, cacheFlush bool) ResourceRecordCommon {
+		return ResourceRecordCommon{Domain: domain, Type: rt, Class: ClassINET, TTL: ttl, CacheFlush: cacheFlush}
+	}
+	response := DNSMessage{
+		Hdr: DNSHeader{IsResponse: true, Authoritative: true},
+		Answers: []DNSResourceRecord{
+			PTRRecord{Common: common("_ipp._tcp.local", TypePTR, 0, false), PtrDName: "printer._ipp._tcp.local"},
+			SRVRecord{Common: common("printer._ipp._tcp.local", TypeSRV, 0, true), Port: 631, Target: "printer.local"},
+		},
+		Additional: []DNSResourceRecord{
+			ARecord{Common: common("printer.local", TypeA, 120, true), Addr: net.ParseIP("10.0.0.5").To4()},
+			OPTRecord{Common: ResourceRecordCommon{Domain: "", Type: TypeOPT, Class: 1440}},
+		},
+	}
+	query := DNSMessage{
+		Hdr:       DNSHeader{ID: 7, RecursionDesired: true},
+		Questions: []DNSQuestion{{Domain: "printer.local", Type: TypeA, Class: ClassINET}},
+		Answers:   response.Additional[:1],
+	}
+	legacy := DNSMessage{
+		Hdr:       DNSHeader{ID: 7, IsResponse: true, Truncated: true, RecursionAvailable: true, ResponseCode: CodeNameError},
+		Questions: query.Questions,
+	}
+
+	testCases := []struct {
+		dm       DNSMessage
+		maxLen   int
+		expected string
+	}{
+		{response, 0, "0*- 2/0/2 (Goodbye) PTR printer._ipp._tcp.local., (Cache flush) (Goodbye) SRV printer.local.:631 0 0"},
+		{response, 40, "0*- 2/0/2 ..."},
+		{response, 80, "0*- 2/0/2 (Goodbye) PTR printer._ipp._tcp.local. ..."},
+		// the last item may use the room kept for the marker
+		{response, 101, "0*- 2/0/2 (Goodbye) PTR printer._ipp._tcp.local., (Cache flush) (Goodbye) SRV printer.local.:631 0 0"},
+		{DNSMessage{Hdr: DNSHeader{IsResponse: true}, Additional: response.Additional[1:]}, 0, "0- 0/0/1"},
+		{query, 0, "7+ [1a] A (QM)? printer.local."},
+		{legacy, 0, "7| NXDOMAIN q: A (QM)? printer.local. 0/0/0"},
+		// the questions of a response can be left off too, but not the counts
+		{legacy, 30, "7| NXDOMAIN ... 0/0/0"},
+		{legacy, 43, "7| NXDOMAIN q: A (QM)? printer.local. 0/0/0"},
+	}
+	for i, tc := range testCases {
+		if s := tc.dm.Summary(tc.maxLen); s != tc.expected {
+			t.Errorf("Case %d: Summary(%d) is %q, expected %q", i, tc.maxLen, s, tc.expected)
+		}
+		if tc.maxLen > 0 && len(tc.dm.Summary(tc.maxLen)) > tc.maxLen {
+			t.Errorf("Case %d: Summary(%d) is longer than %d", i, tc.maxLen, tc.maxLen)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package rawmdns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// SvcParamKey identifies a SvcParam in the RDATA of an SVCB or HTTPS record;
+// see RFC 9460 section 14.3.2.
+type SvcParamKey uint16
+
+const (
+	// SvcParamKeyMandatory lists the keys a client must understand to use the record.
+	SvcParamKeyMandatory SvcParamKey = 0
+	// SvcParamKeyALPN lists the supported Application-Layer Protocol Negotiation IDs.
+	SvcParamKeyALPN SvcParamKey = 1
+	// SvcParamKeyNoDefaultALPN indicates the scheme's default ALPN is not supported.
+	SvcParamKeyNoDefaultALPN SvcParamKey = 2
+	// SvcParamKeyPort is the alternative port the service is reachable on.
+	SvcParamKeyPort SvcParamKey = 3
+	// SvcParamKeyIPv4Hint lists IPv4 addresses the client may use to reach the service.
+	SvcParamKeyIPv4Hint SvcParamKey = 4
+	// SvcParamKeyECH carries a TLS Encrypted ClientHello configuration list.
+	SvcParamKeyECH SvcParamKey = 5
+	// SvcParamKeyIPv6Hint lists IPv6 addresses the client may use to reach the service.
+	SvcParamKeyIPv6Hint SvcParamKey = 6
+)
+
+// SvcParam is a single key/value pair from the SvcParams of an SVCB or HTTPS
+// record. Keys without a dedicated type in this package are represented by
+// SvcUnknown.
+type SvcParam interface {
+	Key() SvcParamKey
+	value() ([]byte, error)
+}
+
+// SvcMandatory is the "mandatory" SvcParam; Keys must be sorted and must not
+// include SvcParamKeyMandatory itself.
+type SvcMandatory struct {
+	Keys []SvcParamKey
+}
+
+func (sm SvcMandatory) Key() SvcParamKey {
+	return SvcParamKeyMandatory
+}
+
+func (sm SvcMandatory) value() ([]byte, error) {
+	if len(sm.Keys) == 0 {
+		return nil, fmt.Errorf("mandatory: at least one key is required")
+	}
+	bwa := newBufWriteAttempter()
+	for i, key := range sm.Keys {
+		if key == SvcParamKeyMandatory {
+			return nil, fmt.Errorf("mandatory: may not list itself")
+		}
+		if i > 0 && key <= sm.Keys[i-1] {
+			return nil, fmt.Errorf("mandatory: keys not sorted or duplicated at %d", key)
+		}
+		bwa.attemptBinaryWrite(binary.BigEndian, key)
+	}
+	return bwa.buf.Bytes(), bwa.err
+}
+
+// SvcALPN is the "alpn" SvcParam, e.g. []string{"h2", "h3"}.
+type SvcALPN struct {
+	IDs []string
+}
+
+func (sa SvcALPN) Key() SvcParamKey {
+	return SvcParamKeyALPN
+}
+
+func (sa SvcALPN) value() ([]byte, error) {
+	if len(sa.IDs) == 0 {
+		return nil, fmt.Errorf("alpn: at least one ID is required")
+	}
+	bwa := newBufWriteAttempter()
+	for _, id := range sa.IDs {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("alpn: ID %q must be 1-255 bytes", id)
+		}
+		bwa.attemptWrite([]byte{uint8(len(id))})
+		bwa.attemptWrite([]byte(id))
+	}
+	return bwa.buf.Bytes(), bwa.err
+}
+
+// SvcNoDefaultALPN is the "no-default-alpn" SvcParam, which has no value.
+type SvcNoDefaultALPN struct{}
+
+func (snda SvcNoDefaultALPN) Key() SvcParamKey {
+	return SvcParamKeyNoDefaultALPN
+}
+
+func (snda SvcNoDefaultALPN) value() ([]byte, error) {
+	return nil, nil
+}
+
+// SvcPort is the "port" SvcParam.
+type SvcPort struct {
+	Port uint16
+}
+
+func (sp SvcPort) Key() SvcParamKey {
+	return SvcParamKeyPort
+}
+
+func (sp SvcPort) value() ([]byte, error) {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, sp.Port)
+	return b, nil
+}
+
+// SvcIPv4Hint is the "ipv4hint" SvcParam.
+type SvcIPv4Hint struct {
+	Addrs []net.IP
+}
+
+func (sih SvcIPv4Hint) Key() SvcParamKey {
+	return SvcParamKeyIPv4Hint
+}
+
+func (sih SvcIPv4Hint) value() ([]byte, error) {
+	if len(sih.Addrs) == 0 {
+		return nil, fmt.Errorf("ipv4hint: at least one address is required")
+	}
+	var ret []byte
+	for _, addr := range sih.Addrs {
+		v4 := addr.To4()
+		if v4 == nil {
+			return nil, fmt.Errorf("ipv4hint: %s is not an IPv4 address", addr)
+		}
+		ret = append(ret, v4...)
+	}
+	return ret, nil
+}
+
+// SvcECH is the "ech" SvcParam; Config is an opaque ECHConfigList.
+type SvcECH struct {
+	Config []byte
+}
+
+func (se SvcECH) Key() SvcParamKey {
+	return SvcParamKeyECH
+}
+
+func (se SvcECH) value() ([]byte, error) {
+	return se.Config, nil
+}
+
+// SvcIPv6Hint is the "ipv6hint" SvcParam.
+type SvcIPv6Hint struct {
+	Addrs []net.IP
+}
+
+func (sih SvcIPv6Hint) Key() SvcParamKey {
+	return SvcParamKeyIPv6Hint
+}
+
+func (sih SvcIPv6Hint) value() ([]byte, error) {
+	if len(sih.Addrs) == 0 {
+		return nil, fmt.Errorf("ipv6hint: at least one address is required")
+	}
+	var ret []byte
+	for _, addr := range sih.Addrs {
+		if addr.To4() != nil || addr.To16() == nil {
+			return nil, fmt.Errorf("ipv6hint: %s is not an IPv6 address", addr)
+		}
+		ret = append(ret, addr.To16()...)
+	}
+	return ret, nil
+}
+
+// SvcUnknown is any SvcParam whose key has no dedicated type in this package;
+// its value is kept as raw bytes.
+type SvcUnknown struct {
+	Code  SvcParamKey
+	Value []byte
+}
+
+func (su SvcUnknown) Key() SvcParamKey {
+	return su.Code
+}
+
+func (su SvcUnknown) value() ([]byte, error) {
+	return su.Value, nil
+}
+
+// packSvcParams encodes params as the SvcParams portion of SVCB/HTTPS RDATA.
+// RFC 9460 section 2.2 requires that keys appear in strictly increasing
+// order, so unsorted or duplicated keys are an error.
+func packSvcParams(params []SvcParam) ([]byte, error) {
+	bwa := newBufWriteAttempter()
+	for i, param := range params {
+		if i > 0 && param.Key() <= params[i-1].Key() {
+			return nil, fmt.Errorf("SvcParams not sorted or duplicated at key %d", param.Key())
+		}
+		val, err := param.value()
+		if err != nil {
+			return nil, err
+		}
+		if len(val) > 65535 {
+			return nil, fmt.Errorf("SvcParam %d value is %d bytes, may not exceed 65535", param.Key(), len(val))
+		}
+		bwa.attemptBinaryWrite(binary.BigEndian, param.Key())
+		bwa.attemptBinaryWrite(binary.BigEndian, uint16(len(val)))
+		bwa.attemptWrite(val)
+	}
+	return bwa.buf.Bytes(), bwa.err
+}
+
+// unpackSvcParams is the inverse of packSvcParams; it applies the same
+// ordering rules, since RFC 9460 says a client must treat such a record as
+// malformed.
+func unpackSvcParams(b []byte) ([]SvcParam, error) {
+	var params []SvcParam
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("truncated SvcParam header")
+		}
+		key := SvcParamKey(binary.BigEndian.Uint16(b[0:2]))
+		valLen := int(binary.BigEndian.Uint16(b[2:4]))
+		if len(b) < 4+valLen {
+			return nil, fmt.Errorf("SvcParam %d length %d exceeds remaining %d bytes", key, valLen, len(b)-4)
+		}
+		val := make([]byte, valLen)
+		copy(val, b[4:4+valLen])
+		b = b[4+valLen:]
+
+		if len(params) > 0 && key <= params[len(params)-1].Key() {
+			return nil, fmt.Errorf("SvcParams not sorted or duplicated at key %d", key)
+		}
+		param, err := unpackSvcParam(key, val)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+func unpackSvcParam(key SvcParamKey, val []byte) (SvcParam, error) {
+	switch key {
+	case SvcParamKeyMandatory:
+		if len(val) == 0 || len(val)%2 != 0 {
+			return nil, fmt.Errorf("mandatory: bad length %d", len(val))
+		}
+		var sm SvcMandatory
+		for i := 0; i < len(val); i += 2 {
+			sm.Keys = append(sm.Keys, SvcParamKey(binary.BigEndian.Uint16(val[i:i+2])))
+		}
+		return sm, nil
+	case SvcParamKeyALPN:
+		var sa SvcALPN
+		for len(val) > 0 {
+			id, rest, err := readCharacterString(val)
+			if err != nil {
+				return nil, fmt.Errorf("alpn: readCharacterString: %s", err)
+			}
+			sa.IDs = append(sa.IDs, string(id))
+			val = rest
+		}
+		return sa, nil
+	case SvcParamKeyNoDefaultALPN:
+		if len(val) != 0 {
+			return nil, fmt.Errorf("no-default-alpn: must have an empty value")
+		}
+		return SvcNoDefaultALPN{}, nil
+	case SvcParamKeyPort:
+		if len(val) != 2 {
+			return nil, fmt.Errorf("port: bad length %d", len(val))
+		}
+		return SvcPort{Port: binary.BigEndian.Uint16(val)}, nil
+	case SvcParamKeyIPv4Hint:
+		if len(val) == 0 || len(val)%4 != 0 {
+			return nil, fmt.Errorf("ipv4hint: bad length %d", len(val))
+		}
+		var sih SvcIPv4Hint
+		for i := 0; i < len(val); i += 4 {
+			sih.Addrs = append(sih.Addrs, net.IP(val[i:i+4]))
+		}
+		return sih, nil
+	case SvcParamKeyECH:
+		return SvcECH{Config: val}, nil
+	case SvcParamKeyIPv6Hint:
+		if len(val) == 0 || len(val)%16 != 0 {
+			return nil, fmt.Errorf("ipv6hint: bad length %d", len(val))
+		}
+		var sih SvcIPv6Hint
+		for i := 0; i < len(val); i += 16 {
+			sih.Addrs = append(sih.Addrs, net.IP(val[i:i+16]))
+		}
+		return sih, nil
+	default:
+		return SvcUnknown{Code: key, Value: val}, nil
+	}
+}
+
+// svcParamsEqual compares two SvcParams lists by their wire-format encoding,
+// so that e.g. a 16-byte and a 4-byte net.IP for the same IPv4 hint match.
+func svcParamsEqual(a, b []SvcParam) (bool, []string) {
+	same := true
+	var reasons []string
+	if len(a) != len(b) {
+		reason := fmt.Sprintf("len(Params): %d != %d", len(a), len(b))
+		return false, []string{reason}
+	}
+	for i := range a {
+		if a[i].Key() != b[i].Key() {
+			same = false
+			reason := fmt.Sprintf("Params[%d].Key(): %d != %d", i, a[i].Key(), b[i].Key())
+			reasons = append(reasons, reason)
+			continue
+		}
+		aVal, aErr := a[i].value()
+		bVal, bErr := b[i].value()
+		if aErr != nil || bErr != nil || !bytes.Equal(aVal, bVal) {
+			same = false
+			reason := fmt.Sprintf("Params[%d]: %v != %v", i, a[i], b[i])
+			reasons = append(reasons, reason)
+		}
+	}
+	return same, reasons
+}
@@ -0,0 +1,72 @@
+package rawmdns
+
+import (
+	"bytes"
+	"testing"
+)
+
+// From RFC 9460 appendix D.2, figure 4:
+// example.com.   HTTPS   16 foo.example.com. port=53
+func TestSVCBRecord_PackRData(t *testing.T) {
+	expectedRData := []byte{
+		0x00, 0x10,
+		0x03, 'f', 'o', 'o',
+		0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e',
+		0x03, 'c', 'o', 'm', 0x00,
+		0x00, 0x03, 0x00, 0x02, 0x00, 0x35,
+	}
+	sr := SVCBRecord{
+		Common: ResourceRecordCommon{
+			Domain: "example.com",
+			Type:   TypeSVCB,
+			Class:  ClassINET,
+		},
+		Priority: 16,
+		Target:   "foo.example.com.",
+		Params:   []SvcParam{SvcPort{Port: 53}},
+	}
+	rData, err := sr.PackRData()
+	if err != nil {
+		t.Errorf("Unexpected error from PackRData: %s", err)
+	}
+	if !bytes.Equal(expectedRData, rData) {
+		t.Errorf("expectedRData != rData: % x", rData)
+	}
+}
+
+func TestPackSvcParams_ordering(t *testing.T) {
+	testCases := map[string][]SvcParam{
+		"unsorted": {
+			SvcPort{Port: 53},
+			SvcALPN{IDs: []string{"h2"}},
+		},
+		"duplicated": {
+			SvcALPN{IDs: []string{"h2"}},
+			SvcALPN{IDs: []string{"h3"}},
+		},
+		"unsorted mandatory": {
+			SvcMandatory{Keys: []SvcParamKey{SvcParamKeyPort, SvcParamKeyALPN}},
+		},
+		"self-referencing mandatory": {
+			SvcMandatory{Keys: []SvcParamKey{SvcParamKeyMandatory}},
+		},
+	}
+	for name, params := range testCases {
+		_, err := packSvcParams(params)
+		if err == nil {
+			t.Errorf("%s: expected error from packSvcParams, got none", name)
+		}
+	}
+}
+
+func TestUnpackSvcParams_ordering(t *testing.T) {
+	// port=53 followed by alpn=h2, i.e. out of order
+	b := []byte{
+		0x00, 0x03, 0x00, 0x02, 0x00, 0x35,
+		0x00, 0x01, 0x00, 0x03, 0x02, 'h', '2',
+	}
+	_, err := unpackSvcParams(b)
+	if err == nil {
+		t.Error("Expected error from unpackSvcParams, got none")
+	}
+}
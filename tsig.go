@@ -0,0 +1,366 @@
+package rawmdns
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+const (
+	// TSIGHMACSHA1 is the name of the HMAC-SHA1 TSIG algorithm; see RFC 8945 section 6.
+	TSIGHMACSHA1 = "hmac-sha1"
+	// TSIGHMACSHA224 is the name of the HMAC-SHA224 TSIG algorithm; see RFC 8945 section 6.
+	TSIGHMACSHA224 = "hmac-sha224"
+	// TSIGHMACSHA256 is the name of the HMAC-SHA256 TSIG algorithm; see RFC 8945 section 6.
+	TSIGHMACSHA256 = "hmac-sha256"
+	// TSIGHMACSHA384 is the name of the HMAC-SHA384 TSIG algorithm; see RFC 8945 section 6.
+	TSIGHMACSHA384 = "hmac-sha384"
+	// TSIGHMACSHA512 is the name of the HMAC-SHA512 TSIG algorithm; see RFC 8945 section 6.
+	TSIGHMACSHA512 = "hmac-sha512"
+)
+
+// DefaultTSIGFudge is the permitted clock skew, in seconds, written into the
+// TSIG records produced by this package; RFC 8945 section 10 recommends 300.
+const DefaultTSIGFudge uint16 = 300
+
+// maxUnsignedTSIGMessages is the most consecutive unsigned messages RFC 8945
+// section 5.3.1 permits in a multi-message response.
+const maxUnsignedTSIGMessages = 99
+
+// TSIGKey is a shared secret used to sign and verify messages. Name is the
+// key's domain name, which is also the owner name of the TSIG record.
+type TSIGKey struct {
+	Name      string
+	Algorithm string
+	Secret    []byte
+}
+
+func (k TSIGKey) newHash() (func() hash.Hash, error) {
+	switch strings.ToLower(strings.TrimSuffix(k.Algorithm, ".")) {
+	case TSIGHMACSHA1:
+		return sha1.New, nil
+	case TSIGHMACSHA224:
+		return sha256.New224, nil
+	case TSIGHMACSHA256:
+		return sha256.New, nil
+	case TSIGHMACSHA384:
+		return sha512.New384, nil
+	case TSIGHMACSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("Unsupported TSIG algorithm: %q", k.Algorithm)
+	}
+}
+
+func (k TSIGKey) mac(digest []byte) ([]byte, error) {
+	newHash, err := k.newHash()
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(newHash, k.Secret)
+	h.Write(digest)
+	return h.Sum(nil), nil
+}
+
+func (k TSIGKey) newTSIGRecord(originalID uint16, timeSigned time.Time) TSIGRecord {
+	return TSIGRecord{
+		Common: ResourceRecordCommon{
+			Domain: k.Name,
+			Type:   TypeTSIG,
+			Class:  ClassANY,
+			TTL:    0,
+		},
+		Algorithm:  k.Algorithm,
+		TimeSigned: uint64(timeSigned.Unix()),
+		Fudge:      DefaultTSIGFudge,
+		OriginalID: originalID,
+	}
+}
+
+// TSIGError is returned when a TSIG-signed message fails verification. Code
+// is the TSIG error (e.g. CodeBadSig) a server should return to the client.
+type TSIGError struct {
+	Code   ResponseCode
+	Reason string
+}
+
+func (te TSIGError) Error() string {
+	return fmt.Sprintf("TSIG verification failed (rcode %d): %s", te.Code, te.Reason)
+}
+
+// SignTSIG encodes dm, then appends a TSIG record signed with key and
+// returns the resulting wire-format message along with that record, whose
+// MAC must be passed as requestMAC when verifying the response. dm.Hdr should
+// not account for the TSIG record; its additional-record count is adjusted
+// in the output. When signing a response, requestMAC is the MAC from the
+// request's TSIG record; when signing a request, it should be nil.
+func SignTSIG(dm DNSMessage, key TSIGKey, requestMAC []byte, timeSigned time.Time) ([]byte, TSIGRecord, error) {
+	msg, err := dm.ToBytes()
+	if err != nil {
+		return nil, TSIGRecord{}, fmt.Errorf("DNSMessage.ToBytes: %s", err)
+	}
+	tr := key.newTSIGRecord(dm.Hdr.ID, timeSigned)
+	tr.MAC, err = key.mac(tsigDigest(requestMAC, [][]byte{msg}, tr, false))
+	if err != nil {
+		return nil, tr, err
+	}
+	signed, err := appendTSIG(msg, tr)
+	return signed, tr, err
+}
+
+// VerifyTSIG checks the TSIG record at the end of the wire-format message
+// msg against key. requestMAC is the MAC of the signed request when msg is a
+// response, or nil when msg is itself a request. The TSIG record is returned
+// even when verification fails, so that a server can build its error
+// response; failures are reported as a TSIGError.
+func VerifyTSIG(msg []byte, key TSIGKey, requestMAC []byte, now time.Time) (TSIGRecord, error) {
+	unsigned, tr, found, err := splitTSIG(msg)
+	if err != nil {
+		return tr, err
+	}
+	if !found {
+		return tr, TSIGError{Code: CodeFormatError, Reason: "message has no TSIG record"}
+	}
+	return tr, key.verify(tr, tsigDigest(requestMAC, [][]byte{unsigned}, tr, false), now)
+}
+
+func (k TSIGKey) verify(tr TSIGRecord, digest []byte, now time.Time) error {
+	// Checks are made in the order given by RFC 8945 section 5.2
+	if !strings.EqualFold(strings.TrimSuffix(tr.Common.Domain, "."), strings.TrimSuffix(k.Name, ".")) {
+		return TSIGError{Code: CodeBadKey, Reason: fmt.Sprintf("unknown key %q", tr.Common.Domain)}
+	}
+	if !strings.EqualFold(strings.TrimSuffix(tr.Algorithm, "."), strings.TrimSuffix(k.Algorithm, ".")) {
+		return TSIGError{Code: CodeBadKey, Reason: fmt.Sprintf("algorithm %q does not match key", tr.Algorithm)}
+	}
+	expected, err := k.mac(digest)
+	if err != nil {
+		return TSIGError{Code: CodeBadKey, Reason: err.Error()}
+	}
+	// A truncated MAC must keep at least half of the digest, and no fewer
+	// than 10 octets; see RFC 8945 section 5.2.2.1
+	minLen := len(expected) / 2
+	if minLen < 10 {
+		minLen = 10
+	}
+	if len(tr.MAC) > len(expected) || len(tr.MAC) < minLen {
+		return TSIGError{Code: CodeFormatError, Reason: fmt.Sprintf("MAC length %d out of range", len(tr.MAC))}
+	}
+	if !hmac.Equal(tr.MAC, expected[:len(tr.MAC)]) {
+		return TSIGError{Code: CodeBadSig, Reason: "MAC does not match"}
+	}
+	skew := now.Unix() - int64(tr.TimeSigned)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > int64(tr.Fudge) {
+		return TSIGError{Code: CodeBadTime, Reason: fmt.Sprintf("time signed is %d seconds from now, fudge is %d", skew, tr.Fudge)}
+	}
+	return nil
+}
+
+// TSIGStreamSigner signs the messages of a multi-message response, such as
+// a zone transfer over TCP, chaining each MAC into the next as described in
+// RFC 8945 section 5.3.1.
+type TSIGStreamSigner struct {
+	key      TSIGKey
+	priorMAC []byte
+	signed   bool
+	pending  [][]byte
+}
+
+// NewTSIGStreamSigner returns a TSIGStreamSigner for a response to a request
+// whose TSIG record carried requestMAC.
+func NewTSIGStreamSigner(key TSIGKey, requestMAC []byte) *TSIGStreamSigner {
+	return &TSIGStreamSigner{key: key, priorMAC: requestMAC}
+}
+
+// Sign encodes dm and appends a TSIG record to it. The first message is
+// signed like a single response; later ones cover the prior MAC, every
+// message since, and only the TSIG timers.
+func (s *TSIGStreamSigner) Sign(dm DNSMessage, timeSigned time.Time) ([]byte, error) {
+	msg, err := dm.ToBytes()
+	if err != nil {
+		return nil, fmt.Errorf("DNSMessage.ToBytes: %s", err)
+	}
+	tr := s.key.newTSIGRecord(dm.Hdr.ID, timeSigned)
+	tr.MAC, err = s.key.mac(tsigDigest(s.priorMAC, append(s.pending, msg), tr, s.signed))
+	if err != nil {
+		return nil, err
+	}
+	s.priorMAC = tr.MAC
+	s.signed = true
+	s.pending = nil
+	return appendTSIG(msg, tr)
+}
+
+// AddUnsigned encodes dm without a TSIG record; it will be covered by the
+// next call to Sign. Only messages after the first may be left unsigned.
+func (s *TSIGStreamSigner) AddUnsigned(dm DNSMessage) ([]byte, error) {
+	if !s.signed {
+		return nil, fmt.Errorf("The first message of a stream must be signed")
+	}
+	if len(s.pending) >= maxUnsignedTSIGMessages {
+		return nil, fmt.Errorf("No more than %d consecutive messages may be unsigned", maxUnsignedTSIGMessages)
+	}
+	msg, err := dm.ToBytes()
+	if err != nil {
+		return nil, fmt.Errorf("DNSMessage.ToBytes: %s", err)
+	}
+	s.pending = append(s.pending, msg)
+	return msg, nil
+}
+
+// TSIGStreamVerifier is the receiving counterpart of TSIGStreamSigner.
+type TSIGStreamVerifier struct {
+	key      TSIGKey
+	priorMAC []byte
+	verified bool
+	pending  [][]byte
+}
+
+// NewTSIGStreamVerifier returns a TSIGStreamVerifier for the responses to a
+// request whose TSIG record carried requestMAC.
+func NewTSIGStreamVerifier(key TSIGKey, requestMAC []byte) *TSIGStreamVerifier {
+	return &TSIGStreamVerifier{key: key, priorMAC: requestMAC}
+}
+
+// Verify checks the next wire-format message of the stream, returning
+// whether it carried a TSIG record. Unsigned messages are held and covered
+// by the next signed one; call Done after the last message to ensure it was
+// signed.
+func (v *TSIGStreamVerifier) Verify(msg []byte, now time.Time) (bool, error) {
+	unsigned, tr, found, err := splitTSIG(msg)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		if !v.verified {
+			return false, TSIGError{Code: CodeFormatError, Reason: "first message of stream is unsigned"}
+		}
+		if len(v.pending) >= maxUnsignedTSIGMessages {
+			return false, TSIGError{Code: CodeFormatError, Reason: "too many consecutive unsigned messages"}
+		}
+		v.pending = append(v.pending, msg)
+		return false, nil
+	}
+
+	digest := tsigDigest(v.priorMAC, append(v.pending, unsigned), tr, v.verified)
+	if err = v.key.verify(tr, digest, now); err != nil {
+		return true, err
+	}
+	v.priorMAC = tr.MAC
+	v.verified = true
+	v.pending = nil
+	return true, nil
+}
+
+// Done reports an error if the stream ended with unsigned messages, which
+// RFC 8945 section 5.3.1 forbids.
+func (v *TSIGStreamVerifier) Done() error {
+	if !v.verified || len(v.pending) > 0 {
+		return TSIGError{Code: CodeFormatError, Reason: "last message of stream is unsigned"}
+	}
+	return nil
+}
+
+// tsigDigest builds the input to the MAC described in RFC 8945 section 4.3:
+// the prior (request) MAC if any, the message(s) without their TSIG record,
+// and either all the TSIG variables or just the timers.
+func tsigDigest(priorMAC []byte, msgs [][]byte, tr TSIGRecord, timersOnly bool) []byte {
+	var buf bytes.Buffer
+	if len(priorMAC) > 0 {
+		binary.Write(&buf, binary.BigEndian, uint16(len(priorMAC)))
+		buf.Write(priorMAC)
+	}
+	for _, msg := range msgs {
+		buf.Write(msg)
+	}
+	if timersOnly {
+		buf.Write(tr.timersBytes())
+		return buf.Bytes()
+	}
+	buf.Write(domain(tr.Common.Domain).toCanonicalBytes())
+	binary.Write(&buf, binary.BigEndian, uint16(ClassANY))
+	binary.Write(&buf, binary.BigEndian, uint32(0))
+	buf.Write(domain(tr.Algorithm).toCanonicalBytes())
+	buf.Write(tr.timersBytes())
+	binary.Write(&buf, binary.BigEndian, tr.Error)
+	binary.Write(&buf, binary.BigEndian, uint16(len(tr.OtherData)))
+	buf.Write(tr.OtherData)
+	return buf.Bytes()
+}
+
+// appendTSIG appends tr to the wire-format message msg and increments its
+// additional-record count.
+func appendTSIG(msg []byte, tr TSIGRecord) ([]byte, error) {
+	if len(msg) < 12 {
+		return nil, fmt.Errorf("Message too short for a header (%d bytes)", len(msg))
+	}
+	rrr, err := packRecord(tr)
+	if err != nil {
+		return nil, fmt.Errorf("packRecord: %s", err)
+	}
+	rrb, err := rrr.toBytes()
+	if err != nil {
+		return nil, fmt.Errorf("rawResourceRecord.toBytes: %s", err)
+	}
+	ret := make([]byte, len(msg), len(msg)+len(rrb))
+	copy(ret, msg)
+	arCount := binary.BigEndian.Uint16(ret[10:12])
+	binary.BigEndian.PutUint16(ret[10:12], arCount+1)
+	return append(ret, rrb...), nil
+}
+
+// splitTSIG finds a TSIG record as the last additional record of the
+// wire-format message msg. If there is one, it returns the message as it was
+// before signing (TSIG removed, additional-record count decremented and ID
+// restored to the original ID) along with the decoded record.
+func splitTSIG(msg []byte) ([]byte, TSIGRecord, bool, error) {
+	var tr TSIGRecord
+	d := NewDecoder(bytes.NewReader(msg))
+	rdh, err := d.nextRawDNSHeader()
+	if err != nil {
+		return nil, tr, false, fmt.Errorf("d.nextRawDNSHeader: %s", err)
+	}
+	if rdh.ArCount == 0 {
+		return msg, tr, false, nil
+	}
+	for i := 0; i < int(rdh.QdCount); i++ {
+		if _, err = d.nextRawQuestion(); err != nil {
+			return nil, tr, false, fmt.Errorf("d.nextRawQuestion: %s", err)
+		}
+	}
+	// Skip everything but the last record without interpreting RDATA, so
+	// that record types this package can't decode don't get in the way
+	numRecords := int(rdh.AnCount) + int(rdh.NSCount) + int(rdh.ArCount)
+	for i := 0; i < numRecords-1; i++ {
+		if _, err = d.nextRawDNSResourceRecord(); err != nil {
+			return nil, tr, false, fmt.Errorf("d.nextRawDNSResourceRecord: %s", err)
+		}
+	}
+	tsigOffset := d.rdr.offset
+	rdrr, err := d.nextRawDNSResourceRecord()
+	if err != nil {
+		return nil, tr, false, fmt.Errorf("d.nextRawDNSResourceRecord: %s", err)
+	}
+	if rdrr.static.Type != TypeTSIG {
+		return msg, tr, false, nil
+	}
+	tr, err = d.newTSIGRecordFromRawRR(rdrr)
+	if err != nil {
+		return nil, tr, false, fmt.Errorf("d.newTSIGRecordFromRawRR: %s", err)
+	}
+
+	unsigned := make([]byte, tsigOffset)
+	copy(unsigned, msg[:tsigOffset])
+	binary.BigEndian.PutUint16(unsigned[0:2], tr.OriginalID)
+	binary.BigEndian.PutUint16(unsigned[10:12], rdh.ArCount-1)
+	return unsigned, tr, true, nil
+}
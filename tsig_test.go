@@ -0,0 +1,214 @@
+package rawmdns
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func newTSIGTestMessage(id uint16) DNSMessage {
+	return DNSMessage{
+		Hdr: DNSHeader{
+			ID:             id,
+			OpCode:         OpCodeUpdate,
+			NumQuestions:   1,
+			NumNameServers: 1,
+		},
+		Questions: []DNSQuestion{
+			{Domain: "lab.example", Type: TypeSOA, Class: ClassINET},
+		},
+		Authority: []DNSResourceRecord{
+			ARecord{
+				Common: ResourceRecordCommon{
+					Domain: "printer.lab.example",
+					Type:   TypeA,
+					Class:  ClassINET,
+					TTL:    300,
+				},
+				Addr: []byte{10, 0, 0, 5},
+			},
+		},
+	}
+}
+
+func TestSignTSIG_verifyTSIG(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	for _, alg := range []string{TSIGHMACSHA1, TSIGHMACSHA256, TSIGHMACSHA384, TSIGHMACSHA512} {
+		key := TSIGKey{Name: "update-key.lab.example", Algorithm: alg, Secret: []byte("0123456789abcdef")}
+		signed, tr, err := SignTSIG(newTSIGTestMessage(0x1234), key, nil, now)
+		if err != nil {
+			t.Fatalf("%s: Unexpected error from SignTSIG: %s", alg, err)
+		}
+
+		decoder := NewDecoder(bytes.NewReader(signed))
+		dm, err := decoder.DecodeDNSMessage()
+		if err != nil {
+			t.Fatalf("%s: Unexpected error from DecodeDNSMessage: %s", alg, err)
+		}
+		if len(dm.Additional) != 1 {
+			t.Fatalf("%s: len(dm.Additional) is %d, expected 1", alg, len(dm.Additional))
+		}
+		same, reasons := tr.Equal(dm.Additional[0])
+		if !same {
+			t.Errorf("%s: decoded TSIG record differs:", alg)
+			for _, reason := range reasons {
+				t.Log(reason)
+			}
+		}
+
+		_, err = VerifyTSIG(signed, key, nil, now.Add(10*time.Second))
+		if err != nil {
+			t.Errorf("%s: Unexpected error from VerifyTSIG: %s", alg, err)
+		}
+	}
+}
+
+// The expected MAC here is built by hand from the layout in RFC 8945
+// section 4.3, rather than through tsigDigest.
+func TestSignTSIG_digestLayout(t *testing.T) {
+	key := TSIGKey{Name: "Key.Example.", Algorithm: TSIGHMACSHA256, Secret: []byte("secret")}
+	dm := newTSIGTestMessage(0xbeef)
+	msg, err := dm.ToBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error from dm.ToBytes: %s", err)
+	}
+
+	var digest []byte
+	digest = append(digest, msg...)
+	digest = append(digest, 3, 'k', 'e', 'y', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0)
+	digest = append(digest, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00)
+	digest = append(digest, 11, 'h', 'm', 'a', 'c', '-', 's', 'h', 'a', '2', '5', '6', 0)
+	digest = append(digest, 0x00, 0x00, 0x65, 0x53, 0xf1, 0x00, 0x01, 0x2c)
+	digest = append(digest, 0x00, 0x00, 0x00, 0x00)
+	h := hmac.New(sha256.New, key.Secret)
+	h.Write(digest)
+	expected := h.Sum(nil)
+
+	_, tr, err := SignTSIG(dm, key, nil, time.Unix(0x6553f100, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error from SignTSIG: %s", err)
+	}
+	if !bytes.Equal(tr.MAC, expected) {
+		t.Errorf("MAC: %x != %x", tr.MAC, expected)
+	}
+}
+
+func TestVerifyTSIG_failures(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	key := TSIGKey{Name: "update-key.lab.example", Algorithm: TSIGHMACSHA256, Secret: []byte("0123456789abcdef")}
+	signed, _, err := SignTSIG(newTSIGTestMessage(0x1234), key, nil, now)
+	if err != nil {
+		t.Fatalf("Unexpected error from SignTSIG: %s", err)
+	}
+
+	tampered := make([]byte, len(signed))
+	copy(tampered, signed)
+	tampered[20] ^= 0x20
+	wrongKey := key
+	wrongKey.Name = "other-key.lab.example"
+	wrongSecret := key
+	wrongSecret.Secret = []byte("fedcba9876543210")
+
+	testCases := []struct {
+		name     string
+		msg      []byte
+		key      TSIGKey
+		now      time.Time
+		expected ResponseCode
+	}{
+		{"tampered", tampered, key, now, CodeBadSig},
+		{"wrong key name", signed, wrongKey, now, CodeBadKey},
+		{"wrong secret", signed, wrongSecret, now, CodeBadSig},
+		{"outside fudge", signed, key, now.Add(301 * time.Second), CodeBadTime},
+	}
+	for _, tc := range testCases {
+		_, err := VerifyTSIG(tc.msg, tc.key, nil, tc.now)
+		tsigErr, ok := err.(TSIGError)
+		if !ok {
+			t.Errorf("%s: expected a TSIGError, got %v", tc.name, err)
+			continue
+		}
+		if tsigErr.Code != tc.expected {
+			t.Errorf("%s: Code %d != %d", tc.name, tsigErr.Code, tc.expected)
+		}
+	}
+}
+
+func TestVerifyTSIG_response(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	key := TSIGKey{Name: "update-key.lab.example", Algorithm: TSIGHMACSHA512, Secret: []byte("0123456789abcdef")}
+	_, reqTSIG, err := SignTSIG(newTSIGTestMessage(0x1234), key, nil, now)
+	if err != nil {
+		t.Fatalf("Unexpected error from SignTSIG: %s", err)
+	}
+
+	resp := DNSMessage{Hdr: DNSHeader{ID: 0x1234, IsResponse: true, OpCode: OpCodeUpdate}}
+	signed, _, err := SignTSIG(resp, key, reqTSIG.MAC, now)
+	if err != nil {
+		t.Fatalf("Unexpected error from SignTSIG: %s", err)
+	}
+	if _, err = VerifyTSIG(signed, key, reqTSIG.MAC, now); err != nil {
+		t.Errorf("Unexpected error from VerifyTSIG: %s", err)
+	}
+	if _, err = VerifyTSIG(signed, key, nil, now); err == nil {
+		t.Error("Expected error verifying a response without its request MAC, got none")
+	}
+
+	// A forwarder may have rewritten the ID; Original ID must be used instead
+	binary.BigEndian.PutUint16(signed[0:2], 0x9999)
+	if _, err = VerifyTSIG(signed, key, reqTSIG.MAC, now); err != nil {
+		t.Errorf("Unexpected error from VerifyTSIG after ID rewrite: %s", err)
+	}
+}
+
+func TestTSIGStream(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	key := TSIGKey{Name: "xfr-key.lab.example", Algorithm: TSIGHMACSHA256, Secret: []byte("0123456789abcdef")}
+	requestMAC := []byte("pretend this is the request MAC")
+
+	signer := NewTSIGStreamSigner(key, requestMAC)
+	var msgs [][]byte
+	for i := 0; i < 4; i++ {
+		dm := newTSIGTestMessage(0x4321)
+		var b []byte
+		var err error
+		if i == 1 || i == 2 {
+			b, err = signer.AddUnsigned(dm)
+		} else {
+			b, err = signer.Sign(dm, now)
+		}
+		if err != nil {
+			t.Fatalf("message %d: Unexpected error from signer: %s", i, err)
+		}
+		msgs = append(msgs, b)
+	}
+
+	verifier := NewTSIGStreamVerifier(key, requestMAC)
+	for i, msg := range msgs {
+		signed, err := verifier.Verify(msg, now)
+		if err != nil {
+			t.Fatalf("message %d: Unexpected error from Verify: %s", i, err)
+		}
+		if signed != (i == 0 || i == 3) {
+			t.Errorf("message %d: signed is %t", i, signed)
+		}
+	}
+	if err := verifier.Done(); err != nil {
+		t.Errorf("Unexpected error from Done: %s", err)
+	}
+
+	// Dropping a message from the middle of the stream must break the chain
+	verifier = NewTSIGStreamVerifier(key, requestMAC)
+	if _, err := verifier.Verify(msgs[0], now); err != nil {
+		t.Fatalf("Unexpected error from Verify: %s", err)
+	}
+	if _, err := verifier.Verify(msgs[1], now); err != nil {
+		t.Fatalf("Unexpected error from Verify: %s", err)
+	}
+	if _, err := verifier.Verify(msgs[3], now); err == nil {
+		t.Error("Expected error after dropping a message, got none")
+	}
+}
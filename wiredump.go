@@ -0,0 +1,536 @@
+package rawmdns
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// WireField describes one field of a wire-format message, as found by
+// DumpMessage.
+type WireField struct {
+	// Start and End are the offsets of the field's first byte and of the
+	// byte after its last.
+	Start int
+	End   int
+	// Path names the field, e.g. "answers[2].rdata.target.labels[0]".
+	Path string
+	Raw  []byte
+	// Value is the field's decoded value, in presentation format.
+	Value string
+	// PointerTarget is the offset a compression pointer refers to, or -1
+	// if the field is not a compression pointer.
+	PointerTarget int
+}
+
+// WireDump is the annotated layout of a wire-format message.
+type WireDump struct {
+	Bytes  []byte
+	Fields []WireField
+}
+
+// DumpMessage walks a wire-format message, recording the byte range, raw
+// bytes and decoded value of every field, down to individual labels and
+// compression pointers in domain names. Fields are in the order they appear,
+// and don't overlap. RDATA is broken down into fields for most types; the
+// rest get a single "rdata" field.
+//
+// If the message is malformed, DumpMessage returns the fields it found before
+// the problem, followed by a field named "unparsed" covering the rest of the
+// message, along with the error. Bytes after the last record are covered by
+// a field named "trailing".
+func DumpMessage(b []byte) (WireDump, error) {
+	wa := &wireAnnotator{msg: b, d: NewDecoder(bytes.NewReader(b))}
+	err := wa.message()
+
+	consumed := 0
+	if len(wa.fields) > 0 {
+		consumed = wa.fields[len(wa.fields)-1].End
+	}
+	if consumed < len(b) {
+		path := "trailing"
+		if err != nil {
+			path = "unparsed"
+		}
+		wa.add(consumed, len(b), path, "", -1)
+	}
+	return WireDump{Bytes: b, Fields: wa.fields}, err
+}
+
+// String renders the dump in the style of Wireshark: one line per field with
+// its offset, bytes, path and value, then a hex and ASCII dump of the whole
+// message like the packet bytes pane.
+func (wd WireDump) String() string {
+	var buf bytes.Buffer
+	for _, f := range wd.Fields {
+		rawHex := hex.EncodeToString(f.Raw)
+		if len(f.Raw) > 8 {
+			rawHex = hex.EncodeToString(f.Raw[:8]) + ".."
+		}
+		fmt.Fprintf(&buf, "%04x  %-18s  %s", f.Start, rawHex, f.Path)
+		if f.Value != "" {
+			fmt.Fprintf(&buf, ": %s", f.Value)
+		}
+		if f.PointerTarget >= 0 {
+			fmt.Fprintf(&buf, " (pointer to %04x)", f.PointerTarget)
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	for off := 0; off < len(wd.Bytes); off += 16 {
+		line := wd.Bytes[off:]
+		if len(line) > 16 {
+			line = line[:16]
+		}
+		fmt.Fprintf(&buf, "%04x  ", off)
+		for i := 0; i < 16; i++ {
+			if i == 8 {
+				buf.WriteByte(' ')
+			}
+			if i < len(line) {
+				fmt.Fprintf(&buf, "%02x ", line[i])
+			} else {
+				buf.WriteString("   ")
+			}
+		}
+		buf.WriteByte(' ')
+		for i, c := range line {
+			if i == 8 {
+				buf.WriteByte(' ')
+			}
+			if c < ' ' || c >= 0x7f {
+				c = '.'
+			}
+			buf.WriteByte(c)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// wireAnnotator reads a message through a Decoder, so that its readCounter
+// gives the offset of each field and its labelRecords the layout of each
+// domain name.
+type wireAnnotator struct {
+	msg    []byte
+	d      Decoder
+	fields []WireField
+}
+
+func (wa *wireAnnotator) offset() int {
+	return wa.d.rdr.offset
+}
+
+func (wa *wireAnnotator) add(start, end int, path, value string, target int) {
+	wa.fields = append(wa.fields, WireField{
+		Start:         start,
+		End:           end,
+		Path:          path,
+		Raw:           wa.msg[start:end],
+		Value:         value,
+		PointerTarget: target,
+	})
+}
+
+// read consumes n bytes as a field whose value is given by format.
+func (wa *wireAnnotator) read(n int, path string, format func([]byte) string) ([]byte, error) {
+	start := wa.offset()
+	b := make([]byte, n)
+	if _, err := io.ReadFull(wa.d.rdr, b); err != nil {
+		return nil, fmt.Errorf("%s: reading %d bytes at offset %d: %s", path, n, start, err)
+	}
+	wa.add(start, wa.offset(), path, format(b), -1)
+	return b, nil
+}
+
+func (wa *wireAnnotator) uint16(path string, format func(uint16) string) (uint16, error) {
+	b, err := wa.read(2, path, func(b []byte) string {
+		return format(binary.BigEndian.Uint16(b))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func decimal16(v uint16) string {
+	return fmt.Sprintf("%d", v)
+}
+
+// name reads a domain name, recording a field for each label, for the
+// compression pointer if there is one, and for the root label which ends an
+// uncompressed name.
+func (wa *wireAnnotator) name(path string) (rawLabels, error) {
+	start := wa.offset()
+	before := len(wa.d.labelRecords)
+	rls, err := wa.d.nextRawLabels()
+	if err != nil {
+		return nil, fmt.Errorf("%s: name at offset %d: %s", path, start, err)
+	}
+	for i, lr := range wa.d.labelRecords[before:] {
+		off := int(lr.offset)
+		switch {
+		case lr.isPtr:
+			target := wa.d.rawLabelsFromOffset(lr.targetOffset)
+			wa.add(off, off+2, path+".pointer", presentName(target.toDomain()), int(lr.targetOffset))
+		case lr.length == 0:
+			wa.add(off, off+1, path+".root", "", -1)
+		default:
+			wa.add(off, off+1+int(lr.length), fmt.Sprintf("%s.labels[%d]", path, i), presentCharacterString(lr.content), -1)
+		}
+	}
+	return rls, nil
+}
+
+var wireHeaderCounts = []string{"qdcount", "ancount", "nscount", "arcount"}
+
+func (wa *wireAnnotator) message() error {
+	if _, err := wa.uint16("header.id", decimal16); err != nil {
+		return err
+	}
+	_, err := wa.read(2, "header.flags", func(b []byte) string {
+		hdr := rawDNSHeader{Flag: [2]byte{b[0], b[1]}}.toDNSHeader()
+		return fmt.Sprintf("opcode: %s, status: %s, flags: [%s]", hdr.OpCode, hdr.ResponseCode, strings.Join(hdr.flagNames(), " "))
+	})
+	if err != nil {
+		return err
+	}
+	var counts [4]uint16
+	for i, name := range wireHeaderCounts {
+		if counts[i], err = wa.uint16("header."+name, decimal16); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < int(counts[0]); i++ {
+		if err = wa.question(fmt.Sprintf("questions[%d]", i)); err != nil {
+			return err
+		}
+	}
+	for s, section := range []string{"answers", "authority", "additional"} {
+		for i := 0; i < int(counts[s+1]); i++ {
+			if err = wa.record(fmt.Sprintf("%s[%d]", section, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (wa *wireAnnotator) question(path string) error {
+	if _, err := wa.name(path + ".name"); err != nil {
+		return err
+	}
+	if _, err := wa.uint16(path+".type", typeValue); err != nil {
+		return err
+	}
+	_, err := wa.uint16(path+".class", func(v uint16) string {
+		s := RecordClass(v & 0x7FFF).String()
+		if v&0x8000 != 0 {
+			s += ", unicast response requested"
+		}
+		return s
+	})
+	return err
+}
+
+func typeValue(v uint16) string {
+	return RecordType(v).String()
+}
+
+func (wa *wireAnnotator) record(path string) error {
+	var rrr rawResourceRecord
+	var err error
+	if rrr.domainLabels, err = wa.name(path + ".name"); err != nil {
+		return err
+	}
+	rt, err := wa.uint16(path+".type", typeValue)
+	if err != nil {
+		return err
+	}
+	rrr.static.Type = RecordType(rt)
+
+	classFormat := func(v uint16) string {
+		s := RecordClass(v & 0x7FFF).String()
+		if v&0x8000 != 0 {
+			s += ", cache flush"
+		}
+		return s
+	}
+	ttlFormat := func(b []byte) string {
+		return fmt.Sprintf("%d", binary.BigEndian.Uint32(b))
+	}
+	if rrr.static.Type == TypeOPT {
+		// RFC 6891 section 6.1.2 reuses CLASS and TTL
+		classFormat = func(v uint16) string {
+			return fmt.Sprintf("UDP payload size %d", v)
+		}
+		ttlFormat = func(b []byte) string {
+			s := fmt.Sprintf("extended RCODE %d, version %d", b[0], b[1])
+			if b[2]&0x80 != 0 {
+				s += ", flags: do"
+			}
+			return s
+		}
+	}
+	class, err := wa.uint16(path+".class", classFormat)
+	if err != nil {
+		return err
+	}
+	rrr.static.Class = RecordClass(class)
+	ttl, err := wa.read(4, path+".ttl", ttlFormat)
+	if err != nil {
+		return err
+	}
+	rrr.static.TTL = binary.BigEndian.Uint32(ttl)
+	if rrr.static.RDataLength, err = wa.uint16(path+".rdlength", decimal16); err != nil {
+		return err
+	}
+
+	rrr.rDataOffsetInMsg = wa.offset()
+	end := rrr.rDataOffsetInMsg + int(rrr.static.RDataLength)
+	if end > len(wa.msg) {
+		return fmt.Errorf("%s.rdlength: %d bytes of RDATA at offset %d overruns the message", path, rrr.static.RDataLength, rrr.rDataOffsetInMsg)
+	}
+	rrr.rData = wa.msg[rrr.rDataOffsetInMsg:end]
+	return wa.rdata(path+".rdata", rrr, end)
+}
+
+// wireKind says how to read and present one field of RDATA.
+type wireKind int
+
+const (
+	wireU8 wireKind = iota
+	wireU16
+	wireIPv4
+	wireIPv6
+	wireName
+	wireCharString
+	wireCharStrings // character-strings to the end of the RDATA
+	wireHexRest
+	wireBase64Rest
+	wireTextRest
+	wireTypeBitmap // type bitmap to the end of the RDATA
+	wireHexLen8    // a length octet followed by that many bytes, as hex
+	wireBase32Len8 // a length octet followed by that many bytes, as base32hex
+	wireEUI48
+	wireEUI64
+	wireILNP64
+)
+
+type wireRDataField struct {
+	name string
+	kind wireKind
+}
+
+// wireRDataLayouts describes the RDATA of each type whose fields can be read
+// one after another without looking back at earlier ones. Types not listed,
+// apart from OPT, SVCB and HTTPS which are handled specially, get a single
+// field for all their RDATA.
+var wireRDataLayouts = map[RecordType][]wireRDataField{
+	TypeA:          {{"address", wireIPv4}},
+	TypeAAAA:       {{"address", wireIPv6}},
+	TypePTR:        {{"ptrdname", wireName}},
+	TypeSRV:        {{"priority", wireU16}, {"weight", wireU16}, {"port", wireU16}, {"target", wireName}},
+	TypeTXT:        {{"text", wireCharStrings}},
+	TypeNSEC:       {{"next", wireName}, {"types", wireTypeBitmap}},
+	TypeNSEC3:      {{"algorithm", wireU8}, {"flags", wireU8}, {"iterations", wireU16}, {"salt", wireHexLen8}, {"next", wireBase32Len8}, {"types", wireTypeBitmap}},
+	TypeNSEC3PARAM: {{"algorithm", wireU8}, {"flags", wireU8}, {"iterations", wireU16}, {"salt", wireHexLen8}},
+	TypeNAPTR:      {{"order", wireU16}, {"preference", wireU16}, {"flags", wireCharString}, {"services", wireCharString}, {"regexp", wireCharString}, {"replacement", wireName}},
+	TypeURI:        {{"priority", wireU16}, {"weight", wireU16}, {"target", wireTextRest}},
+	TypeSSHFP:      {{"algorithm", wireU8}, {"fptype", wireU8}, {"fingerprint", wireHexRest}},
+	TypeTLSA:       {{"usage", wireU8}, {"selector", wireU8}, {"mtype", wireU8}, {"data", wireHexRest}},
+	TypeSMIMEA:     {{"usage", wireU8}, {"selector", wireU8}, {"mtype", wireU8}, {"data", wireHexRest}},
+	TypeOPENPGPKEY: {{"key", wireBase64Rest}},
+	TypeCAA:        {{"flags", wireU8}, {"tag", wireCharString}, {"value", wireTextRest}},
+	TypeCERT:       {{"type", wireU16}, {"keytag", wireU16}, {"algorithm", wireU8}, {"certificate", wireBase64Rest}},
+	TypeKX:         {{"preference", wireU16}, {"exchanger", wireName}},
+	TypeRP:         {{"mailbox", wireName}, {"txtdomain", wireName}},
+	TypeAFSDB:      {{"subtype", wireU16}, {"hostname", wireName}},
+	TypeX25:        {{"address", wireCharString}},
+	TypeNSAPPTR:    {{"ptrdname", wireName}},
+	TypeDNAME:      {{"target", wireName}},
+	TypeEUI48:      {{"address", wireEUI48}},
+	TypeEUI64:      {{"address", wireEUI64}},
+	TypeNID:        {{"preference", wireU16}, {"nodeid", wireILNP64}},
+	TypeL32:        {{"preference", wireU16}, {"locator", wireIPv4}},
+	TypeL64:        {{"preference", wireU16}, {"locator", wireILNP64}},
+	TypeLP:         {{"preference", wireU16}, {"fqdn", wireName}},
+}
+
+func (wa *wireAnnotator) rdata(path string, rrr rawResourceRecord, end int) error {
+	var err error
+	switch rrr.static.Type {
+	case TypeOPT:
+		err = wa.optRData(path, end)
+	case TypeSVCB, TypeHTTPS:
+		err = wa.svcbRData(path, end)
+	default:
+		layout, found := wireRDataLayouts[rrr.static.Type]
+		if !found {
+			return wa.opaqueRData(path, rrr, end)
+		}
+		for _, field := range layout {
+			if err = wa.rdataField(path+"."+field.name, field.kind, end); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if wa.offset() > end {
+		return fmt.Errorf("%s: fields overrun RDLENGTH by %d bytes", path, wa.offset()-end)
+	}
+	if wa.offset() < end {
+		_, err = wa.read(end-wa.offset(), path+".trailing", func([]byte) string { return "" })
+	}
+	return err
+}
+
+// opaqueRData records the RDATA as a single field, with the value the
+// record's String method gives it if it decodes.
+func (wa *wireAnnotator) opaqueRData(path string, rrr rawResourceRecord, end int) error {
+	value := presentGenericRData(rrr.rData)
+	rr, err := recordForType(rrr.static.Type).UnpackRData(RData{rrr: rrr, d: &wa.d})
+	if err == nil {
+		if s, ok := presentRData(rr); ok {
+			value = s
+		}
+	}
+	_, err = wa.read(end-wa.offset(), path, func([]byte) string { return value })
+	return err
+}
+
+func (wa *wireAnnotator) rdataField(path string, kind wireKind, end int) error {
+	remaining := end - wa.offset()
+	var err error
+	switch kind {
+	case wireU8:
+		_, err = wa.read(1, path, func(b []byte) string { return fmt.Sprintf("%d", b[0]) })
+	case wireU16:
+		_, err = wa.uint16(path, decimal16)
+	case wireIPv4:
+		_, err = wa.read(4, path, func(b []byte) string { return net.IP(b).String() })
+	case wireIPv6:
+		_, err = wa.read(16, path, func(b []byte) string { return net.IP(b).String() })
+	case wireName:
+		_, err = wa.name(path)
+	case wireCharString:
+		err = wa.charString(path)
+	case wireCharStrings:
+		for i := 0; wa.offset() < end && err == nil; i++ {
+			err = wa.charString(fmt.Sprintf("%s[%d]", path, i))
+		}
+	case wireHexRest:
+		_, err = wa.read(remaining, path, presentHex)
+	case wireBase64Rest:
+		_, err = wa.read(remaining, path, base64.StdEncoding.EncodeToString)
+	case wireTextRest:
+		_, err = wa.read(remaining, path, func(b []byte) string { return presentCharacterString(string(b)) })
+	case wireTypeBitmap:
+		_, err = wa.read(remaining, path, func(b []byte) string {
+			return presentTypes(readTypeBitMap(bytes.NewReader(b)))
+		})
+	case wireHexLen8, wireBase32Len8:
+		var length []byte
+		if length, err = wa.read(1, path+".length", func(b []byte) string { return fmt.Sprintf("%d", b[0]) }); err != nil {
+			return err
+		}
+		format := presentHex
+		if kind == wireBase32Len8 {
+			format = func(b []byte) string { return strings.ToLower(nsec3Encoding.EncodeToString(b)) }
+		}
+		_, err = wa.read(int(length[0]), path, format)
+	case wireEUI48:
+		_, err = wa.read(6, path, presentEUI)
+	case wireEUI64:
+		_, err = wa.read(8, path, presentEUI)
+	case wireILNP64:
+		_, err = wa.read(8, path, func(b []byte) string { return presentILNP64(binary.BigEndian.Uint64(b)) })
+	}
+	if err == nil && wa.offset() > end {
+		err = fmt.Errorf("%s: overruns RDLENGTH by %d bytes", path, wa.offset()-end)
+	}
+	return err
+}
+
+func (wa *wireAnnotator) charString(path string) error {
+	length, err := wa.read(1, path+".length", func(b []byte) string { return fmt.Sprintf("%d", b[0]) })
+	if err != nil {
+		return err
+	}
+	_, err = wa.read(int(length[0]), path, func(b []byte) string { return presentCharacterString(string(b)) })
+	return err
+}
+
+// ednsOptionNames names the EDNS options most often seen in mDNS traffic.
+var ednsOptionNames = map[uint16]string{
+	3:  "NSID",
+	4:  "Owner",
+	8:  "Client Subnet",
+	10: "Cookie",
+	12: "Padding",
+}
+
+func (wa *wireAnnotator) optRData(path string, end int) error {
+	for i := 0; wa.offset() < end; i++ {
+		optPath := fmt.Sprintf("%s.options[%d]", path, i)
+		_, err := wa.uint16(optPath+".code", func(v uint16) string {
+			if name, found := ednsOptionNames[v]; found {
+				return fmt.Sprintf("%d (%s)", v, name)
+			}
+			return fmt.Sprintf("%d", v)
+		})
+		if err != nil {
+			return err
+		}
+		length, err := wa.uint16(optPath+".length", decimal16)
+		if err != nil {
+			return err
+		}
+		if _, err = wa.read(int(length), optPath+".data", presentHex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wa *wireAnnotator) svcbRData(path string, end int) error {
+	if _, err := wa.uint16(path+".priority", decimal16); err != nil {
+		return err
+	}
+	if _, err := wa.name(path + ".target"); err != nil {
+		return err
+	}
+	for i := 0; wa.offset() < end; i++ {
+		paramPath := fmt.Sprintf("%s.params[%d]", path, i)
+		key, err := wa.uint16(paramPath+".key", func(v uint16) string {
+			return SvcParamKey(v).String()
+		})
+		if err != nil {
+			return err
+		}
+		length, err := wa.uint16(paramPath+".length", decimal16)
+		if err != nil {
+			return err
+		}
+		_, err = wa.read(int(length), paramPath+".value", func(b []byte) string {
+			param, err := unpackSvcParam(SvcParamKey(key), b)
+			if err != nil {
+				return fmt.Sprintf("invalid: %s", err)
+			}
+			return svcParamString(param)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
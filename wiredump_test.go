@@ -0,0 +1,130 @@
+package rawmdns
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// checkWireDumpLayout checks that the fields of a dump cover the message
+// exactly, without gaps or overlaps.
+func checkWireDumpLayout(t *testing.T, wd WireDump) {
+	next := 0
+	for _, f := range wd.Fields {
+		if f.Start != next {
+			t.Errorf("Field %s starts at %d, expected %d", f.Path, f.Start, next)
+		}
+		if !bytes.Equal(f.Raw, wd.Bytes[f.Start:f.End]) {
+			t.Errorf("Field %s has Raw %x, expected %x", f.Path, f.Raw, wd.Bytes[f.Start:f.End])
+		}
+		next = f.End
+	}
+	if next != len(wd.Bytes) {
+		t.Errorf("Fields end at %d, message is %d bytes", next, len(wd.Bytes))
+	}
+}
+
+func TestDumpMessage_capture(t *testing.T) {
+	b, err := os.ReadFile("testdata/airplay-answer.cap")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %s", err)
+	}
+	wd, err := DumpMessage(b)
+	if err != nil {
+		t.Fatalf("DumpMessage: %s", err)
+	}
+	checkWireDumpLayout(t, wd)
+	pointers := 0
+	for _, f := range wd.Fields {
+		if f.PointerTarget >= 0 {
+			pointers++
+			if f.PointerTarget >= f.Start || f.Value == "" {
+				t.Errorf("Bad pointer field %+v", f)
+			}
+		}
+		if f.Path == "unparsed" || f.Path == "trailing" {
+			t.Errorf("Unexpected field %+v", f)
+		}
+	}
+	if pointers == 0 {
+		t.Errorf("Expected some compression pointers in %s", wd)
+	}
+}
+
+func TestDumpMessage(t *testing.T) {
+	// An SRV answer whose owner points at the question name, and whose
+	// target points into the middle of it
+	b := []byte{
+		0x00, 0x00, 0x84, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		// 0x0c: question printer._ipp._tcp.local SRV QU
+		7, 'p', 'r', 'i', 'n', 't', 'e', 'r',
+		4, '_', 'i', 'p', 'p', 4, '_', 't', 'c', 'p', 5, 'l', 'o', 'c', 'a', 'l', 0,
+		0x00, 0x21, 0x80, 0x01,
+		// answer
+		0xc0, 0x0c, 0x00, 0x21, 0x80, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x0b,
+		0x00, 0x00, 0x00, 0x00, 0x02, 0x77,
+		2, 'p', 'c', 0xc0, 0x19,
+	}
+	wd, err := DumpMessage(b)
+	if err != nil {
+		t.Fatalf("DumpMessage: %s", err)
+	}
+	checkWireDumpLayout(t, wd)
+
+	expected := map[string]string{
+		"header.flags":                      "opcode: QUERY, status: NOERROR, flags: [qr aa]",
+		"questions[0].name.labels[1]":       `"_ipp"`,
+		"questions[0].class":                "IN, unicast response requested",
+		"answers[0].name.pointer":           "printer._ipp._tcp.local.",
+		"answers[0].class":                  "IN, cache flush",
+		"answers[0].rdata.port":             "631",
+		"answers[0].rdata.target.labels[0]": `"pc"`,
+		"answers[0].rdata.target.pointer":   "_tcp.local.",
+	}
+	found := map[string]WireField{}
+	for _, f := range wd.Fields {
+		found[f.Path] = f
+	}
+	for path, value := range expected {
+		f, ok := found[path]
+		if !ok {
+			t.Errorf("No field %s in:\n%s", path, wd)
+			continue
+		}
+		if f.Value != value {
+			t.Errorf("Field %s is %q, expected %q", path, f.Value, value)
+		}
+	}
+	if target := found["answers[0].rdata.target.pointer"]; target.PointerTarget != 0x19 || target.Start != 0x3e {
+		t.Errorf("Bad target pointer %+v", target)
+	}
+
+	s := wd.String()
+	for _, line := range []string{
+		"003e  c019                answers[0].rdata.target.pointer: _tcp.local. (pointer to 0019)\n",
+		"0000  00 00 84 00 00 01 00 01  00 00 00 00 07 70 72 69  ........ .....pri\n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("String() is missing %q:\n%s", line, s)
+		}
+	}
+}
+
+func TestDumpMessage_truncated(t *testing.T) {
+	b := []byte{
+		0x00, 0x00, 0x84, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		5, 'l', 'o', 'c', 'a', 'l', 0,
+		0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x04,
+		10, 0, 0,
+	}
+	wd, err := DumpMessage(b)
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+	checkWireDumpLayout(t, wd)
+	last := wd.Fields[len(wd.Fields)-1]
+	if last.Path != "unparsed" || last.Start != 0x1d {
+		t.Errorf("Last field is %+v, expected unparsed RDATA at 0x1d", last)
+	}
+}
@@ -0,0 +1,1556 @@
+package rawmdns
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxZoneIncludeDepth bounds nested $INCLUDEs, so that a file including
+// itself fails rather than recursing forever.
+const maxZoneIncludeDepth = 16
+
+// ZoneParseError is returned by ParseZone and NewRR for malformed input. Line
+// and Column are 1-based; Column counts bytes.
+type ZoneParseError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (zpe ZoneParseError) Error() string {
+	if zpe.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", zpe.File, zpe.Line, zpe.Column, zpe.Msg)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", zpe.Line, zpe.Column, zpe.Msg)
+}
+
+// ParseZone reads records in the RFC 1035 section 5 master file format from
+// r, returning them in the order they appear. Relative names are completed
+// with origin until a $ORIGIN directive changes it. $INCLUDE directives are
+// read from fsys; if fsys is nil, they are an error.
+//
+// Every type with a String method in this package can be parsed from that
+// same format, as can NS, CNAME, SOA, HINFO, MX and the rest of RFC 1035's
+// types, which are returned as an UnknownRecord holding their uncompressed
+// RDATA. Any type at all can be parsed from the RFC 3597 generic format, e.g.
+// "TYPE65280 \# 2 ABCD". A class with the top bit set, e.g. CLASS32769,
+// is read as the mDNS cache-flush bit plus the class in the low 15 bits.
+//
+// Domain names in the returned records have no trailing dot, as with records
+// returned by the Decoder. Escaped dots inside a label (`\.`) cannot be
+// represented and are an error.
+func ParseZone(r io.Reader, origin string, fsys fs.FS) ([]DNSResourceRecord, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll: %s", err)
+	}
+	zp := &zoneParser{fsys: fsys, class: ClassINET}
+	if origin = strings.TrimSuffix(origin, "."); origin != "" {
+		zp.origin, err = zp.parseName(zoneToken{raw: origin + "."}, true)
+		if err != nil {
+			return nil, fmt.Errorf("bad origin %q: %s", origin, err)
+		}
+	}
+	err = zp.parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return zp.records, nil
+}
+
+// NewRR parses a single record in master file format, e.g.
+// "printer.local. 120 IN A 10.0.0.5". Names are relative to the root, and a
+// TTL must be given.
+func NewRR(s string) (DNSResourceRecord, error) {
+	rrs, err := ParseZone(strings.NewReader(s), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(rrs) != 1 {
+		return nil, fmt.Errorf("expected 1 record, found %d", len(rrs))
+	}
+	return rrs[0], nil
+}
+
+type zoneToken struct {
+	raw    string // as written, with escapes intact and without quotes
+	quoted bool
+	line   int
+	col    int
+}
+
+// zoneEntry is one logical line of a master file, i.e. a directive or a
+// record, which parentheses may have spread across several physical lines.
+type zoneEntry struct {
+	tokens []zoneToken
+	// blankOwner is set if the entry starts with whitespace, meaning the
+	// owner is the same as that of the previous record
+	blankOwner bool
+}
+
+type zoneParser struct {
+	fsys    fs.FS
+	file    string
+	depth   int
+	origin  string
+	records []DNSResourceRecord
+
+	defaultTTL    uint32
+	hasDefaultTTL bool
+	lastOwner     string
+	hasLastOwner  bool
+	lastTTL       uint32
+	hasLastTTL    bool
+	class         RecordClass
+}
+
+func (zp *zoneParser) errorf(tok zoneToken, format string, args ...interface{}) error {
+	return ZoneParseError{File: zp.file, Line: tok.line, Column: tok.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (zp *zoneParser) lex(src []byte) ([]zoneEntry, error) {
+	var entries []zoneEntry
+	var cur zoneEntry
+	line, lineStart := 1, 0
+	parens := 0
+	var openParen zoneToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		col := i - lineStart + 1
+		switch {
+		case c == '\n':
+			if parens == 0 {
+				if len(cur.tokens) > 0 {
+					entries = append(entries, cur)
+				}
+				cur = zoneEntry{}
+			}
+			i++
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == '\r':
+			if i == lineStart && parens == 0 && len(cur.tokens) == 0 {
+				cur.blankOwner = true
+			}
+			i++
+		case c == ';':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			if parens == 0 {
+				openParen = zoneToken{line: line, col: col}
+			}
+			parens++
+			i++
+		case c == ')':
+			if parens == 0 {
+				return nil, zp.errorf(zoneToken{line: line, col: col}, "unbalanced ')'")
+			}
+			parens--
+			i++
+		case c == '"':
+			start := i + 1
+			for i++; ; i++ {
+				if i >= len(src) || src[i] == '\n' {
+					return nil, zp.errorf(zoneToken{line: line, col: col}, "unterminated quoted string")
+				}
+				if src[i] == '\\' && i+1 < len(src) && src[i+1] != '\n' {
+					i++
+					continue
+				}
+				if src[i] == '"' {
+					break
+				}
+			}
+			cur.tokens = append(cur.tokens, zoneToken{raw: string(src[start:i]), quoted: true, line: line, col: col})
+			i++
+		default:
+			start := i
+			for i < len(src) {
+				c = src[i]
+				if c == '\\' && i+1 < len(src) && src[i+1] != '\n' {
+					i += 2
+					continue
+				}
+				if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '(' || c == ')' {
+					break
+				}
+				i++
+			}
+			cur.tokens = append(cur.tokens, zoneToken{raw: string(src[start:i]), line: line, col: col})
+		}
+	}
+	if parens > 0 {
+		return nil, zp.errorf(openParen, "unbalanced '('")
+	}
+	if len(cur.tokens) > 0 {
+		entries = append(entries, cur)
+	}
+	return entries, nil
+}
+
+func (zp *zoneParser) parse(src []byte) error {
+	entries, err := zp.lex(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		first := entry.tokens[0]
+		if !entry.blankOwner && !first.quoted && strings.HasPrefix(first.raw, "$") {
+			err = zp.directive(entry.tokens)
+		} else {
+			err = zp.record(entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (zp *zoneParser) directive(tokens []zoneToken) error {
+	switch strings.ToUpper(tokens[0].raw) {
+	case "$ORIGIN":
+		if len(tokens) != 2 {
+			return zp.errorf(tokens[0], "$ORIGIN takes exactly one domain name")
+		}
+		origin, err := zp.parseName(tokens[1], false)
+		if err != nil {
+			return err
+		}
+		zp.origin = origin
+	case "$TTL":
+		if len(tokens) != 2 {
+			return zp.errorf(tokens[0], "$TTL takes exactly one TTL")
+		}
+		ttl, err := parseZoneTTL(tokens[1].raw)
+		if err != nil {
+			return zp.errorf(tokens[1], "bad TTL %q: %s", tokens[1].raw, err)
+		}
+		zp.defaultTTL, zp.hasDefaultTTL = ttl, true
+	case "$INCLUDE":
+		if len(tokens) < 2 || len(tokens) > 3 {
+			return zp.errorf(tokens[0], "$INCLUDE takes a file name and an optional domain name")
+		}
+		return zp.include(tokens)
+	default:
+		return zp.errorf(tokens[0], "unknown directive %s", tokens[0].raw)
+	}
+	return nil
+}
+
+// include parses another file as if it appeared in place of the $INCLUDE.
+// Per RFC 1035 section 5.1, any origin it sets does not outlast it.
+func (zp *zoneParser) include(tokens []zoneToken) error {
+	if zp.fsys == nil {
+		return zp.errorf(tokens[0], "$INCLUDE is not allowed here")
+	}
+	if zp.depth >= maxZoneIncludeDepth {
+		return zp.errorf(tokens[0], "$INCLUDE nested more than %d deep", maxZoneIncludeDepth)
+	}
+	fileName, err := unescapeZone(tokens[1].raw)
+	if err != nil {
+		return zp.errorf(tokens[1], "bad file name: %s", err)
+	}
+	src, err := fs.ReadFile(zp.fsys, string(fileName))
+	if err != nil {
+		return zp.errorf(tokens[1], "$INCLUDE: %s", err)
+	}
+
+	child := *zp
+	child.file = string(fileName)
+	child.depth++
+	child.records = nil
+	if len(tokens) == 3 {
+		child.origin, err = zp.parseName(tokens[2], false)
+		if err != nil {
+			return err
+		}
+	}
+	err = child.parse(src)
+	if err != nil {
+		return err
+	}
+	zp.records = append(zp.records, child.records...)
+	zp.defaultTTL, zp.hasDefaultTTL = child.defaultTTL, child.hasDefaultTTL
+	zp.lastOwner, zp.hasLastOwner = child.lastOwner, child.hasLastOwner
+	zp.lastTTL, zp.hasLastTTL = child.lastTTL, child.hasLastTTL
+	zp.class = child.class
+	return nil
+}
+
+func (zp *zoneParser) record(entry zoneEntry) error {
+	tokens := entry.tokens
+	common := ResourceRecordCommon{}
+	if entry.blankOwner {
+		if !zp.hasLastOwner {
+			return zp.errorf(tokens[0], "no owner name, and no previous record to take it from")
+		}
+		common.Domain = zp.lastOwner
+	} else {
+		owner, err := zp.parseName(tokens[0], false)
+		if err != nil {
+			return err
+		}
+		common.Domain = owner
+		tokens = tokens[1:]
+	}
+
+	// The TTL and class are both optional, and may come in either order
+	hasTTL, hasClass := false, false
+	var typeTok zoneToken
+	for {
+		if len(tokens) == 0 {
+			return zp.errorf(entry.tokens[len(entry.tokens)-1], "missing type")
+		}
+		tok := tokens[0]
+		tokens = tokens[1:]
+		if ttl, err := parseZoneTTL(tok.raw); err == nil && !hasTTL && !tok.quoted {
+			common.TTL, hasTTL = ttl, true
+			continue
+		}
+		// "ANY" is both a class and a type, so it is only a class if
+		// something follows it
+		if class, ok := parseZoneClass(tok.raw); ok && !hasClass && len(tokens) > 0 {
+			_, isType := parseZoneType(tok.raw)
+			_, nextIsType := parseZoneType(tokens[0].raw)
+			_, nextTTLErr := parseZoneTTL(tokens[0].raw)
+			if !isType || nextIsType || nextTTLErr == nil {
+				zp.class, hasClass = class, true
+				continue
+			}
+		}
+		typeTok = tok
+		break
+	}
+	rt, ok := parseZoneType(typeTok.raw)
+	if !ok {
+		return zp.errorf(typeTok, "unknown type %q", typeTok.raw)
+	}
+	common.Type = rt
+	common.Class = zp.class & 0x7FFF
+	common.CacheFlush = zp.class&0x8000 == 0x8000
+
+	if !hasTTL {
+		switch {
+		case zp.hasDefaultTTL:
+			common.TTL = zp.defaultTTL
+		case zp.hasLastTTL:
+			common.TTL = zp.lastTTL
+		default:
+			return zp.errorf(typeTok, "no TTL given, and no $TTL or previous TTL to use")
+		}
+	}
+
+	p := &rdataParser{zp: zp, tokens: tokens, last: typeTok}
+	rr, err := p.parse(common)
+	if err != nil {
+		return err
+	}
+	zp.records = append(zp.records, rr)
+	zp.lastOwner, zp.hasLastOwner = common.Domain, true
+	zp.lastTTL, zp.hasLastTTL = common.TTL, true
+	return nil
+}
+
+// parseName converts a master-file domain name to the form used throughout
+// this package: labels joined by dots, with no trailing dot. Relative names
+// are completed with the current origin.
+func (zp *zoneParser) parseName(tok zoneToken, noOrigin bool) (string, error) {
+	if tok.quoted {
+		return "", zp.errorf(tok, "domain name %q may not be quoted", tok.raw)
+	}
+	raw := tok.raw
+	if raw == "@" {
+		return zp.origin, nil
+	}
+	if raw == "." {
+		return "", nil
+	}
+
+	var labels []string
+	var label []byte
+	absolute := false
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\' && i+3 < len(raw) && isDecimalDigits(raw[i+1:i+4]):
+			label = append(label, raw[i:i+4]...)
+			i += 3
+		case raw[i] == '\\' && i+1 < len(raw):
+			label = append(label, raw[i:i+2]...)
+			i++
+		case raw[i] == '.':
+			if len(label) == 0 {
+				return "", zp.errorf(tok, "empty label in domain name %q", raw)
+			}
+			unescaped, err := unescapeZone(string(label))
+			if err != nil {
+				return "", zp.errorf(tok, "bad domain name %q: %s", raw, err)
+			}
+			labels = append(labels, string(unescaped))
+			label = nil
+			absolute = i == len(raw)-1
+		default:
+			label = append(label, raw[i])
+		}
+	}
+	if len(label) > 0 {
+		unescaped, err := unescapeZone(string(label))
+		if err != nil {
+			return "", zp.errorf(tok, "bad domain name %q: %s", raw, err)
+		}
+		labels = append(labels, string(unescaped))
+	}
+	for _, l := range labels {
+		if strings.Contains(l, ".") {
+			return "", zp.errorf(tok, "escaped dot in a label of %q is not supported", raw)
+		}
+		if len(l) > 63 {
+			return "", zp.errorf(tok, "label %q is longer than 63 bytes", l)
+		}
+	}
+
+	name := strings.Join(labels, ".")
+	if !absolute && !noOrigin && zp.origin != "" {
+		name += "." + zp.origin
+	}
+	if len(PackName(name)) > 255 {
+		return "", zp.errorf(tok, "domain name %q is longer than 255 bytes", name)
+	}
+	return name, nil
+}
+
+func isDecimalDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeZone resolves the \X and \DDD escapes of RFC 1035 section 5.1.
+func unescapeZone(raw string) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			out = append(out, raw[i])
+			continue
+		}
+		if i+1 >= len(raw) {
+			return nil, fmt.Errorf("trailing backslash")
+		}
+		if raw[i+1] >= '0' && raw[i+1] <= '9' {
+			if i+3 >= len(raw) || !isDecimalDigits(raw[i+1:i+4]) {
+				return nil, fmt.Errorf("\\DDD escape needs exactly 3 digits")
+			}
+			v, _ := strconv.Atoi(raw[i+1 : i+4])
+			if v > 255 {
+				return nil, fmt.Errorf("\\%s escape is greater than 255", raw[i+1:i+4])
+			}
+			out = append(out, byte(v))
+			i += 3
+			continue
+		}
+		out = append(out, raw[i+1])
+		i++
+	}
+	return out, nil
+}
+
+// parseZoneTTL parses a TTL given either in seconds or, as BIND allows, as a
+// sum of weeks, days, hours, minutes and seconds such as "1h30m".
+func parseZoneTTL(s string) (uint32, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty TTL")
+	}
+	if v, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return uint32(v), nil
+	}
+	var total, cur uint64
+	digits := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= '0' && c <= '9' {
+			cur = cur*10 + uint64(c-'0')
+			digits = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("unit without a number")
+		}
+		switch c {
+		case 'w', 'W':
+			cur *= 7 * 24 * 3600
+		case 'd', 'D':
+			cur *= 24 * 3600
+		case 'h', 'H':
+			cur *= 3600
+		case 'm', 'M':
+			cur *= 60
+		case 's', 'S':
+		default:
+			return 0, fmt.Errorf("unknown unit %q", c)
+		}
+		total += cur
+		cur, digits = 0, false
+		if total > 0xFFFFFFFF {
+			return 0, fmt.Errorf("TTL too large")
+		}
+	}
+	if digits {
+		return 0, fmt.Errorf("number without a unit")
+	}
+	return uint32(total), nil
+}
+
+func parseZoneClass(s string) (RecordClass, bool) {
+	upper := strings.ToUpper(s)
+	for class, name := range recordClassNames {
+		if upper == name {
+			return class, true
+		}
+	}
+	if strings.HasPrefix(upper, "CLASS") {
+		v, err := strconv.ParseUint(upper[len("CLASS"):], 10, 16)
+		if err == nil {
+			return RecordClass(v), true
+		}
+	}
+	return 0, false
+}
+
+func parseZoneType(s string) (RecordType, bool) {
+	upper := strings.ToUpper(s)
+	for rt, name := range recordTypeNames {
+		if upper == name {
+			return rt, true
+		}
+	}
+	if strings.HasPrefix(upper, "TYPE") {
+		v, err := strconv.ParseUint(upper[len("TYPE"):], 10, 16)
+		if err == nil {
+			return RecordType(v), true
+		}
+	}
+	return 0, false
+}
+
+// rdataParser consumes the RDATA tokens of a single record.
+type rdataParser struct {
+	zp     *zoneParser
+	tokens []zoneToken
+	pos    int
+	// last is the most recently consumed token, used to place errors about
+	// missing fields
+	last zoneToken
+}
+
+func (p *rdataParser) more() bool {
+	return p.pos < len(p.tokens)
+}
+
+func (p *rdataParser) next(what string) (zoneToken, error) {
+	if !p.more() {
+		return zoneToken{}, p.zp.errorf(p.last, "missing %s", what)
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	p.last = tok
+	return tok, nil
+}
+
+func (p *rdataParser) done() error {
+	if p.more() {
+		tok := p.tokens[p.pos]
+		return p.zp.errorf(tok, "unexpected %q after RDATA", tok.raw)
+	}
+	return nil
+}
+
+func (p *rdataParser) name(what string) (string, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return "", err
+	}
+	return p.zp.parseName(tok, false)
+}
+
+func (p *rdataParser) uint(what string, bits int) (uint64, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(tok.raw, 10, bits)
+	if err != nil {
+		return 0, p.zp.errorf(tok, "bad %s %q: not a %d-bit unsigned integer", what, tok.raw, bits)
+	}
+	return v, nil
+}
+
+func (p *rdataParser) uint8(what string) (uint8, error) {
+	v, err := p.uint(what, 8)
+	return uint8(v), err
+}
+
+func (p *rdataParser) uint16(what string) (uint16, error) {
+	v, err := p.uint(what, 16)
+	return uint16(v), err
+}
+
+// text reads a <character-string>, quoted or not.
+func (p *rdataParser) text(what string) (string, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return "", err
+	}
+	b, err := unescapeZone(tok.raw)
+	if err != nil {
+		return "", p.zp.errorf(tok, "bad %s: %s", what, err)
+	}
+	return string(b), nil
+}
+
+func (p *rdataParser) ip(what string, v4 bool) (net.IP, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(tok.raw)
+	if ip == nil || (ip.To4() != nil) != v4 {
+		return nil, p.zp.errorf(tok, "bad %s %q", what, tok.raw)
+	}
+	if v4 {
+		return ip.To4(), nil
+	}
+	return ip, nil
+}
+
+// rest joins all remaining tokens, for base64 and hex fields which may be
+// split by whitespace.
+func (p *rdataParser) rest(what string) (string, zoneToken, error) {
+	first, err := p.next(what)
+	if err != nil {
+		return "", first, err
+	}
+	s := first.raw
+	for p.more() {
+		tok, _ := p.next(what)
+		s += tok.raw
+	}
+	return s, first, nil
+}
+
+func (p *rdataParser) base64Rest(what string) ([]byte, error) {
+	s, tok, err := p.rest(what)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, p.zp.errorf(tok, "bad %s: %s", what, err)
+	}
+	return b, nil
+}
+
+// hexRest reads the remaining tokens as hex; "-" stands for no data.
+func (p *rdataParser) hexRest(what string) ([]byte, error) {
+	s, tok, err := p.rest(what)
+	if err != nil {
+		return nil, err
+	}
+	if s == "-" {
+		return nil, nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, p.zp.errorf(tok, "bad %s: %s", what, err)
+	}
+	return b, nil
+}
+
+func (p *rdataParser) hexField(what string) ([]byte, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return nil, err
+	}
+	if tok.raw == "-" {
+		return nil, nil
+	}
+	b, err := hex.DecodeString(tok.raw)
+	if err != nil {
+		return nil, p.zp.errorf(tok, "bad %s: %s", what, err)
+	}
+	return b, nil
+}
+
+func (p *rdataParser) types() ([]RecordType, error) {
+	var types []RecordType
+	for p.more() {
+		tok, _ := p.next("type")
+		rt, ok := parseZoneType(tok.raw)
+		if !ok {
+			return nil, p.zp.errorf(tok, "unknown type %q", tok.raw)
+		}
+		types = append(types, rt)
+	}
+	return types, nil
+}
+
+func (p *rdataParser) parse(common ResourceRecordCommon) (DNSResourceRecord, error) {
+	if p.more() && !p.tokens[p.pos].quoted && p.tokens[p.pos].raw == `\#` {
+		return p.generic(common)
+	}
+	parser, found := zoneRDataParsers[common.Type]
+	if !found {
+		return nil, p.zp.errorf(p.last, "no presentation format for type %s, use the RFC 3597 \\# format", common.Type)
+	}
+	rr, err := parser(p, common)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.done(); err != nil {
+		return nil, err
+	}
+	// Catch anything else the type can't encode, e.g. an out of range
+	// field, here rather than when the record is first sent
+	if _, err = rr.PackRData(); err != nil {
+		return nil, p.zp.errorf(p.tokens[0], "invalid RDATA: %s", err)
+	}
+	return rr, nil
+}
+
+// generic parses the RFC 3597 section 5 format, \# <length> <hex>..., and
+// decodes the result as whatever type is registered for common.Type.
+func (p *rdataParser) generic(common ResourceRecordCommon) (DNSResourceRecord, error) {
+	marker, _ := p.next(`\#`)
+	length, err := p.uint("RDATA length", 16)
+	if err != nil {
+		return nil, err
+	}
+	var rdata []byte
+	if length > 0 {
+		rdata, err = p.hexRest("RDATA")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if uint64(len(rdata)) != length {
+		return nil, p.zp.errorf(marker, "RDATA is %d bytes, but length says %d", len(rdata), length)
+	}
+	rr, err := recordForType(common.Type).UnpackRData(NewRData(common, rdata))
+	if err != nil {
+		return nil, p.zp.errorf(marker, "invalid %s RDATA: %s", common.Type, err)
+	}
+	return rr, nil
+}
+
+type zoneRDataParser func(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error)
+
+var zoneRDataParsers = map[RecordType]zoneRDataParser{
+	TypeA:          parseZoneA,
+	TypeAAAA:       parseZoneAAAA,
+	TypeSRV:        parseZoneSRV,
+	TypePTR:        parseZonePTR,
+	TypeTXT:        parseZoneTXT,
+	TypeNSEC:       parseZoneNSEC,
+	TypeNSEC3:      parseZoneNSEC3,
+	TypeNSEC3PARAM: parseZoneNSEC3PARAM,
+	TypeSVCB:       parseZoneSVCB,
+	TypeHTTPS:      parseZoneHTTPS,
+	TypeNAPTR:      parseZoneNAPTR,
+	TypeURI:        parseZoneURI,
+	TypeSSHFP:      parseZoneSSHFP,
+	TypeTLSA:       parseZoneTLSA,
+	TypeSMIMEA:     parseZoneSMIMEA,
+	TypeOPENPGPKEY: parseZoneOPENPGPKEY,
+	TypeCAA:        parseZoneCAA,
+	TypeLOC:        parseZoneLOC,
+	TypeCERT:       parseZoneCERT,
+	TypeIPSECKEY:   parseZoneIPSECKEY,
+	TypeKX:         parseZoneKX,
+	TypeWKS:        parseZoneWKS,
+	TypeRP:         parseZoneRP,
+	TypeAFSDB:      parseZoneAFSDB,
+	TypeX25:        parseZoneX25,
+	TypeNSAPPTR:    parseZoneNSAPPTR,
+	TypeDNAME:      parseZoneDNAME,
+	TypeEUI48:      parseZoneEUI48,
+	TypeEUI64:      parseZoneEUI64,
+	TypeNID:        parseZoneNID,
+	TypeL32:        parseZoneL32,
+	TypeL64:        parseZoneL64,
+	TypeLP:         parseZoneLP,
+	TypeNS:         parseZoneRFC1035Name,
+	TypeMD:         parseZoneRFC1035Name,
+	TypeMF:         parseZoneRFC1035Name,
+	TypeCNAME:      parseZoneRFC1035Name,
+	TypeSOA:        parseZoneSOA,
+	TypeMB:         parseZoneRFC1035Name,
+	TypeMG:         parseZoneRFC1035Name,
+	TypeMR:         parseZoneRFC1035Name,
+	TypeHINFO:      parseZoneHINFO,
+	TypeMINFO:      parseZoneMINFO,
+	TypeMX:         parseZoneMX,
+}
+
+func parseZoneA(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	addr, err := p.ip("IPv4 address", true)
+	return ARecord{Common: common, Addr: addr}, err
+}
+
+func parseZoneAAAA(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	addr, err := p.ip("IPv6 address", false)
+	return AAAARecord{Common: common, Addr: addr}, err
+}
+
+func parseZoneSRV(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	sr := SRVRecord{Common: common}
+	var err error
+	if sr.Priority, err = p.uint16("priority"); err != nil {
+		return nil, err
+	}
+	if sr.Weight, err = p.uint16("weight"); err != nil {
+		return nil, err
+	}
+	if sr.Port, err = p.uint16("port"); err != nil {
+		return nil, err
+	}
+	sr.Target, err = p.name("target")
+	return sr, err
+}
+
+func parseZonePTR(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	target, err := p.name("PTRDNAME")
+	return PTRRecord{Common: common, PtrDName: target}, err
+}
+
+func parseZoneTXT(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	tr := TXTRecord{Common: common}
+	for p.more() {
+		t, err := p.text("text")
+		if err != nil {
+			return nil, err
+		}
+		tr.texts = append(tr.texts, t)
+	}
+	if len(tr.texts) == 0 {
+		return nil, p.zp.errorf(p.last, "missing text")
+	}
+	return tr, nil
+}
+
+func parseZoneNSEC(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	nr := NSECRecord{Common: common}
+	var err error
+	if nr.NextDomainName, err = p.name("next domain name"); err != nil {
+		return nil, err
+	}
+	nr.NextDomainTypes, err = p.types()
+	return nr, err
+}
+
+func parseZoneNSEC3(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	nr := NSEC3Record{Common: common}
+	var err error
+	if nr.HashAlgorithm, err = p.uint8("hash algorithm"); err != nil {
+		return nil, err
+	}
+	if nr.Flags, err = p.uint8("flags"); err != nil {
+		return nil, err
+	}
+	if nr.Iterations, err = p.uint16("iterations"); err != nil {
+		return nil, err
+	}
+	if nr.Salt, err = p.hexField("salt"); err != nil {
+		return nil, err
+	}
+	tok, err := p.next("next hashed owner name")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = nsec3Encoding.DecodeString(strings.ToUpper(tok.raw)); err != nil {
+		return nil, p.zp.errorf(tok, "bad next hashed owner name: %s", err)
+	}
+	nr.NextHashedOwner = strings.ToLower(tok.raw)
+	nr.Types, err = p.types()
+	return nr, err
+}
+
+func parseZoneNSEC3PARAM(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	nr := NSEC3PARAMRecord{Common: common}
+	var err error
+	if nr.HashAlgorithm, err = p.uint8("hash algorithm"); err != nil {
+		return nil, err
+	}
+	if nr.Flags, err = p.uint8("flags"); err != nil {
+		return nil, err
+	}
+	if nr.Iterations, err = p.uint16("iterations"); err != nil {
+		return nil, err
+	}
+	nr.Salt, err = p.hexField("salt")
+	return nr, err
+}
+
+func parseZoneSVCBRData(p *rdataParser) (uint16, string, []SvcParam, error) {
+	priority, err := p.uint16("priority")
+	if err != nil {
+		return 0, "", nil, err
+	}
+	target, err := p.name("target")
+	if err != nil {
+		return 0, "", nil, err
+	}
+	var params []SvcParam
+	for p.more() {
+		tok, _ := p.next("SvcParam")
+		param, err := parseSvcParam(tok.raw)
+		if err != nil {
+			return 0, "", nil, p.zp.errorf(tok, "bad SvcParam %q: %s", tok.raw, err)
+		}
+		for _, existing := range params {
+			if existing.Key() == param.Key() {
+				return 0, "", nil, p.zp.errorf(tok, "duplicate SvcParam %s", param.Key())
+			}
+		}
+		params = append(params, param)
+	}
+	// The wire format requires keys in increasing order, the presentation
+	// format does not
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].Key() < params[j].Key()
+	})
+	return priority, target, params, nil
+}
+
+func parseZoneSVCB(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	priority, target, params, err := parseZoneSVCBRData(p)
+	return SVCBRecord{Common: common, Priority: priority, Target: target, Params: params}, err
+}
+
+func parseZoneHTTPS(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	priority, target, params, err := parseZoneSVCBRData(p)
+	return HTTPSRecord{Common: common, Priority: priority, Target: target, Params: params}, err
+}
+
+func parseSvcParamKey(s string) (SvcParamKey, error) {
+	for k := SvcParamKeyMandatory; k <= SvcParamKeyIPv6Hint; k++ {
+		if s == k.String() {
+			return k, nil
+		}
+	}
+	if strings.HasPrefix(s, "key") {
+		v, err := strconv.ParseUint(s[len("key"):], 10, 16)
+		if err == nil {
+			return SvcParamKey(v), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key %q", s)
+}
+
+// splitSvcValueList splits a comma-separated value list, in which a comma or
+// backslash may be escaped with a backslash; see RFC 9460 appendix A.1.
+func splitSvcValueList(s string) []string {
+	var items []string
+	var cur []byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			cur = append(cur, s[i+1])
+			i++
+		case s[i] == ',':
+			items = append(items, string(cur))
+			cur = nil
+		default:
+			cur = append(cur, s[i])
+		}
+	}
+	return append(items, string(cur))
+}
+
+func parseSvcParam(raw string) (SvcParam, error) {
+	keyStr, valRaw, hasVal := raw, "", false
+	if i := strings.Index(raw, "="); i >= 0 {
+		keyStr, valRaw, hasVal = raw[:i], raw[i+1:], true
+	}
+	key, err := parseSvcParamKey(keyStr)
+	if err != nil {
+		return nil, err
+	}
+	if len(valRaw) >= 2 && valRaw[0] == '"' && valRaw[len(valRaw)-1] == '"' {
+		valRaw = valRaw[1 : len(valRaw)-1]
+	}
+	valBytes, err := unescapeZone(valRaw)
+	if err != nil {
+		return nil, err
+	}
+	val := string(valBytes)
+	if key == SvcParamKeyNoDefaultALPN {
+		if hasVal {
+			return nil, fmt.Errorf("no-default-alpn takes no value")
+		}
+		return SvcNoDefaultALPN{}, nil
+	}
+	if !hasVal && key <= SvcParamKeyIPv6Hint {
+		return nil, fmt.Errorf("%s requires a value", key)
+	}
+
+	switch key {
+	case SvcParamKeyMandatory:
+		var sm SvcMandatory
+		for _, item := range splitSvcValueList(val) {
+			k, err := parseSvcParamKey(item)
+			if err != nil {
+				return nil, err
+			}
+			sm.Keys = append(sm.Keys, k)
+		}
+		sort.Sort(svcParamKeys(sm.Keys))
+		return sm, nil
+	case SvcParamKeyALPN:
+		return SvcALPN{IDs: splitSvcValueList(val)}, nil
+	case SvcParamKeyPort:
+		port, err := strconv.ParseUint(val, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("bad port %q", val)
+		}
+		return SvcPort{Port: uint16(port)}, nil
+	case SvcParamKeyIPv4Hint, SvcParamKeyIPv6Hint:
+		var addrs []net.IP
+		for _, item := range splitSvcValueList(val) {
+			addr := net.ParseIP(item)
+			if addr == nil || (addr.To4() != nil) != (key == SvcParamKeyIPv4Hint) {
+				return nil, fmt.Errorf("bad address %q", item)
+			}
+			addrs = append(addrs, addr)
+		}
+		if key == SvcParamKeyIPv4Hint {
+			return SvcIPv4Hint{Addrs: addrs}, nil
+		}
+		return SvcIPv6Hint{Addrs: addrs}, nil
+	case SvcParamKeyECH:
+		config, err := base64.StdEncoding.DecodeString(val)
+		if err != nil {
+			return nil, err
+		}
+		return SvcECH{Config: config}, nil
+	default:
+		return SvcUnknown{Code: key, Value: []byte(val)}, nil
+	}
+}
+
+// svcParamKeys implements sort.Interface for a slice of SvcParamKey.
+type svcParamKeys []SvcParamKey
+
+func (sk svcParamKeys) Len() int {
+	return len(sk)
+}
+func (sk svcParamKeys) Less(i, j int) bool {
+	return sk[i] < sk[j]
+}
+func (sk svcParamKeys) Swap(i, j int) {
+	sk[i], sk[j] = sk[j], sk[i]
+}
+
+func parseZoneNAPTR(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	nr := NAPTRRecord{Common: common}
+	var err error
+	if nr.Order, err = p.uint16("order"); err != nil {
+		return nil, err
+	}
+	if nr.Preference, err = p.uint16("preference"); err != nil {
+		return nil, err
+	}
+	if nr.Flags, err = p.text("flags"); err != nil {
+		return nil, err
+	}
+	if nr.Service, err = p.text("services"); err != nil {
+		return nil, err
+	}
+	if nr.Regexp, err = p.text("regexp"); err != nil {
+		return nil, err
+	}
+	nr.Replacement, err = p.name("replacement")
+	return nr, err
+}
+
+func parseZoneURI(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	ur := URIRecord{Common: common}
+	var err error
+	if ur.Priority, err = p.uint16("priority"); err != nil {
+		return nil, err
+	}
+	if ur.Weight, err = p.uint16("weight"); err != nil {
+		return nil, err
+	}
+	ur.Target, err = p.text("target")
+	return ur, err
+}
+
+func parseZoneSSHFP(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	sr := SSHFPRecord{Common: common}
+	var err error
+	if sr.Algorithm, err = p.uint8("algorithm"); err != nil {
+		return nil, err
+	}
+	if sr.FingerprintType, err = p.uint8("fingerprint type"); err != nil {
+		return nil, err
+	}
+	sr.Fingerprint, err = p.hexRest("fingerprint")
+	return sr, err
+}
+
+func parseZoneCertAssociation(p *rdataParser) (uint8, uint8, uint8, []byte, error) {
+	usage, err := p.uint8("certificate usage")
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	selector, err := p.uint8("selector")
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	matchingType, err := p.uint8("matching type")
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	data, err := p.hexRest("certificate association data")
+	return usage, selector, matchingType, data, err
+}
+
+func parseZoneTLSA(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	usage, selector, matchingType, data, err := parseZoneCertAssociation(p)
+	return TLSARecord{Common: common, Usage: usage, Selector: selector, MatchingType: matchingType, Data: data}, err
+}
+
+func parseZoneSMIMEA(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	usage, selector, matchingType, data, err := parseZoneCertAssociation(p)
+	return SMIMEARecord{Common: common, Usage: usage, Selector: selector, MatchingType: matchingType, Data: data}, err
+}
+
+func parseZoneOPENPGPKEY(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	key, err := p.base64Rest("public key")
+	return OPENPGPKEYRecord{Common: common, PublicKey: key}, err
+}
+
+func parseZoneCAA(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	cr := CAARecord{Common: common}
+	var err error
+	if cr.Flags, err = p.uint8("flags"); err != nil {
+		return nil, err
+	}
+	if cr.Tag, err = p.text("tag"); err != nil {
+		return nil, err
+	}
+	value, err := p.text("value")
+	cr.Value = []byte(value)
+	return cr, err
+}
+
+// locAngle reads a latitude or longitude: degrees, optional minutes and
+// seconds, then a hemisphere letter.
+func (p *rdataParser) locAngle(what, positive, negative string, max float64) (float64, error) {
+	var parts []float64
+	for {
+		tok, err := p.next(what)
+		if err != nil {
+			return 0, err
+		}
+		hemisphere := strings.ToUpper(tok.raw)
+		if hemisphere == positive || hemisphere == negative {
+			if len(parts) == 0 {
+				return 0, p.zp.errorf(tok, "missing degrees of %s", what)
+			}
+			for len(parts) < 3 {
+				parts = append(parts, 0)
+			}
+			degrees := parts[0] + parts[1]/60 + parts[2]/3600
+			if degrees > max {
+				return 0, p.zp.errorf(tok, "%s out of range", what)
+			}
+			if hemisphere == negative {
+				degrees = -degrees
+			}
+			return degrees, nil
+		}
+		if len(parts) == 3 {
+			return 0, p.zp.errorf(tok, "expected %s or %s, got %q", positive, negative, tok.raw)
+		}
+		v, err := strconv.ParseFloat(tok.raw, 64)
+		if err != nil || v < 0 {
+			return 0, p.zp.errorf(tok, "bad %s %q", what, tok.raw)
+		}
+		parts = append(parts, v)
+	}
+}
+
+func (p *rdataParser) meters(what string) (float64, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(tok.raw, "m"), 64)
+	if err != nil {
+		return 0, p.zp.errorf(tok, "bad %s %q", what, tok.raw)
+	}
+	return v, nil
+}
+
+func parseZoneLOC(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	lr := LOCRecord{Common: common}
+	var err error
+	if lr.Latitude, err = p.locAngle("latitude", "N", "S", 90); err != nil {
+		return nil, err
+	}
+	if lr.Longitude, err = p.locAngle("longitude", "E", "W", 180); err != nil {
+		return nil, err
+	}
+	if lr.Altitude, err = p.meters("altitude"); err != nil {
+		return nil, err
+	}
+	// Defaults from RFC 1876 section 3
+	precisions := []struct {
+		what    string
+		meters  float64
+		encoded *uint8
+	}{
+		{"size", 1, &lr.Size},
+		{"horizontal precision", 10000, &lr.HorizPre},
+		{"vertical precision", 10, &lr.VertPre},
+	}
+	for _, prec := range precisions {
+		m := prec.meters
+		if p.more() {
+			if m, err = p.meters(prec.what); err != nil {
+				return nil, err
+			}
+		}
+		if *prec.encoded, err = LOCPrecision(m); err != nil {
+			return nil, p.zp.errorf(p.last, "bad %s: %s", prec.what, err)
+		}
+	}
+	return lr, nil
+}
+
+func parseZoneCERT(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	cr := CERTRecord{Common: common}
+	var err error
+	if cr.CertType, err = p.uint16("certificate type"); err != nil {
+		return nil, err
+	}
+	if cr.KeyTag, err = p.uint16("key tag"); err != nil {
+		return nil, err
+	}
+	if cr.Algorithm, err = p.uint8("algorithm"); err != nil {
+		return nil, err
+	}
+	cr.Certificate, err = p.base64Rest("certificate")
+	return cr, err
+}
+
+func parseZoneIPSECKEY(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	ir := IPSECKEYRecord{Common: common}
+	var err error
+	if ir.Precedence, err = p.uint8("precedence"); err != nil {
+		return nil, err
+	}
+	if ir.GatewayType, err = p.uint8("gateway type"); err != nil {
+		return nil, err
+	}
+	if ir.Algorithm, err = p.uint8("algorithm"); err != nil {
+		return nil, err
+	}
+	switch ir.GatewayType {
+	case IPSECKEYGatewayNone:
+		tok, err := p.next("gateway")
+		if err != nil {
+			return nil, err
+		}
+		if tok.raw != "." {
+			return nil, p.zp.errorf(tok, "gateway must be \".\" for gateway type 0")
+		}
+	case IPSECKEYGatewayIPv4:
+		ir.GatewayAddr, err = p.ip("gateway", true)
+	case IPSECKEYGatewayIPv6:
+		ir.GatewayAddr, err = p.ip("gateway", false)
+	case IPSECKEYGatewayDomain:
+		ir.GatewayDomain, err = p.name("gateway")
+	default:
+		return nil, p.zp.errorf(p.last, "unknown gateway type %d", ir.GatewayType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.more() {
+		ir.PublicKey, err = p.base64Rest("public key")
+	}
+	return ir, err
+}
+
+func parseZoneKX(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	kr := KXRecord{Common: common}
+	var err error
+	if kr.Preference, err = p.uint16("preference"); err != nil {
+		return nil, err
+	}
+	kr.Exchanger, err = p.name("exchanger")
+	return kr, err
+}
+
+func parseZoneWKS(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	wr := WKSRecord{Common: common}
+	var err error
+	if wr.Address, err = p.ip("address", true); err != nil {
+		return nil, err
+	}
+	tok, err := p.next("protocol")
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToUpper(tok.raw) {
+	case "TCP":
+		wr.Protocol = 6
+	case "UDP":
+		wr.Protocol = 17
+	default:
+		v, err := strconv.ParseUint(tok.raw, 10, 8)
+		if err != nil {
+			return nil, p.zp.errorf(tok, "bad protocol %q", tok.raw)
+		}
+		wr.Protocol = uint8(v)
+	}
+	for p.more() {
+		port, err := p.uint16("port")
+		if err != nil {
+			return nil, err
+		}
+		wr.Ports = append(wr.Ports, port)
+	}
+	return wr, nil
+}
+
+func parseZoneRP(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	rr := RPRecord{Common: common}
+	var err error
+	if rr.Mailbox, err = p.name("mailbox"); err != nil {
+		return nil, err
+	}
+	rr.TXTDomain, err = p.name("TXT domain")
+	return rr, err
+}
+
+func parseZoneAFSDB(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	ar := AFSDBRecord{Common: common}
+	var err error
+	if ar.Subtype, err = p.uint16("subtype"); err != nil {
+		return nil, err
+	}
+	ar.Hostname, err = p.name("hostname")
+	return ar, err
+}
+
+func parseZoneX25(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	addr, err := p.text("PSDN address")
+	return X25Record{Common: common, PSDNAddress: addr}, err
+}
+
+func parseZoneNSAPPTR(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	target, err := p.name("PTRDNAME")
+	return NSAPPTRRecord{Common: common, PtrDName: target}, err
+}
+
+func parseZoneDNAME(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	target, err := p.name("target")
+	return DNAMERecord{Common: common, Target: target}, err
+}
+
+// eui reads an EUI written as hyphen-separated hex pairs.
+func (p *rdataParser) eui(numBytes int) (net.HardwareAddr, error) {
+	tok, err := p.next("address")
+	if err != nil {
+		return nil, err
+	}
+	pairs := strings.Split(tok.raw, "-")
+	if len(pairs) != numBytes {
+		return nil, p.zp.errorf(tok, "address %q must be %d hyphen-separated hex pairs", tok.raw, numBytes)
+	}
+	addr := make(net.HardwareAddr, numBytes)
+	for i, pair := range pairs {
+		b, err := hex.DecodeString(pair)
+		if err != nil || len(b) != 1 {
+			return nil, p.zp.errorf(tok, "address %q must be %d hyphen-separated hex pairs", tok.raw, numBytes)
+		}
+		addr[i] = b[0]
+	}
+	return addr, nil
+}
+
+func parseZoneEUI48(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	addr, err := p.eui(6)
+	return EUI48Record{Common: common, Address: addr}, err
+}
+
+func parseZoneEUI64(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	addr, err := p.eui(8)
+	return EUI64Record{Common: common, Address: addr}, err
+}
+
+// ilnp64 reads a 64-bit NodeID or Locator64, written as four colon-separated
+// groups of up to 4 hex digits.
+func (p *rdataParser) ilnp64(what string) (uint64, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return 0, err
+	}
+	groups := strings.Split(tok.raw, ":")
+	if len(groups) != 4 {
+		return 0, p.zp.errorf(tok, "bad %s %q", what, tok.raw)
+	}
+	var v uint64
+	for _, group := range groups {
+		g, err := strconv.ParseUint(group, 16, 16)
+		if err != nil {
+			return 0, p.zp.errorf(tok, "bad %s %q", what, tok.raw)
+		}
+		v = v<<16 | g
+	}
+	return v, nil
+}
+
+func parseZoneNID(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	nr := NIDRecord{Common: common}
+	var err error
+	if nr.Preference, err = p.uint16("preference"); err != nil {
+		return nil, err
+	}
+	nr.NodeID, err = p.ilnp64("node ID")
+	return nr, err
+}
+
+func parseZoneL32(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	lr := L32Record{Common: common}
+	var err error
+	if lr.Preference, err = p.uint16("preference"); err != nil {
+		return nil, err
+	}
+	lr.Locator32, err = p.ip("locator", true)
+	return lr, err
+}
+
+func parseZoneL64(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	lr := L64Record{Common: common}
+	var err error
+	if lr.Preference, err = p.uint16("preference"); err != nil {
+		return nil, err
+	}
+	lr.Locator64, err = p.ilnp64("locator")
+	return lr, err
+}
+
+func parseZoneLP(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	lr := LPRecord{Common: common}
+	var err error
+	if lr.Preference, err = p.uint16("preference"); err != nil {
+		return nil, err
+	}
+	lr.FQDN, err = p.name("FQDN")
+	return lr, err
+}
+
+// The RFC 1035 types below have no record type of their own, so they are
+// parsed into an UnknownRecord holding their RDATA, with names uncompressed.
+
+// parseZoneRFC1035Name parses the types whose RDATA is a single domain name:
+// NS, MD, MF, CNAME, MB, MG and MR.
+func parseZoneRFC1035Name(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	name, err := p.name("domain name")
+	if err != nil {
+		return nil, err
+	}
+	return UnknownRecord{Common: common, RData: PackName(name)}, nil
+}
+
+func parseZoneSOA(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	mname, err := p.name("MNAME")
+	if err != nil {
+		return nil, err
+	}
+	rname, err := p.name("RNAME")
+	if err != nil {
+		return nil, err
+	}
+	serial, err := p.uint("serial", 32)
+	if err != nil {
+		return nil, err
+	}
+	bwa := newBufWriteAttempter()
+	bwa.attemptWrite(PackName(mname))
+	bwa.attemptWrite(PackName(rname))
+	bwa.attemptBinaryWrite(binary.BigEndian, uint32(serial))
+	// the timers are often written with units, like TTLs
+	for _, what := range []string{"refresh", "retry", "expire", "minimum"} {
+		tok, err := p.next(what)
+		if err != nil {
+			return nil, err
+		}
+		v, err := parseZoneTTL(tok.raw)
+		if err != nil {
+			return nil, p.zp.errorf(tok, "bad %s %q: %s", what, tok.raw, err)
+		}
+		bwa.attemptBinaryWrite(binary.BigEndian, v)
+	}
+	return UnknownRecord{Common: common, RData: bwa.buf.Bytes()}, bwa.err
+}
+
+func parseZoneHINFO(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	cpu, err := p.text("CPU")
+	if err != nil {
+		return nil, err
+	}
+	os, err := p.text("OS")
+	if err != nil {
+		return nil, err
+	}
+	bwa := newBufWriteAttempter()
+	bwa.attemptWriteCharacterString(cpu)
+	bwa.attemptWriteCharacterString(os)
+	if bwa.err != nil {
+		return nil, p.zp.errorf(p.last, "bad HINFO: %s", bwa.err)
+	}
+	return UnknownRecord{Common: common, RData: bwa.buf.Bytes()}, nil
+}
+
+func parseZoneMINFO(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	rmailbx, err := p.name("RMAILBX")
+	if err != nil {
+		return nil, err
+	}
+	emailbx, err := p.name("EMAILBX")
+	if err != nil {
+		return nil, err
+	}
+	return UnknownRecord{Common: common, RData: append(PackName(rmailbx), PackName(emailbx)...)}, nil
+}
+
+func parseZoneMX(p *rdataParser, common ResourceRecordCommon) (DNSResourceRecord, error) {
+	preference, err := p.uint16("preference")
+	if err != nil {
+		return nil, err
+	}
+	exchange, err := p.name("exchange")
+	if err != nil {
+		return nil, err
+	}
+	rdata := make([]byte, 2)
+	binary.BigEndian.PutUint16(rdata, preference)
+	return UnknownRecord{Common: common, RData: append(rdata, PackName(exchange)...)}, nil
+}
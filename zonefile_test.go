@@ -0,0 +1,185 @@
+package rawmdns
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewRR_roundtrip(t *testing.T) {
+	common := func(name string, rt RecordType) ResourceRecordCommon {
+		return ResourceRecordCommon{Domain: name, Type: rt, Class: ClassINET, TTL: 120}
+	}
+	records := []DNSResourceRecord{
+		ARecord{Common: common("printer.local", TypeA), Addr: net.ParseIP("10.0.0.5").To4()},
+		SRVRecord{Common: common("My Printer._ipp._tcp.local", TypeSRV), Port: 631, Target: "printer.local"},
+		TXTRecord{Common: common("My Printer._ipp._tcp.local", TypeTXT), texts: []string{"txtvers=1", `note="lobby"`}},
+		NSECRecord{Common: common("printer.local", TypeNSEC), NextDomainName: "printer.local", NextDomainTypes: []RecordType{TypeA, TypeAAAA}},
+		HTTPSRecord{Common: common("lab.example", TypeHTTPS), Priority: 1, Target: "", Params: []SvcParam{
+			SvcALPN{IDs: []string{"h2", "h3,x"}},
+			SvcPort{Port: 8443},
+		}},
+		EUI48Record{Common: common("printer.local", TypeEUI48), Address: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}},
+		L64Record{Common: common("host.lab.example", TypeL64), Preference: 10, Locator64: 0x20010db811401000},
+		UnknownRecord{Common: common("printer.local", 65301), RData: []byte{0xde, 0xad}},
+	}
+	for _, rr := range records {
+		parsed, err := NewRR(recordString(rr))
+		if err != nil {
+			t.Errorf("NewRR(%q): %s", recordString(rr), err)
+			continue
+		}
+		if same, reasons := safeRecordEqual(rr, parsed); !same {
+			t.Errorf("NewRR(%q) differs: %v", recordString(rr), reasons)
+		}
+	}
+}
+
+func TestNewRR_rfc1035(t *testing.T) {
+	mx := append([]byte{0x00, 0x0a}, PackName("mail.example")...)
+	soa := append(PackName("ns.example"), PackName("admin.example")...)
+	soa = append(soa,
+		0x78, 0xc2, 0x6b, 0xf9, // serial 2026007545
+		0x00, 0x00, 0x0e, 0x10, // refresh 1h
+		0x00, 0x00, 0x03, 0x84, // retry 15m
+		0x00, 0x09, 0x3a, 0x80, // expire 1w
+		0x00, 0x00, 0x01, 0x2c, // minimum 5m
+	)
+	testCases := []struct {
+		line      string
+		rdata     []byte
+		presented string
+	}{
+		{"a.example. 120 IN CNAME b.example.", PackName("b.example"), "b.example."},
+		{"example. 120 IN NS ns.example.", PackName("ns.example"), "ns.example."},
+		{"example. 120 IN MX 10 mail.example.", mx, "10 mail.example."},
+		{"example. 120 IN SOA ns.example. admin.example. ( 2026007545 1h 15m 1w 5m )", soa,
+			"ns.example. admin.example. 2026007545 3600 900 604800 300"},
+		{"example. 120 IN MINFO admin.example. errors.example.",
+			append(PackName("admin.example"), PackName("errors.example")...), "admin.example. errors.example."},
+		{`example. 120 IN HINFO "ARM64" Linux`, []byte("\x05ARM64\x05Linux"), `"ARM64" "Linux"`},
+	}
+	for _, tc := range testCases {
+		rr, err := NewRR(tc.line)
+		if err != nil {
+			t.Errorf("NewRR(%q): %s", tc.line, err)
+			continue
+		}
+		unk, ok := rr.(UnknownRecord)
+		if !ok || !bytes.Equal(unk.RData, tc.rdata) {
+			t.Errorf("NewRR(%q) is %s, expected RDATA % x", tc.line, recordString(rr), tc.rdata)
+			continue
+		}
+		s := recordString(rr)
+		if !strings.HasSuffix(s, "\t"+tc.presented) {
+			t.Errorf("%q presented as %q, expected RDATA %q", tc.line, s, tc.presented)
+		}
+		parsed, err := NewRR(s)
+		if err != nil {
+			t.Errorf("NewRR(%q): %s", s, err)
+			continue
+		}
+		if same, reasons := safeRecordEqual(rr, parsed); !same {
+			t.Errorf("NewRR(%q) differs: %v", s, reasons)
+		}
+	}
+
+	// RDATA which doesn't fit the type falls back to the generic format
+	short := UnknownRecord{Common: ResourceRecordCommon{Domain: "example", Type: TypeMX, Class: ClassINET, TTL: 120}, RData: []byte{0x00}}
+	if s := recordString(short); !strings.HasSuffix(s, "\t\\# 1 00") {
+		t.Errorf("Short MX presented as %q", s)
+	}
+}
+
+func TestParseZone(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hosts.zone": &fstest.MapFile{Data: []byte("$ORIGIN elsewhere.\nprinter A 10.0.0.9\n")},
+	}
+	zone := `$TTL 1h
+@	IN	PTR	printer   ; the origin itself
+printer	120 A	10.0.0.5
+	AAAA	fe80::1
+My\032Printer._ipp._tcp SRV (
+	0 0 ; priority and weight
+	631 printer )
+$INCLUDE hosts.zone lab
+other	TYPE65301	\# 3 ( ABCD
+	EF )
+`
+	rrs, err := ParseZone(strings.NewReader(zone), "local.", fsys)
+	if err != nil {
+		t.Fatalf("ParseZone: %s", err)
+	}
+	expected := []struct {
+		domain string
+		rt     RecordType
+		ttl    uint32
+	}{
+		{"local", TypePTR, 3600},
+		{"printer.local", TypeA, 120},
+		{"printer.local", TypeAAAA, 3600},
+		{"My Printer._ipp._tcp.local", TypeSRV, 3600},
+		{"printer.elsewhere", TypeA, 3600},
+		{"other.local", 65301, 3600},
+	}
+	if len(rrs) != len(expected) {
+		t.Fatalf("Got %d records, expected %d", len(rrs), len(expected))
+	}
+	for i, e := range expected {
+		c := rrs[i].GetCommon()
+		if c.Domain != e.domain || c.Type != e.rt || c.TTL != e.ttl || c.Class != ClassINET {
+			t.Errorf("rrs[%d] is %s, expected %s %d %s", i, recordString(rrs[i]), e.domain, e.ttl, e.rt)
+		}
+	}
+	if srv := rrs[3].(SRVRecord); srv.Port != 631 || srv.Target != "printer.local" {
+		t.Errorf("Bad SRV RDATA: %s", srv)
+	}
+	if unk := rrs[5].(UnknownRecord); string(unk.RData) != "\xab\xcd\xef" {
+		t.Errorf("Bad generic RDATA: %x", unk.RData)
+	}
+}
+
+func TestParseZone_cacheFlush(t *testing.T) {
+	rr, err := NewRR("printer.local. 120 CLASS32769 A 10.0.0.5")
+	if err != nil {
+		t.Fatalf("NewRR: %s", err)
+	}
+	if c := rr.GetCommon(); !c.CacheFlush || c.Class != ClassINET {
+		t.Errorf("Expected cache-flush IN, got %s", rr)
+	}
+}
+
+func TestParseZone_errors(t *testing.T) {
+	testCases := []struct {
+		zone   string
+		line   int
+		column int
+	}{
+		{"a. 120 A 10.0.0.300\n", 1, 10},
+		{"a. 120 A 10.0.0.1\nb. 120 SRV 0 0\n", 2, 14},
+		{"a. 120 A 10.0.0.1\n\nb. 120 TXT \"unterminated\n", 3, 12},
+		{"$TTL 60\na. SRV ( 0 0 631\n  b.\n", 2, 8},
+		{"a. A 10.0.0.1\n", 1, 4},
+		{"$INCLUDE other.zone\n", 1, 1},
+		{"a.. 120 A 10.0.0.1\n", 1, 1},
+		{"a. 120 TYPE65280 \\# 3 ABCD\n", 1, 18},
+		{"a. 120 OPT 0\n", 1, 8},
+		// generic RDATA too short for the type
+		{"a. 1 IN A \\# 0\n", 1, 11},
+		{"a. 1 IN AAAA \\# 10 00000000000000000000\n", 1, 14},
+		{"a. 1 IN SRV \\# 3 000000\n", 1, 13},
+	}
+	for _, tc := range testCases {
+		_, err := ParseZone(strings.NewReader(tc.zone), "", nil)
+		zpe, ok := err.(ZoneParseError)
+		if !ok {
+			t.Errorf("ParseZone(%q) returned %v, expected a ZoneParseError", tc.zone, err)
+			continue
+		}
+		if zpe.Line != tc.line || zpe.Column != tc.column {
+			t.Errorf("ParseZone(%q) error %q at %d:%d, expected %d:%d", tc.zone, zpe, zpe.Line, zpe.Column, tc.line, tc.column)
+		}
+	}
+}